@@ -32,7 +32,7 @@ type Pagination struct {
 func FetchBibles() *log.Status {
 	var result []fetch.BibleInfoType
 	ctx := context.Background()
-	url := "https://4.dbt.io/api/bibles?v=4"
+	url := fetch.HOST + "bibles?v=4"
 	for {
 		if url == "" {
 			break
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/fetch/fixture"
+	log "github.com/faithcomesbyhearing/fcbh-dataset-io/logger"
+)
+
+/*
+This program serves recorded DBP API responses from a fixtures directory, so that requests with
+bible_brain inputs can be run without network access.  Run the dataset program with
+FCBH_DBP_HOST=http://localhost:8090/
+With -record, responses that are not in the directory are fetched from the DBP API and saved.
+*/
+
+func main() {
+	var ctx = context.WithValue(context.Background(), `runType`, `cli`)
+	port := flag.String(`port`, `8090`, `port to listen on`)
+	record := flag.Bool(`record`, false, `record responses that are not in the directory`)
+	upstream := flag.String(`upstream`, `https://4.dbt.io/api/`, `DBP API to record from`)
+	flag.Parse()
+	if flag.NArg() != 1 {
+		fmt.Println(`Usage: dbp_fixture_server [-port 8090] [-record] [-upstream url] fixtures_directory`)
+		os.Exit(1)
+	}
+	var from string
+	if *record {
+		from = *upstream
+	}
+	server := fixture.NewServer(ctx, flag.Arg(0), from)
+	log.Info(ctx, "DBP fixture server starting on port", *port, "for", flag.Arg(0))
+	err := http.ListenAndServe(`:`+*port, server)
+	if err != nil {
+		log.Panic(ctx, "Error starting server: ", err)
+	}
+}
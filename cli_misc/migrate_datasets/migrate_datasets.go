@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/db"
+)
+
+/*
+This program upgrades the schema of every dataset under $FCBH_DATASET_DB, or under the
+directory given as its argument.  Datasets are also upgraded when they are opened, so this
+is only needed to upgrade them all at once, e.g. before they are used as base_dataset.
+*/
+
+func main() {
+	var ctx = context.WithValue(context.Background(), `runType`, `cli`)
+	directory := os.Getenv(`FCBH_DATASET_DB`)
+	if len(os.Args) > 1 {
+		directory = os.Args[1]
+	}
+	if directory == `` {
+		fmt.Println(`Usage: migrate_datasets [directory], the default is $FCBH_DATASET_DB`)
+		os.Exit(1)
+	}
+	results, status := db.MigrateAll(ctx, directory)
+	if status != nil {
+		fmt.Println(status.String())
+		os.Exit(1)
+	}
+	var failed int
+	for _, result := range results {
+		if result.Status != nil {
+			failed++
+			fmt.Println(result.DatabasePath, `failed at version`, result.To, result.Status.Message)
+		} else if result.From != result.To {
+			fmt.Println(result.DatabasePath, `upgraded from version`, result.From, `to`, result.To)
+		}
+	}
+	fmt.Println(len(results), `datasets, schema version`, db.SchemaVersion(), `failed`, failed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/decode_yaml/request"
+	log "github.com/faithcomesbyhearing/fcbh-dataset-io/logger"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/metrics"
+)
+
+/*
+Each request, other than /schema and /metrics, must have an API key, either as
+"Authorization: Bearer {key}" or as "X-API-Key: {key}".  The key identifies the caller's
+username, and a caller can only submit requests as that username, and only see its own jobs.
+Keys are managed with the keys subcommand.  FCBH_DATASET_AUTH=off turns authentication off,
+e.g. for a server that is only reachable from localhost.
+*/
+
+var auth *AuthStore
+
+type callerKey struct{}
+
+func authEnabled() bool {
+	return !strings.EqualFold(os.Getenv(`FCBH_DATASET_AUTH`), `off`)
+}
+
+// authenticated responds 401 to a request without a valid key, and otherwise
+// passes it to next, with the caller's username in its context.
+func authenticated(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !authEnabled() {
+			next(w, r)
+			return
+		}
+		var ctx = context.WithValue(context.Background(), `runType`, `server`)
+		key := requestKey(r)
+		if key == `` {
+			w.Header().Set(`WWW-Authenticate`, `Bearer`)
+			errorResponse(ctx, w, http.StatusUnauthorized, nil, `An API key is required`)
+			return
+		}
+		username, found, status := auth.SelectUsername(key)
+		if status != nil {
+			errorResponse(ctx, w, status.Status, status, `Error checking API key`)
+			return
+		}
+		if !found {
+			w.Header().Set(`WWW-Authenticate`, `Bearer error="invalid_token"`)
+			errorResponse(ctx, w, http.StatusUnauthorized, nil, `API key is not valid`)
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), callerKey{}, username)))
+	}
+}
+
+func requestKey(r *http.Request) string {
+	header := r.Header.Get(`Authorization`)
+	if len(header) > 7 && strings.EqualFold(header[:7], `Bearer `) {
+		return strings.TrimSpace(header[7:])
+	}
+	return strings.TrimSpace(r.Header.Get(`X-API-Key`))
+}
+
+// caller returns the username of a request's key, which is empty when authentication is off
+func caller(r *http.Request) string {
+	username, _ := r.Context().Value(callerKey{}).(string)
+	return username
+}
+
+// authorize checks that a request is the caller's own, and that its names and paths cannot reach
+// outside of the user's directory, which a base_dataset of ../other, or a database file of
+// $FCBH_DATASET_DB/other/x.db could otherwise do.
+func authorize(ctx context.Context, username string, req request.Request) *log.Status {
+	if username != `` && req.Username != username {
+		return log.ErrorNoErr(ctx, http.StatusForbidden, `Request username `+req.Username+` is not the caller `+username)
+	}
+	names := append([]string{req.Username}, datasetNames(reflect.ValueOf(req))...)
+	for _, name := range names {
+		if strings.ContainsAny(name, `/\`) || strings.Contains(name, `..`) {
+			return log.ErrorNoErr(ctx, http.StatusBadRequest, `Name `+name+` must not contain /, \ or ..`)
+		}
+	}
+	for field, paths := range requestPaths(req) {
+		for _, path := range paths {
+			if filepath.IsAbs(path) || strings.HasPrefix(path, `/`) || strings.HasPrefix(path, `\`) ||
+				strings.Contains(path, `..`) {
+				return log.ErrorNoErr(ctx, http.StatusBadRequest, field+` `+path+` must be relative, and must not contain ..`)
+			}
+		}
+	}
+	return nil
+}
+
+// requestPaths returns the fields of a request that are paths of files, by their yaml name
+func requestPaths(req request.Request) map[string][]string {
+	return map[string][]string{
+		`database.file`:     {req.Database.File},
+		`database.aws_s3`:   {req.Database.AWSS3},
+		`audio_data.file`:   {req.AudioData.File},
+		`audio_data.aws_s3`: {req.AudioData.AWSS3},
+		`text_data.file`:    {req.TextData.File},
+		`text_data.aws_s3`:  {req.TextData.AWSS3},
+		`verdicts.import`:   req.Verdicts.Import,
+	}
+}
+
+// datasetNames returns the dataset names of a request, which are the string and []string fields
+// whose name includes Dataset, e.g. dataset_name, base_dataset and timestamp_ensemble datasets.
+// Finding them by name means a dataset list added to the request later is also checked.
+func datasetNames(value reflect.Value) []string {
+	var names []string
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Field(i)
+		isDataset := strings.Contains(value.Type().Field(i).Name, `Dataset`)
+		switch field.Kind() {
+		case reflect.Struct:
+			names = append(names, datasetNames(field)...)
+		case reflect.String:
+			if isDataset {
+				names = append(names, field.String())
+			}
+		case reflect.Slice:
+			if isDataset && field.Type().Elem().Kind() == reflect.String {
+				for j := 0; j < field.Len(); j++ {
+					names = append(names, field.Index(j).String())
+				}
+			}
+		}
+	}
+	return names
+}
+
+// checkLimits responds 507 when a user's datasets use the maximum storage, and otherwise
+// returns the maximum jobs they may have queued or running, which JobStore.Insert checks,
+// so that the count and the insert of the job are done together.
+func checkLimits(ctx context.Context, username string) (int, *log.Status) {
+	if auth == nil {
+		return 0, nil
+	}
+	limits, status := auth.SelectLimits(username)
+	if status != nil {
+		return 0, status
+	}
+	if limits.MaxStorage > 0 {
+		size := metrics.DirectorySize(filepath.Join(os.Getenv(`FCBH_DATASET_DB`), username))
+		if size >= limits.MaxStorage {
+			return 0, log.ErrorNoErr(ctx, http.StatusInsufficientStorage, username, `uses`, size, `bytes of storage, the limit is`, limits.MaxStorage)
+		}
+	}
+	return limits.MaxJobs, nil
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sync"
+
+	log "github.com/faithcomesbyhearing/fcbh-dataset-io/logger"
+)
+
+// keyPrefix begins each API key, so that a leaked key can be recognized
+const keyPrefix = `fcbh_`
+
+type APIKey struct {
+	KeyId    string `json:"key_id"`
+	Username string `json:"username"`
+	Created  string `json:"created"`
+}
+
+// UserLimits are the limits of one user, 0 is unlimited
+type UserLimits struct {
+	Username   string `json:"username"`
+	MaxJobs    int    `json:"max_jobs"`    // jobs queued or running at once
+	MaxStorage int64  `json:"max_storage"` // bytes of the user's datasets
+}
+
+// AuthStore keeps the API keys of users, and their limits, in a local SQLite file.
+// Only a SHA-256 hash of each key is kept.  Keys are random, so a salt is not needed.
+type AuthStore struct {
+	ctx  context.Context
+	db   *sql.DB
+	lock sync.Mutex
+}
+
+func NewAuthStore(ctx context.Context) (*AuthStore, *log.Status) {
+	var s AuthStore
+	s.ctx = ctx
+	baseDir := os.Getenv(`FCBH_DATASET_DB`)
+	if baseDir == `` {
+		baseDir = os.Getenv(`HOME`)
+	}
+	var err error
+	s.db, err = sql.Open("sqlite3", filepath.Join(baseDir, "api_auth.db"))
+	if err != nil {
+		return &s, log.Error(ctx, 500, err, `Failed to open auth database`)
+	}
+	for _, query := range []string{`CREATE TABLE IF NOT EXISTS api_keys (
+		key_id TEXT PRIMARY KEY,
+		key_hash TEXT NOT NULL UNIQUE,
+		username TEXT NOT NULL,
+		created TEXT NOT NULL) STRICT`,
+		`CREATE TABLE IF NOT EXISTS user_limits (
+		username TEXT PRIMARY KEY,
+		max_jobs INTEGER NOT NULL DEFAULT 0,
+		max_storage INTEGER NOT NULL DEFAULT 0) STRICT`} {
+		_, err = s.db.Exec(query)
+		if err != nil {
+			return &s, log.Error(ctx, 500, err, query)
+		}
+	}
+	return &s, nil
+}
+
+// InsertKey makes a new key for username.  The key is returned only here, it cannot be found later.
+func (s *AuthStore) InsertKey(username string) (string, APIKey, *log.Status) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var rec APIKey
+	var buf = make([]byte, 32)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return ``, rec, log.Error(s.ctx, 500, err, `Error making API key`)
+	}
+	key := keyPrefix + hex.EncodeToString(buf)
+	rec.KeyId = newJobId()[:12]
+	rec.Username = username
+	rec.Created = now()
+	query := `INSERT INTO api_keys (key_id, key_hash, username, created) VALUES (?,?,?,?)`
+	_, err = s.db.Exec(query, rec.KeyId, hashKey(key), rec.Username, rec.Created)
+	if err != nil {
+		return ``, rec, log.Error(s.ctx, 500, err, `Error inserting API key`)
+	}
+	return key, rec, nil
+}
+
+// SelectUsername returns the username of a key, and false when it is not a key
+func (s *AuthStore) SelectUsername(key string) (string, bool, *log.Status) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var username string
+	query := `SELECT username FROM api_keys WHERE key_hash = ?`
+	err := s.db.QueryRow(query, hashKey(key)).Scan(&username)
+	if err == sql.ErrNoRows {
+		return ``, false, nil
+	}
+	if err != nil {
+		return ``, false, log.Error(s.ctx, 500, err, query)
+	}
+	return username, true, nil
+}
+
+// SelectKeys returns the keys of username, or of all users when username is empty
+func (s *AuthStore) SelectKeys(username string) ([]APIKey, *log.Status) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var results []APIKey
+	query := `SELECT key_id, username, created FROM api_keys WHERE username = ? OR ? = '' ORDER BY username, created`
+	rows, err := s.db.Query(query, username, username)
+	if err != nil {
+		return results, log.Error(s.ctx, 500, err, query)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var rec APIKey
+		err = rows.Scan(&rec.KeyId, &rec.Username, &rec.Created)
+		if err != nil {
+			return results, log.Error(s.ctx, 500, err, query)
+		}
+		results = append(results, rec)
+	}
+	return results, nil
+}
+
+// DeleteKey revokes a key by its id, and returns false when there is no such key
+func (s *AuthStore) DeleteKey(keyId string) (bool, *log.Status) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	query := `DELETE FROM api_keys WHERE key_id = ?`
+	result, err := s.db.Exec(query, keyId)
+	if err != nil {
+		return false, log.Error(s.ctx, 500, err, query)
+	}
+	count, _ := result.RowsAffected()
+	return count > 0, nil
+}
+
+func (s *AuthStore) UpdateLimits(limits UserLimits) *log.Status {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	query := `REPLACE INTO user_limits (username, max_jobs, max_storage) VALUES (?,?,?)`
+	_, err := s.db.Exec(query, limits.Username, limits.MaxJobs, limits.MaxStorage)
+	if err != nil {
+		return log.Error(s.ctx, 500, err, query)
+	}
+	return nil
+}
+
+// SelectLimits returns the limits of username, which are 0 when none were set
+func (s *AuthStore) SelectLimits(username string) (UserLimits, *log.Status) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var limits = UserLimits{Username: username}
+	query := `SELECT max_jobs, max_storage FROM user_limits WHERE username = ?`
+	err := s.db.QueryRow(query, username).Scan(&limits.MaxJobs, &limits.MaxStorage)
+	if err != nil && err != sql.ErrNoRows {
+		return limits, log.Error(s.ctx, 500, err, query)
+	}
+	return limits, nil
+}
+
+func (s *AuthStore) Close() {
+	_ = s.db.Close()
+}
+
+func hashKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
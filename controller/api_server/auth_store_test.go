@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/decode_yaml/request"
+)
+
+func TestAuthStore(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv(`FCBH_DATASET_DB`, t.TempDir())
+	store, status := NewAuthStore(ctx)
+	if status != nil {
+		t.Fatal(status)
+	}
+	defer store.Close()
+	key, rec, status := store.InsertKey(`GaryNTest`)
+	if status != nil {
+		t.Fatal(status)
+	}
+	if !strings.HasPrefix(key, keyPrefix) || rec.KeyId == `` {
+		t.Error(`Unexpected key`, key, rec)
+	}
+	username, found, status := store.SelectUsername(key)
+	if status != nil || !found || username != `GaryNTest` {
+		t.Error(`Key not found`, username, found, status)
+	}
+	_, found, _ = store.SelectUsername(key + `x`)
+	if found {
+		t.Error(`Wrong key was found`)
+	}
+	keys, _ := store.SelectKeys(``)
+	if len(keys) != 1 || keys[0].Username != `GaryNTest` {
+		t.Error(`Unexpected keys`, keys)
+	}
+	status = store.UpdateLimits(UserLimits{Username: `GaryNTest`, MaxJobs: 2, MaxStorage: 1000})
+	if status != nil {
+		t.Fatal(status)
+	}
+	limits, _ := store.SelectLimits(`GaryNTest`)
+	if limits.MaxJobs != 2 || limits.MaxStorage != 1000 {
+		t.Error(`Unexpected limits`, limits)
+	}
+	limits, _ = store.SelectLimits(`Other`)
+	if limits.MaxJobs != 0 || limits.MaxStorage != 0 {
+		t.Error(`Unexpected default limits`, limits)
+	}
+	deleted, _ := store.DeleteKey(rec.KeyId)
+	if !deleted {
+		t.Error(`Key was not revoked`)
+	}
+	_, found, _ = store.SelectUsername(key)
+	if found {
+		t.Error(`Revoked key was found`)
+	}
+}
+
+func TestAuthenticated(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv(`FCBH_DATASET_DB`, t.TempDir())
+	var err error
+	auth, _ = NewAuthStore(ctx)
+	defer func() { auth.Close(); auth = nil }()
+	key, _, _ := auth.InsertKey(`GaryNTest`)
+	handler := authenticated(func(w http.ResponseWriter, r *http.Request) {
+		_, err = w.Write([]byte(caller(r)))
+	})
+	tests := []struct {
+		header string
+		value  string
+		code   int
+	}{
+		{``, ``, http.StatusUnauthorized},
+		{`Authorization`, `Bearer wrong`, http.StatusUnauthorized},
+		{`Authorization`, `Bearer ` + key, http.StatusOK},
+		{`X-API-Key`, key, http.StatusOK},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(`GET`, `/jobs`, nil)
+		if tt.header != `` {
+			r.Header.Set(tt.header, tt.value)
+		}
+		w := httptest.NewRecorder()
+		handler(w, r)
+		if w.Code != tt.code {
+			t.Error(tt.header, `expected`, tt.code, `got`, w.Code)
+		}
+		if w.Code == http.StatusOK && w.Body.String() != `GaryNTest` {
+			t.Error(`Unexpected caller`, w.Body.String())
+		}
+	}
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	ctx := context.Background()
+	var req request.Request
+	req.Username = `GaryNTest`
+	req.DatasetName = `TestAuthorize`
+	if authorize(ctx, `GaryNTest`, req) != nil {
+		t.Error(`Own request was not authorized`)
+	}
+	status := authorize(ctx, `Other`, req)
+	if status == nil || status.Status != http.StatusForbidden {
+		t.Error(`Other user's request was authorized`, status)
+	}
+	req.Compare.BaseDataset = `../Other/Dataset`
+	status = authorize(ctx, `GaryNTest`, req)
+	if status == nil || status.Status != http.StatusBadRequest {
+		t.Error(`base_dataset outside of user was authorized`, status)
+	}
+	req.Compare.BaseDataset = ``
+	req.TSEnsemble.Datasets = []string{`TestAuthorize`, `../Other/Dataset`}
+	status = authorize(ctx, `GaryNTest`, req)
+	if status == nil || status.Status != http.StatusBadRequest {
+		t.Error(`timestamp_ensemble dataset outside of user was authorized`, status)
+	}
+}
+
+func TestAuthorizePaths(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		field string
+		set   func(req *request.Request, path string)
+	}{
+		{`database.file`, func(req *request.Request, path string) { req.Database.File = path }},
+		{`database.aws_s3`, func(req *request.Request, path string) { req.Database.AWSS3 = path }},
+		{`audio_data.file`, func(req *request.Request, path string) { req.AudioData.File = path }},
+		{`audio_data.aws_s3`, func(req *request.Request, path string) { req.AudioData.AWSS3 = path }},
+		{`text_data.file`, func(req *request.Request, path string) { req.TextData.File = path }},
+		{`text_data.aws_s3`, func(req *request.Request, path string) { req.TextData.AWSS3 = path }},
+		{`verdicts.import`, func(req *request.Request, path string) { req.Verdicts.Import = []string{`ok.json`, path} }},
+	}
+	for _, test := range tests {
+		for _, path := range []string{`/data/Other/x.db`, `../Other/x.db`, `s3://bucket/GaryNTest/../Other/x.db`} {
+			var req request.Request
+			req.Username = `GaryNTest`
+			req.DatasetName = `TestAuthorizePaths`
+			test.set(&req, path)
+			status := authorize(ctx, `GaryNTest`, req)
+			if status == nil || status.Status != http.StatusBadRequest {
+				t.Error(test.field, path, `was authorized`, status)
+			}
+		}
+		var req request.Request
+		req.Username = `GaryNTest`
+		req.DatasetName = `TestAuthorizePaths`
+		test.set(&req, `GaryNTest/x.db`)
+		if status := authorize(ctx, `GaryNTest`, req); status != nil {
+			t.Error(test.field, `relative path was not authorized`, status)
+		}
+	}
+}
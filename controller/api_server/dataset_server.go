@@ -2,22 +2,70 @@ package main
 
 import (
 	"context"
-	"github.com/faithcomesbyhearing/fcbh-dataset-io/controller"
-	"github.com/faithcomesbyhearing/fcbh-dataset-io/input"
-	log "github.com/faithcomesbyhearing/fcbh-dataset-io/logger"
+	"encoding/json"
 	"io"
 	"mime/multipart"
 	"net/http"
 	//_ "net/http/pprof"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/controller"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/decode_yaml"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/input"
+	log "github.com/faithcomesbyhearing/fcbh-dataset-io/logger"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/metrics"
 )
 
+/*
+Requests are run as background jobs.  /request and /upload return the job immediately,
+and the job is then followed with:
+GET /jobs lists the caller's jobs
+GET /jobs/{id} returns a job's state, current stage, and percent of chapters done
+POST /jobs/{id}/cancel cancels a queued or running job
+GET /jobs/{id}/outputs/{n} downloads the n'th output file of a finished job
+POST /dry_run checks a request, and returns the plan of what it would do, without queueing it
+GET /schema returns the JSON Schema of a request
+GET /metrics returns the metrics of the server and its jobs in the Prometheus text format
+Each endpoint, other than /schema and /metrics, requires an API key, see auth.go.
+*/
+
+var runner *JobRunner
+
 func main() {
 	var ctx = context.Background()
-	http.HandleFunc("/upload", uploadHandler)
-	http.HandleFunc("/request", handler)
+	if len(os.Args) > 1 {
+		os.Exit(keysCommand(ctx, os.Args[1:]))
+	}
+	var status *log.Status
+	auth, status = NewAuthStore(ctx)
+	if status != nil {
+		log.Panic(ctx, "Error opening auth store: ", status)
+	}
+	defer auth.Close()
+	if !authEnabled() {
+		log.Warn(ctx, "FCBH_DATASET_AUTH is off, requests are not authenticated")
+	}
+	store, status := NewJobStore(ctx)
+	if status != nil {
+		log.Panic(ctx, "Error opening job store: ", status)
+	}
+	defer store.Close()
+	runner = NewJobRunner(ctx, store)
+	runner.Start()
+	http.HandleFunc("/upload", authenticated(uploadHandler))
+	http.HandleFunc("/request", authenticated(handler))
+	http.HandleFunc("POST /dry_run", authenticated(dryRunHandler))
+	http.HandleFunc("GET /schema", schemaHandler)
+	http.HandleFunc("GET /jobs", authenticated(listJobsHandler))
+	http.HandleFunc("GET /jobs/{id}", authenticated(jobHandler))
+	http.HandleFunc("POST /jobs/{id}/cancel", authenticated(cancelHandler))
+	http.HandleFunc("GET /jobs/{id}/outputs/{n}", authenticated(outputHandler))
+	http.Handle("GET /metrics", metrics.Handler())
+	metrics.OnReadQueue(readQueue)
 	log.Info(ctx, "Server starting on port 7777...")
 	err := http.ListenAndServe(":7777", nil)
 	if err != nil {
@@ -26,19 +74,17 @@ func main() {
 }
 
 func handler(w http.ResponseWriter, r *http.Request) {
-	var start = time.Now()
 	var ctx = context.WithValue(context.Background(), `runType`, `server`)
 	if r.Method != `POST` {
 		errorResponse(ctx, w, http.StatusMethodNotAllowed, nil, `Only POST method is allowed`)
+		return
 	}
 	request, err := io.ReadAll(r.Body)
 	if err != nil {
 		errorResponse(ctx, w, http.StatusInternalServerError, err, `Error reading request to server`)
 		return
 	}
-	var control = controller.NewController(ctx, request)
-	responder(ctx, w, control)
-	log.Info(context.TODO(), time.Since(start))
+	submit(ctx, w, caller(r), request, nil)
 }
 
 func uploadHandler(w http.ResponseWriter, r *http.Request) {
@@ -46,6 +92,7 @@ func uploadHandler(w http.ResponseWriter, r *http.Request) {
 	var ctx = context.WithValue(context.Background(), `runType`, `server`)
 	if r.Method != `POST` {
 		errorResponse(ctx, w, http.StatusMethodNotAllowed, nil, `Only POST method is allowed`)
+		return
 	}
 	// Parse the multipart form with a max memory of 10 MiB
 	err := r.ParseMultipartForm(10 << 20)
@@ -61,6 +108,7 @@ func uploadHandler(w http.ResponseWriter, r *http.Request) {
 	for key := range r.MultipartForm.File {
 		file, header, err2 := r.FormFile(key)
 		if err2 != nil {
+			postFiles.RemoveDir()
 			errorResponse(ctx, w, http.StatusInternalServerError, err2, "Failed to read multipart form")
 			return
 		}
@@ -69,6 +117,7 @@ func uploadHandler(w http.ResponseWriter, r *http.Request) {
 			yamlHeader = header
 			request, err = io.ReadAll(file)
 			if err != nil {
+				postFiles.RemoveDir()
 				errorResponse(ctx, w, http.StatusInternalServerError, err, "Unable to read YAML file")
 				return
 			}
@@ -76,49 +125,239 @@ func uploadHandler(w http.ResponseWriter, r *http.Request) {
 			dataHeader = header
 			status := postFiles.ReadFile(key, file, header.Filename)
 			if status != nil {
+				postFiles.RemoveDir()
 				errorResponse(ctx, w, status.Status, nil, status.Message+status.Error())
+				return
 			}
 		}
 	}
-	log.Info(ctx, "Files uploaded successfully:", dataHeader.Filename, yamlHeader.Filename)
-	var control = controller.NewController(ctx, request)
-	control.SetPostFiles(&postFiles)
-	responder(ctx, w, control)
-	log.Info(ctx, time.Since(start))
+	if yamlHeader == nil || dataHeader == nil {
+		postFiles.RemoveDir()
+		errorResponse(ctx, w, http.StatusBadRequest, nil, "Upload requires a yaml file and a text or audio file")
+		return
+	}
+	log.Info(ctx, "Files uploaded successfully:", dataHeader.Filename, yamlHeader.Filename, time.Since(start))
+	submit(ctx, w, caller(r), request, &postFiles)
 }
 
-func responder(ctx context.Context, w http.ResponseWriter, control controller.Controller) {
-	var outputFiles, status = control.ProcessV2()
+// submit queues the request of username as a job, and responds with the job
+func submit(ctx context.Context, w http.ResponseWriter, username string, request []byte, postFiles *input.PostFiles) {
+	decoder := decode_yaml.NewRequestDecoder(ctx)
+	req, status := decoder.Process(request)
 	if status != nil {
-		w.WriteHeader(status.Status)
-	} else {
-		w.WriteHeader(200)
+		if postFiles != nil {
+			postFiles.RemoveDir()
+		}
+		validationResponse(ctx, w, status, decoder.Errors())
+		return
+	}
+	var maxJobs int
+	status = authorize(ctx, username, req)
+	if status == nil {
+		maxJobs, status = checkLimits(ctx, req.Username)
+	}
+	if status != nil {
+		if postFiles != nil {
+			postFiles.RemoveDir()
+		}
+		http.Error(w, status.String(), status.Status)
+		return
+	}
+	var job Job
+	job.Username = req.Username
+	job.DatasetName = req.DatasetName
+	job.yaml = request
+	status = runner.Submit(&job, postFiles, maxJobs)
+	if status != nil {
+		if postFiles != nil {
+			postFiles.RemoveDir()
+		}
+		errorResponse(ctx, w, status.Status, status, `Unable to queue job`)
+		return
+	}
+	jsonResponse(ctx, w, http.StatusAccepted, job)
+}
+
+// dryRunHandler responds with the plan of a request.  The status is 200 even when the plan
+// has problems, because the plan is what was asked for.
+func dryRunHandler(w http.ResponseWriter, r *http.Request) {
+	var ctx = context.WithValue(context.Background(), `runType`, `server`)
+	request, err := io.ReadAll(r.Body)
+	if err != nil {
+		errorResponse(ctx, w, http.StatusInternalServerError, err, `Error reading request to server`)
+		return
+	}
+	username := caller(r)
+	if username != `` {
+		decoder := decode_yaml.NewRequestDecoder(ctx)
+		req, status := decoder.Decode(request)
+		if status == nil {
+			status = authorize(ctx, username, req)
+			if status != nil {
+				http.Error(w, status.String(), status.Status)
+				return
+			}
+		}
+	}
+	plan := controller.DryRun(ctx, request)
+	jsonResponse(ctx, w, http.StatusOK, plan)
+}
+
+func schemaHandler(w http.ResponseWriter, r *http.Request) {
+	var ctx = context.WithValue(context.Background(), `runType`, `server`)
+	jsonResponse(ctx, w, http.StatusOK, decode_yaml.Schema())
+}
+
+// readQueue returns the number of queued jobs, and when the oldest was created
+func readQueue() (int, time.Time, bool) {
+	jobs, status := runner.store.SelectByState(JobQueued)
+	if status != nil {
+		return 0, time.Time{}, false
+	}
+	var oldest time.Time
+	for _, job := range jobs {
+		created, err := time.Parse(time.RFC3339, job.Created)
+		if err == nil && (oldest.IsZero() || created.Before(oldest)) {
+			oldest = created
+		}
+	}
+	return len(jobs), oldest, true
+}
+
+func listJobsHandler(w http.ResponseWriter, r *http.Request) {
+	var ctx = context.WithValue(context.Background(), `runType`, `server`)
+	username := r.URL.Query().Get(`username`)
+	if caller(r) != `` {
+		if username != `` && username != caller(r) {
+			errorResponse(ctx, w, http.StatusForbidden, nil, `Jobs of `+username+` are not the caller's`)
+			return
+		}
+		username = caller(r)
+	}
+	if username == `` {
+		errorResponse(ctx, w, http.StatusBadRequest, nil, `username query parameter is required`)
+		return
+	}
+	jobs, status := runner.store.SelectByUser(username)
+	if status != nil {
+		errorResponse(ctx, w, status.Status, status, `Error listing jobs`)
+		return
+	}
+	if jobs == nil {
+		jobs = []Job{}
+	}
+	jsonResponse(ctx, w, http.StatusOK, jobs)
+}
+
+func jobHandler(w http.ResponseWriter, r *http.Request) {
+	var ctx = context.WithValue(context.Background(), `runType`, `server`)
+	job, ok := findJob(ctx, w, r)
+	if ok {
+		jsonResponse(ctx, w, http.StatusOK, job)
+	}
+}
+
+func cancelHandler(w http.ResponseWriter, r *http.Request) {
+	var ctx = context.WithValue(context.Background(), `runType`, `server`)
+	job, ok := findJob(ctx, w, r)
+	if !ok {
+		return
+	}
+	if !runner.Cancel(job) {
+		errorResponse(ctx, w, http.StatusConflict, nil, `Job is already `+job.State)
+		return
 	}
-	filename := outputFiles.FilePaths[0]
+	job, _, _ = runner.store.Select(job.JobId)
+	jsonResponse(ctx, w, http.StatusAccepted, job)
+}
+
+func outputHandler(w http.ResponseWriter, r *http.Request) {
+	var ctx = context.WithValue(context.Background(), `runType`, `server`)
+	job, ok := findJob(ctx, w, r)
+	if !ok {
+		return
+	}
+	index, err := strconv.Atoi(r.PathValue(`n`))
+	if err != nil || index < 0 || index >= len(job.Outputs) {
+		errorResponse(ctx, w, http.StatusNotFound, nil, `Job has no output `+r.PathValue(`n`))
+		return
+	}
+	filename := job.Outputs[index]
 	var mimeType string
 	if strings.HasSuffix(filename, `.csv`) {
 		mimeType = "text/csv"
 	} else if strings.HasSuffix(filename, `.json`) {
 		mimeType = "application/json"
+	} else if strings.HasSuffix(filename, `.html`) {
+		mimeType = "text/html"
+	} else if strings.HasSuffix(filename, `.zip`) {
+		mimeType = "application/zip"
+	} else if strings.HasSuffix(filename, `.xlsx`) {
+		mimeType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
 	} else {
 		mimeType = "application/octet-stream"
 	}
-	w.Header().Set("Content-Type", mimeType)
 	var file *os.File
-	file, err := os.Open(filename)
+	file, err = os.Open(filename)
 	if err != nil {
-		errorResponse(ctx, w, http.StatusInternalServerError, err, `File containing results is not found`)
+		errorResponse(ctx, w, http.StatusNotFound, err, `File containing results is not found`)
 		return
 	}
 	defer file.Close()
+	w.Header().Set("Content-Type", mimeType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filepath.Base(filename)+`"`)
 	_, err = io.Copy(w, file)
 	if err != nil {
-		errorResponse(ctx, w, http.StatusInternalServerError, err, `Error writing results to http response`)
+		_ = log.Error(ctx, http.StatusInternalServerError, err, `Error writing results to http response`)
+	}
+}
+
+func findJob(ctx context.Context, w http.ResponseWriter, r *http.Request) (Job, bool) {
+	job, found, status := runner.store.Select(r.PathValue(`id`))
+	if status != nil {
+		errorResponse(ctx, w, status.Status, status, `Error finding job`)
+		return job, false
+	}
+	// Another user's job is not found, so that its id is not confirmed
+	if !found || (caller(r) != `` && job.Username != caller(r)) {
+		errorResponse(ctx, w, http.StatusNotFound, nil, `Job not found `+r.PathValue(`id`))
+		return job, false
+	}
+	return job, true
+}
+
+func jsonResponse(ctx context.Context, w http.ResponseWriter, statusCode int, content any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	err := json.NewEncoder(w).Encode(content)
+	if err != nil {
+		_ = log.Error(ctx, http.StatusInternalServerError, err, `Error writing json response`)
+	}
+}
+
+// ValidationResponse is the response to a request that is not valid.  Each error has the
+// YAML path of the field, and its line and column when it is in the request.
+type ValidationResponse struct {
+	Status  int                           `json:"status"`
+	Message string                        `json:"message"`
+	Errors  []decode_yaml.ValidationError `json:"errors"`
+}
+
+func validationResponse(ctx context.Context, w http.ResponseWriter, status *log.Status, errors []decode_yaml.ValidationError) {
+	if len(errors) == 0 {
+		errorResponse(ctx, w, status.Status, status, `Invalid YAML request`)
 		return
 	}
+	var resp = ValidationResponse{Status: status.Status, Message: `Invalid YAML request`, Errors: errors}
+	jsonResponse(ctx, w, status.Status, resp)
 }
 
 func errorResponse(ctx context.Context, w http.ResponseWriter, statusCode int, err error, message string) {
-	status := log.Error(ctx, statusCode, err, message)
+	var status *log.Status
+	if err != nil {
+		status = log.Error(ctx, statusCode, err, message)
+	} else {
+		status = log.ErrorNoErr(ctx, statusCode, message)
+	}
 	http.Error(w, status.String(), statusCode)
 }
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSubmitValidationErrors(t *testing.T) {
+	ctx := context.Background()
+	var yaml = `is_new: yes
+dataset_name: TestSubmit
+username: GaryNTest
+bible_id: ENGWEB
+text_data:
+  no_text: yes
+audio_data:
+  bible_brain:
+    mp3_64: yes
+timestamps:
+  mms_align: yes
+`
+	recorder := httptest.NewRecorder()
+	submit(ctx, recorder, ``, []byte(yaml), nil)
+	if recorder.Code != http.StatusBadRequest {
+		t.Fatal(`Expected 400, found`, recorder.Code, recorder.Body.String())
+	}
+	var resp ValidationResponse
+	err := json.Unmarshal(recorder.Body.Bytes(), &resp)
+	if err != nil {
+		t.Fatal(err, recorder.Body.String())
+	}
+	if len(resp.Errors) == 0 || resp.Errors[0].Path != `timestamps` || resp.Errors[0].Line != 10 {
+		t.Error(`Expected an error located at timestamps`, resp.Errors)
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/controller"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/input"
+	log "github.com/faithcomesbyhearing/fcbh-dataset-io/logger"
+)
+
+// JobRunner runs queued jobs in the background with a fixed number of workers.
+// The number of workers is set by FCBH_DATASET_API_WORKERS, and defaults to 1.
+type JobRunner struct {
+	ctx       context.Context
+	store     *JobStore
+	queue     chan string
+	lock      sync.Mutex
+	cancels   map[string]context.CancelFunc
+	postFiles map[string]*input.PostFiles
+}
+
+func NewJobRunner(ctx context.Context, store *JobStore) *JobRunner {
+	var r JobRunner
+	r.ctx = ctx
+	r.store = store
+	r.queue = make(chan string, 1024)
+	r.cancels = make(map[string]context.CancelFunc)
+	r.postFiles = make(map[string]*input.PostFiles)
+	return &r
+}
+
+func (r *JobRunner) Start() {
+	workers, err := strconv.Atoi(os.Getenv(`FCBH_DATASET_API_WORKERS`))
+	if err != nil || workers < 1 {
+		workers = 1
+	}
+	for i := 0; i < workers; i++ {
+		go r.worker()
+	}
+	go r.recover()
+}
+
+// recover requeues jobs that were queued when the server stopped.  Jobs that were running,
+// or that depend upon uploaded files, which were in memory, are marked failed.
+// It runs after the workers start, so that more queued jobs than the queue holds do not block.
+func (r *JobRunner) recover() {
+	running, status := r.store.SelectByState(JobRunning)
+	if status == nil {
+		for _, job := range running {
+			r.store.UpdateFinished(job.JobId, JobFailed, 500, `Interrupted by server restart`, nil)
+		}
+	}
+	queued, status := r.store.SelectByState(JobQueued)
+	if status == nil {
+		for _, job := range queued {
+			if job.hasPostFiles {
+				r.store.UpdateFinished(job.JobId, JobFailed, 500, `Uploaded files lost in server restart`, nil)
+			} else {
+				r.queue <- job.JobId
+			}
+		}
+	}
+}
+
+// Submit records a new job and queues it.  postFiles is nil unless files were uploaded.
+// maxJobs is the user's limit of jobs queued or running, or 0 for no limit.
+func (r *JobRunner) Submit(job *Job, postFiles *input.PostFiles, maxJobs int) *log.Status {
+	job.hasPostFiles = postFiles != nil
+	status := r.store.Insert(job, maxJobs)
+	if status != nil {
+		return status
+	}
+	if postFiles != nil {
+		r.lock.Lock()
+		r.postFiles[job.JobId] = postFiles
+		r.lock.Unlock()
+	}
+	select {
+	case r.queue <- job.JobId:
+		return nil
+	default:
+		r.store.UpdateFinished(job.JobId, JobFailed, 503, `Job queue is full`, nil)
+		return log.ErrorNoErr(r.ctx, 503, `Job queue is full`)
+	}
+}
+
+// Cancel stops a running job, or prevents a queued job from starting.
+// It returns false if the job has already finished.
+func (r *JobRunner) Cancel(job Job) bool {
+	if job.State == JobQueued {
+		if r.store.UpdateCancelled(job.JobId) {
+			r.lock.Lock()
+			postFiles, ok := r.postFiles[job.JobId]
+			delete(r.postFiles, job.JobId)
+			r.lock.Unlock()
+			if ok {
+				postFiles.RemoveDir()
+			}
+			return true
+		}
+		// A worker started it after it was selected, so it is cancelled as a running job
+	}
+	if job.State == JobQueued || job.State == JobRunning {
+		r.lock.Lock()
+		cancel, ok := r.cancels[job.JobId]
+		r.lock.Unlock()
+		if ok {
+			cancel()
+		}
+		return ok
+	}
+	return false
+}
+
+func (r *JobRunner) worker() {
+	for jobId := range r.queue {
+		job, found, status := r.store.Select(jobId)
+		if status != nil || !found || job.State != JobQueued {
+			continue // cancelled while queued
+		}
+		r.run(job)
+	}
+}
+
+func (r *JobRunner) run(job Job) {
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), `runType`, `server`))
+	defer cancel()
+	ctx = log.WithJob(ctx, job.JobId, job.Username, job.DatasetName)
+	r.lock.Lock()
+	r.cancels[job.JobId] = cancel
+	postFiles := r.postFiles[job.JobId]
+	delete(r.postFiles, job.JobId)
+	r.lock.Unlock()
+	defer func() {
+		r.lock.Lock()
+		delete(r.cancels, job.JobId)
+		r.lock.Unlock()
+	}()
+	if !r.store.UpdateStarted(job.JobId) {
+		if postFiles != nil {
+			postFiles.RemoveDir()
+		}
+		return // cancelled after it was taken from the queue
+	}
+	log.Info(ctx, "Start job", job.JobId, job.Username, job.DatasetName)
+	var control = controller.NewController(ctx, job.yaml)
+	if postFiles != nil {
+		control.SetPostFiles(postFiles)
+	}
+	control.SetProgress(func(stage string, chaptersDone int, chaptersTotal int) {
+		r.store.UpdateProgress(job.JobId, stage, chaptersDone, chaptersTotal)
+	})
+	outputFiles, status := control.ProcessV2()
+	if ctx.Err() != nil {
+		r.store.UpdateFinished(job.JobId, JobCancelled, 499, `Cancelled while running`, outputFiles.FilePaths)
+	} else if status != nil {
+		r.store.UpdateFinished(job.JobId, JobFailed, status.Status, status.Message, outputFiles.FilePaths)
+	} else {
+		r.store.UpdateFinished(job.JobId, JobSucceeded, 200, ``, outputFiles.FilePaths)
+	}
+	log.Info(ctx, "Finish job", job.JobId)
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	log "github.com/faithcomesbyhearing/fcbh-dataset-io/logger"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Job states
+const (
+	JobQueued    = `queued`
+	JobRunning   = `running`
+	JobSucceeded = `succeeded`
+	JobFailed    = `failed`
+	JobCancelled = `cancelled`
+)
+
+type Job struct {
+	JobId         string   `json:"job_id"`
+	Username      string   `json:"username"`
+	DatasetName   string   `json:"dataset_name"`
+	State         string   `json:"state"`
+	Stage         string   `json:"stage"`
+	ChaptersDone  int      `json:"chapters_done"`
+	ChaptersTotal int      `json:"chapters_total"`
+	Percent       float64  `json:"percent"`
+	Status        int      `json:"status,omitempty"`
+	Message       string   `json:"message,omitempty"`
+	Outputs       []string `json:"outputs,omitempty"`
+	Created       string   `json:"created"`
+	Updated       string   `json:"updated"`
+	yaml          []byte
+	hasPostFiles  bool
+}
+
+// JobStore keeps the state of api_server jobs in a local SQLite file, so that it survives a restart.
+type JobStore struct {
+	ctx  context.Context
+	db   *sql.DB
+	lock sync.Mutex
+}
+
+func NewJobStore(ctx context.Context) (*JobStore, *log.Status) {
+	var s JobStore
+	s.ctx = ctx
+	baseDir := os.Getenv(`FCBH_DATASET_DB`)
+	if baseDir == `` {
+		baseDir = os.Getenv(`HOME`)
+	}
+	var err error
+	s.db, err = sql.Open("sqlite3", filepath.Join(baseDir, "api_jobs.db"))
+	if err != nil {
+		return &s, log.Error(ctx, 500, err, `Failed to open job database`)
+	}
+	query := `CREATE TABLE IF NOT EXISTS jobs (
+		job_id TEXT PRIMARY KEY,
+		username TEXT NOT NULL,
+		dataset_name TEXT NOT NULL,
+		state TEXT NOT NULL,
+		stage TEXT NOT NULL DEFAULT '',
+		chapters_done INTEGER NOT NULL DEFAULT 0,
+		chapters_total INTEGER NOT NULL DEFAULT 0,
+		status INTEGER NOT NULL DEFAULT 0,
+		message TEXT NOT NULL DEFAULT '',
+		outputs TEXT NOT NULL DEFAULT '[]',
+		yaml BLOB NOT NULL,
+		has_post_files INTEGER NOT NULL DEFAULT 0,
+		created TEXT NOT NULL,
+		updated TEXT NOT NULL) STRICT`
+	_, err = s.db.Exec(query)
+	if err != nil {
+		return &s, log.Error(ctx, 500, err, query)
+	}
+	query = `CREATE INDEX IF NOT EXISTS jobs_user_idx ON jobs (username)`
+	_, err = s.db.Exec(query)
+	if err != nil {
+		return &s, log.Error(ctx, 500, err, query)
+	}
+	return &s, nil
+}
+
+func newJobId() string {
+	var buf = make([]byte, 12)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+func now() string {
+	return time.Now().UTC().Format(time.RFC3339)
+}
+
+// Insert records a new job as queued.  When maxJobs is more than 0, and the user already has
+// that many jobs queued or running, it returns 429.  The count and the insert are one transaction.
+func (s *JobStore) Insert(job *Job, maxJobs int) *log.Status {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	tx, err := s.db.Begin()
+	if err != nil {
+		return log.Error(s.ctx, 500, err, `Error starting job insert`)
+	}
+	defer tx.Rollback()
+	if maxJobs > 0 {
+		var active int
+		query := `SELECT COUNT(*) FROM jobs WHERE username = ? AND state IN (?,?)`
+		err = tx.QueryRow(query, job.Username, JobQueued, JobRunning).Scan(&active)
+		if err != nil {
+			return log.Error(s.ctx, 500, err, query)
+		}
+		if active >= maxJobs {
+			return log.ErrorNoErr(s.ctx, http.StatusTooManyRequests, job.Username, `has`, active,
+				`jobs queued or running, the limit is`, maxJobs)
+		}
+	}
+	job.JobId = newJobId()
+	job.State = JobQueued
+	job.Created = now()
+	job.Updated = job.Created
+	query := `INSERT INTO jobs (job_id, username, dataset_name, state, yaml, has_post_files, created, updated)
+		VALUES (?,?,?,?,?,?,?,?)`
+	_, err = tx.Exec(query, job.JobId, job.Username, job.DatasetName, job.State, job.yaml,
+		job.hasPostFiles, job.Created, job.Updated)
+	if err != nil {
+		return log.Error(s.ctx, 500, err, `Error inserting job`)
+	}
+	err = tx.Commit()
+	if err != nil {
+		return log.Error(s.ctx, 500, err, `Error committing job insert`)
+	}
+	return nil
+}
+
+// UpdateStarted moves a queued job to running.  It returns false when the job is no longer queued,
+// e.g. because it was cancelled after a worker took it from the queue.
+func (s *JobStore) UpdateStarted(jobId string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	query := `UPDATE jobs SET state = ?, updated = ? WHERE job_id = ? AND state = ?`
+	result, err := s.db.Exec(query, JobRunning, now(), jobId, JobQueued)
+	if err != nil {
+		log.Warn(s.ctx, err, query)
+		return false
+	}
+	count, err := result.RowsAffected()
+	return err == nil && count > 0
+}
+
+// UpdateCancelled cancels a job that is still queued.  It returns false when a worker
+// has started the job, which must then be cancelled as a running job.
+func (s *JobStore) UpdateCancelled(jobId string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	query := `UPDATE jobs SET state = ?, status = ?, message = ?, outputs = ?, updated = ?
+		WHERE job_id = ? AND state = ?`
+	result, err := s.db.Exec(query, JobCancelled, 499, `Cancelled before start`, `null`, now(), jobId, JobQueued)
+	if err != nil {
+		log.Warn(s.ctx, err, query)
+		return false
+	}
+	count, err := result.RowsAffected()
+	return err == nil && count > 0
+}
+
+// UpdateProgress records the progress of a running job.  It does not change the state,
+// so that progress reported after a cancel does not make the job running again.
+func (s *JobStore) UpdateProgress(jobId string, stage string, chaptersDone int, chaptersTotal int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	query := `UPDATE jobs SET stage = ?, chapters_done = ?, chapters_total = ?, updated = ?
+		WHERE job_id = ? AND state = ?`
+	_, err := s.db.Exec(query, stage, chaptersDone, chaptersTotal, now(), jobId, JobRunning)
+	if err != nil {
+		log.Warn(s.ctx, err, query)
+	}
+}
+
+func (s *JobStore) UpdateFinished(jobId string, state string, status int, message string, outputs []string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	outputJson, _ := json.Marshal(outputs)
+	query := `UPDATE jobs SET state = ?, status = ?, message = ?, outputs = ?, updated = ? WHERE job_id = ?`
+	_, err := s.db.Exec(query, state, status, message, string(outputJson), now(), jobId)
+	if err != nil {
+		log.Warn(s.ctx, err, query)
+	}
+}
+
+const selectJobs = `SELECT job_id, username, dataset_name, state, stage, chapters_done, chapters_total,
+	status, message, outputs, yaml, has_post_files, created, updated FROM jobs`
+
+func (s *JobStore) Select(jobId string) (Job, bool, *log.Status) {
+	jobs, status := s.selectJobs(selectJobs+` WHERE job_id = ?`, jobId)
+	if status != nil || len(jobs) == 0 {
+		return Job{}, false, status
+	}
+	return jobs[0], true, nil
+}
+
+func (s *JobStore) SelectByUser(username string) ([]Job, *log.Status) {
+	return s.selectJobs(selectJobs+` WHERE username = ? ORDER BY created DESC`, username)
+}
+
+func (s *JobStore) SelectByState(state string) ([]Job, *log.Status) {
+	return s.selectJobs(selectJobs+` WHERE state = ? ORDER BY created`, state)
+}
+
+func (s *JobStore) selectJobs(query string, param string) ([]Job, *log.Status) {
+	var results []Job
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	rows, err := s.db.Query(query, param)
+	if err != nil {
+		return results, log.Error(s.ctx, 500, err, query)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var job Job
+		var outputs string
+		err = rows.Scan(&job.JobId, &job.Username, &job.DatasetName, &job.State, &job.Stage, &job.ChaptersDone,
+			&job.ChaptersTotal, &job.Status, &job.Message, &outputs, &job.yaml, &job.hasPostFiles,
+			&job.Created, &job.Updated)
+		if err != nil {
+			return results, log.Error(s.ctx, 500, err, query)
+		}
+		err = json.Unmarshal([]byte(outputs), &job.Outputs)
+		if err != nil {
+			log.Warn(s.ctx, err, `Error decoding outputs of job`, job.JobId)
+		}
+		if job.ChaptersTotal > 0 {
+			job.Percent = float64(job.ChaptersDone*1000/job.ChaptersTotal) / 10.0
+		}
+		results = append(results, job)
+	}
+	err = rows.Err()
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Warn(s.ctx, err, query)
+	}
+	return results, nil
+}
+
+func (s *JobStore) Close() {
+	_ = s.db.Close()
+}
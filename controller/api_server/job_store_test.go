@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestJobStore(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv(`FCBH_DATASET_DB`, t.TempDir())
+	store, status := NewJobStore(ctx)
+	if status != nil {
+		t.Fatal(status)
+	}
+	defer store.Close()
+	var job Job
+	job.Username = `GaryNTest`
+	job.DatasetName = `TestJobStore`
+	job.yaml = []byte(`dataset_name: TestJobStore`)
+	status = store.Insert(&job, 0)
+	if status != nil {
+		t.Fatal(status)
+	}
+	if !store.UpdateStarted(job.JobId) {
+		t.Fatal(`Queued job was not started`)
+	}
+	store.UpdateProgress(job.JobId, `timestamps`, 13, 260)
+	found, ok, status := store.Select(job.JobId)
+	if status != nil || !ok {
+		t.Fatal(`Job not found`, status)
+	}
+	if found.State != JobRunning || found.Stage != `timestamps` || found.Percent != 5.0 {
+		t.Error(`Unexpected progress`, found.State, found.Stage, found.Percent)
+	}
+	store.UpdateFinished(job.JobId, JobSucceeded, 200, ``, []string{`/tmp/TestJobStore.json`})
+	store.UpdateProgress(job.JobId, `speech_to_text`, 14, 260)
+	jobs, status := store.SelectByUser(`GaryNTest`)
+	if status != nil {
+		t.Fatal(status)
+	}
+	if len(jobs) != 1 || jobs[0].State != JobSucceeded || len(jobs[0].Outputs) != 1 {
+		t.Error(`Unexpected jobs`, jobs)
+	}
+	if string(jobs[0].yaml) != `dataset_name: TestJobStore` {
+		t.Error(`YAML not kept`, string(jobs[0].yaml))
+	}
+	var queued = Job{Username: `GaryNTest`, DatasetName: `TestJobStore`, yaml: []byte(`dataset_name: TestJobStore`)}
+	status = store.Insert(&queued, 1)
+	if status != nil {
+		t.Fatal(status)
+	}
+	var second = Job{Username: `GaryNTest`, DatasetName: `TestJobStore`, yaml: []byte(`dataset_name: TestJobStore`)}
+	status = store.Insert(&second, 1)
+	if status == nil || status.Status != http.StatusTooManyRequests {
+		t.Error(`Job over the limit was inserted`, status)
+	}
+}
+
+func TestCancelStartedJob(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv(`FCBH_DATASET_DB`, t.TempDir())
+	store, status := NewJobStore(ctx)
+	if status != nil {
+		t.Fatal(status)
+	}
+	defer store.Close()
+	runner := NewJobRunner(ctx, store)
+	var job = Job{Username: `GaryNTest`, DatasetName: `TestCancel`, yaml: []byte(`dataset_name: TestCancel`)}
+	status = store.Insert(&job, 0)
+	if status != nil {
+		t.Fatal(status)
+	}
+	selected, _, _ := store.Select(job.JobId) // selected while queued
+	jobCtx, cancel := context.WithCancel(ctx)
+	runner.cancels[job.JobId] = cancel
+	if !store.UpdateStarted(job.JobId) {
+		t.Fatal(`Queued job was not started`)
+	}
+	if !runner.Cancel(selected) {
+		t.Fatal(`Job started after it was selected was not cancelled`)
+	}
+	if jobCtx.Err() == nil {
+		t.Error(`Expected the running job to be stopped`)
+	}
+	found, _, _ := store.Select(job.JobId)
+	if found.State != JobRunning {
+		t.Error(`Expected the job to stay running until it stops`, found.State)
+	}
+	var queued = Job{Username: `GaryNTest`, DatasetName: `TestCancel`, yaml: []byte(`dataset_name: TestCancel`)}
+	_ = store.Insert(&queued, 0)
+	selected, _, _ = store.Select(queued.JobId)
+	if !runner.Cancel(selected) || store.UpdateStarted(queued.JobId) {
+		t.Error(`Expected the queued job to be cancelled, and not to start`)
+	}
+}
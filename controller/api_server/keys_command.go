@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+const keysUsage = `Usage:
+  api_server keys add {username}       makes a key, which is only shown once
+  api_server keys list [username]      lists key ids
+  api_server keys revoke {key_id}      revokes a key
+  api_server limits {username} {max_jobs} {max_storage_bytes}   sets limits, 0 is unlimited`
+
+// keysCommand runs the keys and limits subcommands, and returns the exit code
+func keysCommand(ctx context.Context, args []string) int {
+	store, status := NewAuthStore(ctx)
+	if status != nil {
+		fmt.Fprintln(os.Stderr, status.String())
+		return 1
+	}
+	defer store.Close()
+	if len(args) == 4 && args[0] == `limits` {
+		var limits = UserLimits{Username: args[1]}
+		var err error
+		limits.MaxJobs, err = strconv.Atoi(args[2])
+		if err == nil {
+			limits.MaxStorage, err = strconv.ParseInt(args[3], 10, 64)
+		}
+		if err != nil || limits.MaxJobs < 0 || limits.MaxStorage < 0 {
+			fmt.Fprintln(os.Stderr, keysUsage)
+			return 2
+		}
+		status = store.UpdateLimits(limits)
+		if status != nil {
+			fmt.Fprintln(os.Stderr, status.String())
+			return 1
+		}
+		fmt.Println(limits.Username, `max_jobs`, limits.MaxJobs, `max_storage`, limits.MaxStorage)
+		return 0
+	}
+	if len(args) < 2 || args[0] != `keys` {
+		fmt.Fprintln(os.Stderr, keysUsage)
+		return 2
+	}
+	switch {
+	case args[1] == `add` && len(args) == 3:
+		key, rec, status := store.InsertKey(args[2])
+		if status != nil {
+			fmt.Fprintln(os.Stderr, status.String())
+			return 1
+		}
+		fmt.Println(`key_id:`, rec.KeyId, `username:`, rec.Username)
+		fmt.Println(key)
+		return 0
+	case args[1] == `list` && len(args) <= 3:
+		var username string
+		if len(args) == 3 {
+			username = args[2]
+		}
+		keys, status := store.SelectKeys(username)
+		if status != nil {
+			fmt.Fprintln(os.Stderr, status.String())
+			return 1
+		}
+		for _, rec := range keys {
+			fmt.Println(rec.KeyId, rec.Username, rec.Created)
+		}
+		return 0
+	case args[1] == `revoke` && len(args) == 3:
+		found, status := store.DeleteKey(args[2])
+		if status != nil {
+			fmt.Fprintln(os.Stderr, status.String())
+			return 1
+		}
+		if !found {
+			fmt.Fprintln(os.Stderr, `No key`, args[2])
+			return 1
+		}
+		fmt.Println(`Revoked`, args[2])
+		return 0
+	}
+	fmt.Fprintln(os.Stderr, keysUsage)
+	return 2
+}
@@ -0,0 +1,202 @@
+package controller
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/db"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/input"
+	log "github.com/faithcomesbyhearing/fcbh-dataset-io/logger"
+)
+
+// Stage names recorded in the checkpoints table of a dataset.
+// Reports (audio_proof, compare) and output are not checkpointed, because the
+// files they produce are the outputs of each run and are cheap to regenerate.
+// Training is not checkpointed here, because the trainers already reuse an existing model.
+const (
+	stageReadText      = `read_text`
+	stageTimestamps    = `timestamps`
+	stageCopyForSTT    = `copy_for_stt`
+	stageSpeechToText  = `speech_to_text`
+	stageAudioEncoding = `audio_encoding`
+	stageTextEncoding  = `text_encoding`
+	stageUpdateDBP     = `update_dbp`
+)
+
+// hashInputs computes a digest of the request sections that configure a stage and
+// the files it reads, so that a change to either causes the stage to be rerun.
+// The filename, size and modification time are used, rather than the directory,
+// because POST files are in a new temp directory each run.
+func hashInputs(files []input.InputFile, sections ...any) string {
+	hash := sha256.New()
+	for _, section := range sections {
+		content, _ := json.Marshal(section)
+		hash.Write(content)
+	}
+	for _, file := range files {
+		hash.Write([]byte(file.Filename))
+		info, err := os.Stat(file.FilePath())
+		if err == nil {
+			hash.Write([]byte(strconv.FormatInt(info.Size(), 10)))
+			hash.Write([]byte(strconv.FormatInt(info.ModTime().UnixNano(), 10)))
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// stageDone is true when a prior run of the same request completed this stage with the same inputs.
+// Checkpoints are only consulted when is_new is false, because a new dataset has none.
+// Once a stage has rerun, the checkpoints of the stages after it are stale, because
+// they were computed from what that stage had produced before.
+func (c *Controller) stageDone(stage string, bookId string, chapter int, inputHash string) bool {
+	if c.req.IsNew {
+		return false
+	}
+	if c.rerunStage != `` && c.rerunStage != stage {
+		return false
+	}
+	prior, status := c.database.SelectCheckpoint(stage, bookId, chapter)
+	if status != nil {
+		return false
+	}
+	return prior == inputHash
+}
+
+// stageRerun records the first stage that does work in this run
+func (c *Controller) stageRerun(stage string) {
+	if c.rerunStage == `` {
+		c.rerunStage = stage
+	}
+}
+
+func (c *Controller) stageComplete(stage string, bookId string, chapter int, inputHash string) *log.Status {
+	var rec db.Checkpoint
+	rec.Stage = stage
+	rec.BookId = bookId
+	rec.ChapterNum = chapter
+	rec.InputHash = inputHash
+	return c.database.InsertCheckpoints([]db.Checkpoint{rec})
+}
+
+// runStage runs a stage that processes the dataset as a whole, unless it was completed by a prior run.
+func (c *Controller) runStage(stage string, inputHash string, process func() *log.Status) *log.Status {
+	if c.stageDone(stage, ``, 0, inputHash) {
+		log.Info(c.ctx, "Skip", stage, "completed by prior run.")
+		return nil
+	}
+	c.stageRerun(stage)
+	status := process()
+	if status != nil {
+		return status
+	}
+	return c.stageComplete(stage, ``, 0, inputHash)
+}
+
+// runByChapter runs a stage that processes audio chapter by chapter.  The chapters that a prior
+// run did not complete are given to process in one call, so that each engine starts once.
+// A process calls input.FileDone as it writes the output of each file, and each chapter is
+// checkpointed when all of its files are done, so that a failed run resumes at the first
+// incomplete chapter.  The chapters of a process that does not call it are checkpointed
+// when it returns.
+func (c *Controller) runByChapter(stage string, files []input.InputFile, process func([]input.InputFile) *log.Status,
+	sections ...any) *log.Status {
+	type chapterState struct {
+		rec     db.Checkpoint
+		files   int
+		written int
+		done    bool
+	}
+	var pending []input.InputFile
+	var chapters []*chapterState
+	var byChapter = make(map[string]*chapterState)
+	var chaptersTotal, chaptersDone int
+	for _, book := range groupByBookChapter(files) {
+		var skipped int
+		for _, chapter := range book {
+			chaptersTotal++
+			var rec db.Checkpoint
+			rec.Stage = stage
+			rec.BookId = chapter[0].BookId
+			rec.ChapterNum = chapter[0].Chapter
+			rec.InputHash = hashInputs(chapter, sections...)
+			if c.stageDone(stage, rec.BookId, rec.ChapterNum, rec.InputHash) {
+				skipped++
+				continue
+			}
+			pending = append(pending, chapter...)
+			state := &chapterState{rec: rec, files: len(chapter)}
+			chapters = append(chapters, state)
+			byChapter[rec.BookId+` `+strconv.Itoa(rec.ChapterNum)] = state
+		}
+		if skipped == len(book) {
+			log.Info(c.ctx, "Skip", stage, book[0][0].BookId, "completed by prior run.")
+		}
+		chaptersDone += skipped
+	}
+	c.reportChapters(stage, chaptersDone, chaptersTotal)
+	if len(pending) == 0 {
+		return nil
+	}
+	if c.ctx.Err() != nil {
+		return log.ContextError(c.ctx, `Request stopped during`, stage)
+	}
+	c.stageRerun(stage)
+	var stageCtx = c.ctx
+	var start = time.Now()
+	var checkpointStatus *log.Status
+	var complete = func(state *chapterState) {
+		if state.done || checkpointStatus != nil {
+			return
+		}
+		state.done = true
+		checkpointStatus = c.database.InsertCheckpoints([]db.Checkpoint{state.rec})
+		c.recordChapters(stage, 1, start)
+		start = time.Now()
+		chaptersDone++
+		c.reportChapters(stage, chaptersDone, chaptersTotal)
+		log.Info(log.WithChapter(stageCtx, state.rec.BookId, state.rec.ChapterNum), stage, "done",
+			chaptersDone, "of", chaptersTotal, "chapters.")
+	}
+	c.ctx = input.WithFileDone(stageCtx, func(file input.InputFile) {
+		state, ok := byChapter[file.BookId+` `+strconv.Itoa(file.Chapter)]
+		if ok {
+			state.written++
+			if state.written >= state.files {
+				complete(state)
+			}
+		}
+	})
+	status := process(pending)
+	c.ctx = stageCtx
+	if status != nil {
+		return status
+	}
+	for _, state := range chapters {
+		complete(state)
+	}
+	return checkpointStatus
+}
+
+// groupByBookChapter groups files by book, and within a book by chapter, keeping their order.
+// Files that hold one script line each share the chapter of that line.
+func groupByBookChapter(files []input.InputFile) [][][]input.InputFile {
+	var books [][][]input.InputFile
+	for _, file := range files {
+		lastBook := len(books) - 1
+		if lastBook < 0 || books[lastBook][0][0].BookId != file.BookId {
+			books = append(books, [][]input.InputFile{{file}})
+			continue
+		}
+		lastChap := len(books[lastBook]) - 1
+		if books[lastBook][lastChap][0].Chapter != file.Chapter {
+			books[lastBook] = append(books[lastBook], []input.InputFile{file})
+		} else {
+			books[lastBook][lastChap] = append(books[lastBook][lastChap], file)
+		}
+	}
+	return books
+}
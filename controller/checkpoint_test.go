@@ -0,0 +1,105 @@
+package controller
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/db"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/decode_yaml/request"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/input"
+	log "github.com/faithcomesbyhearing/fcbh-dataset-io/logger"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/timestamp/provider"
+)
+
+func TestHashInputsModTime(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, `B01___01_Matthew_____ENGWEBN2DA.mp3`)
+	err := os.WriteFile(path, []byte(`first`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := []input.InputFile{{Directory: dir, Filename: filepath.Base(path)}}
+	before := hashInputs(files)
+	err = os.WriteFile(path, []byte(`again`), 0644) // same size
+	if err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	err = os.Chtimes(path, later, later)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hashInputs(files) == before {
+		t.Error(`Replaced file of the same size has the same hash`)
+	}
+}
+
+func TestStageDoneAfterRerun(t *testing.T) {
+	var c Controller
+	c.stageRerun(stageReadText)
+	c.stageRerun(stageTimestamps)
+	if c.rerunStage != stageReadText {
+		t.Error(`Expected first rerun stage`, stageReadText, `got`, c.rerunStage)
+	}
+	if c.stageDone(stageTimestamps, `MAT`, 1, ``) {
+		t.Error(`Stage after a rerun stage is done`)
+	}
+}
+
+func TestChainSegments(t *testing.T) {
+	chain, ok := provider.Chain(request.Timestamps{Chain: []string{`bible_brain`, `mms_fa_verse`, `mms_align`}})
+	if !ok {
+		t.Fatal(`Chain has an unknown provider`)
+	}
+	segments := chainSegments(chain)
+	if len(segments) != 2 || len(segments[0]) != 1 || segments[0][0].Name != `bible_brain` || len(segments[1]) != 2 {
+		t.Error(`Unexpected segments`, segments)
+	}
+}
+
+func TestRunByChapterResume(t *testing.T) {
+	var c Controller
+	c.ctx = context.Background()
+	c.database = db.NewDBAdapter(c.ctx, `:memory:`)
+	defer c.database.Close()
+	var files []input.InputFile
+	for _, ref := range []struct {
+		book    string
+		chapter int
+	}{{`MAT`, 1}, {`MAT`, 2}, {`MAT`, 3}, {`MRK`, 1}} {
+		files = append(files, input.InputFile{BookId: ref.book, Chapter: ref.chapter, Filename: ref.book + strconv.Itoa(ref.chapter)})
+	}
+	status := c.runByChapter(stageSpeechToText, files, func(files []input.InputFile) *log.Status {
+		for _, file := range files {
+			if file.BookId == `MAT` && file.Chapter == 3 {
+				return log.ErrorNoErr(c.ctx, 500, `Failed`, file.Filename)
+			}
+			input.FileDone(c.ctx, file)
+		}
+		return nil
+	})
+	if status == nil {
+		t.Fatal(`Expected the failure of MAT 3`)
+	}
+	c.rerunStage = ``
+	var calls [][]input.InputFile
+	status = c.runByChapter(stageSpeechToText, files, func(files []input.InputFile) *log.Status {
+		calls = append(calls, files)
+		return nil
+	})
+	if status != nil {
+		t.Fatal(status)
+	}
+	if len(calls) != 1 || len(calls[0]) != 2 || calls[0][0].Filename != `MAT3` || calls[0][1].Filename != `MRK1` {
+		t.Error(`Expected one call with the chapters after the failure`, calls)
+	}
+	for _, file := range files {
+		if !c.stageDone(stageSpeechToText, file.BookId, file.Chapter, hashInputs([]input.InputFile{file})) {
+			t.Error(`Chapter was not checkpointed`, file.BookId, file.Chapter)
+		}
+	}
+}
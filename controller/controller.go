@@ -4,6 +4,7 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"strconv"
 	"time"
 
 	"github.com/faithcomesbyhearing/fcbh-dataset-io/bible_brain/timestamp/update"
@@ -17,16 +18,16 @@ import (
 	log "github.com/faithcomesbyhearing/fcbh-dataset-io/logger"
 	"github.com/faithcomesbyhearing/fcbh-dataset-io/match/align"
 	"github.com/faithcomesbyhearing/fcbh-dataset-io/match/diff"
-	"github.com/faithcomesbyhearing/fcbh-dataset-io/mms"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/match/report"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/match/review"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/metrics"
 	"github.com/faithcomesbyhearing/fcbh-dataset-io/mms/adapter"
-	"github.com/faithcomesbyhearing/fcbh-dataset-io/mms/asr_align"
-	"github.com/faithcomesbyhearing/fcbh-dataset-io/mms/mms_align"
-	"github.com/faithcomesbyhearing/fcbh-dataset-io/mms/mms_asr"
 	"github.com/faithcomesbyhearing/fcbh-dataset-io/output"
 	"github.com/faithcomesbyhearing/fcbh-dataset-io/read"
-	"github.com/faithcomesbyhearing/fcbh-dataset-io/speech_to_text"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/speech_to_text/stt"
 	"github.com/faithcomesbyhearing/fcbh-dataset-io/timestamp"
-	asr2 "github.com/faithcomesbyhearing/fcbh-dataset-io/wav2vec2/asr"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/timestamp/ensemble"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/timestamp/provider"
 	"github.com/faithcomesbyhearing/fcbh-dataset-io/wav2vec2/train"
 )
 
@@ -37,12 +38,19 @@ type OutputFiles struct {
 
 type Controller struct {
 	ctx         context.Context
+	jobCtx      context.Context    // ctx of the whole job, c.ctx is that of the current stage
+	stageCancel context.CancelFunc // releases the timeout of the current stage
+	stage       string
+	stageStart  time.Time
+	stopped     *log.Status // set when a stage was cancelled or timed out
 	yamlRequest []byte
 	req         request.Request
 	bucket      courier.Courier
 	ident       db.Ident
 	database    db.DBAdapter
 	postFiles   *input.PostFiles
+	rerunStage  string // the first checkpointed stage that does work in this run
+	progress    Progress
 }
 
 func NewController(ctx context.Context, yamlContent []byte) Controller {
@@ -79,7 +87,12 @@ func (c *Controller) ProcessV2() (OutputFiles, *log.Status) {
 		defer c.postFiles.RemoveDir()
 	}
 	log.Debug(c.ctx)
+	metrics.JobsStarted.Inc(stageDecode)
 	var status = c.processSteps()
+	if status != nil && c.stopped != nil && !status.IsCancelled() {
+		status = c.stopped // report the cancel or timeout, not the error it caused
+	}
+	c.recordJob(status)
 	if status != nil {
 		filename := c.outputStatus(*status)
 		c.bucket.AddOutput(filename)
@@ -89,8 +102,10 @@ func (c *Controller) ProcessV2() (OutputFiles, *log.Status) {
 	output.FilePaths = c.bucket.GetOutputPaths()
 	log.Info(c.ctx, "Duration", time.Since(start))
 	log.Debug(c.ctx)
+	c.bucket.SetJobId(log.GetFields(c.ctx).JobId)
 	_ = c.bucket.PersistToBucket()                              // do not propagate error
 	_ = c.bucket.Notification(c.req, status, time.Since(start)) // do not propagate error
+	_ = c.bucket.PersistLog()                                   // adds the notification results to the log
 	return output, status
 }
 
@@ -99,6 +114,7 @@ func (c *Controller) processSteps() *log.Status {
 	var status *log.Status
 	// Decode YAML Request File
 	log.Info(c.ctx, "Parse .yaml file.")
+	c.reportChapters(stageDecode, 0, 0)
 	reqDecoder := decode_yaml.NewRequestDecoder(c.ctx)
 	c.req, status = reqDecoder.Process(c.yamlRequest)
 	if status != nil {
@@ -116,6 +132,12 @@ func (c *Controller) processSteps() *log.Status {
 	}()
 	defer close(done)
 	c.ctx = context.WithValue(c.ctx, `request`, string(c.yamlRequest))
+	c.ctx = log.WithJob(c.ctx, log.GetFields(c.ctx).JobId, c.req.Username, c.req.DatasetName)
+	var cancelJob context.CancelFunc
+	c.ctx, cancelJob = withTimeout(c.ctx, c.req.Timeouts.Job)
+	defer cancelJob()
+	c.jobCtx = c.ctx
+	defer c.endStage()
 	// Open Database
 	if c.req.Database.AWSS3 != "" {
 		// Create an empty database in order to get the correct path.
@@ -142,6 +164,10 @@ func (c *Controller) processSteps() *log.Status {
 		return status
 	}
 	// Update Ident Data from DBP
+	status = c.beginStage(stageFetch)
+	if status != nil {
+		return status
+	}
 	c.ident, status = c.fetchData()
 	if status != nil {
 		if c.req.TextData.AnyBibleBrain() || c.req.AudioData.AnyBibleBrain() {
@@ -160,7 +186,14 @@ func (c *Controller) processSteps() *log.Status {
 	// Read Text Data
 	if !c.req.TextData.NoText {
 		log.Info(c.ctx, "Read and parse text files.")
-		status = c.readText(textFiles)
+		status = c.beginStage(stageReadText)
+		if status != nil {
+			return status
+		}
+		hash := hashInputs(textFiles, c.req.TextData, c.req.Testament, c.req.Detail)
+		status = c.runStage(stageReadText, hash, func() *log.Status {
+			return c.readText(textFiles)
+		})
 		if status != nil {
 			return status
 		}
@@ -182,7 +215,11 @@ func (c *Controller) processSteps() *log.Status {
 	// Timestamps
 	if len(audioFiles) > 0 {
 		log.Info(c.ctx, "Read or create audio timestamp data.")
-		status = c.timestamps(audioFiles)
+		status = c.beginStage(stageTimestamps)
+		if status != nil {
+			return status
+		}
+		status = c.checkpointTimestamps(audioFiles)
 		if status != nil {
 			return status
 		}
@@ -190,6 +227,10 @@ func (c *Controller) processSteps() *log.Status {
 	// Train MMS Adapter
 	if !c.req.Training.NoTraining {
 		log.Info(c.ctx, "Train", c.ident.LanguageISO)
+		status = c.beginStage(stageTraining)
+		if status != nil {
+			return status
+		}
 		if c.req.Training.MMSAdapter.NumEpochs != 0 {
 			trainer := adapter.NewTrainAdapter(c.ctx, c.database, c.ident.LanguageISO, c.req.Training.MMSAdapter)
 			if c.req.Training.RedoTraining || !trainer.HasModel() {
@@ -212,15 +253,11 @@ func (c *Controller) processSteps() *log.Status {
 	// Copy for STT
 	//if !c.req.TextData.NoText &&
 	if !c.req.SpeechToText.NoSpeechToText {
-		c.req.Compare.BaseDataset = c.database.Project
-		c.req.AudioProof.BaseDataset = c.database.Project // ? should there be one BaseDataset ?
-		// This makes a copy of database, and closes it.  Names the new database *_audio, and returns new
-		c.database, status = c.database.CopyDatabase(`_audio`)
+		status = c.beginStage(stageCopyForSTT)
 		if status != nil {
 			return status
 		}
-		c.bucket.AddDatabase(c.database)
-		status = c.database.UpdateEraseScriptText()
+		status = c.copyForSTT()
 		if status != nil {
 			return status
 		}
@@ -228,7 +265,11 @@ func (c *Controller) processSteps() *log.Status {
 	// Speech to Text
 	if !c.req.SpeechToText.NoSpeechToText {
 		log.Info(c.ctx, "Perform speech to text.")
-		status = c.speechToText(audioFiles)
+		status = c.beginStage(stageSpeechToText)
+		if status != nil {
+			return status
+		}
+		status = c.runByChapter(stageSpeechToText, audioFiles, c.speechToText, c.req.SpeechToText, c.req.AltLanguage)
 		if status != nil {
 			return status
 		}
@@ -236,7 +277,11 @@ func (c *Controller) processSteps() *log.Status {
 	// Encode Audio
 	if !c.req.AudioEncoding.NoEncoding {
 		log.Info(c.ctx, "Perform audio encoding.")
-		status = c.encodeAudio(audioFiles)
+		status = c.beginStage(stageAudioEncoding)
+		if status != nil {
+			return status
+		}
+		status = c.runByChapter(stageAudioEncoding, audioFiles, c.encodeAudio, c.req.AudioEncoding, c.req.Detail)
 		if status != nil {
 			return status
 		}
@@ -244,7 +289,23 @@ func (c *Controller) processSteps() *log.Status {
 	// Encode Text
 	if !c.req.TextEncoding.NoEncoding {
 		log.Info(c.ctx, "Perform text encoding.")
-		status = c.encodeText()
+		status = c.beginStage(stageTextEncoding)
+		if status != nil {
+			return status
+		}
+		status = c.runStage(stageTextEncoding, hashInputs(nil, c.req.TextEncoding), c.encodeText)
+		if status != nil {
+			return status
+		}
+	}
+	// Reviewer Verdicts
+	if len(c.req.Verdicts.Import) > 0 || c.req.Verdicts.Stats {
+		log.Info(c.ctx, "Import reviewer verdicts.")
+		status = c.beginStage(stageVerdicts)
+		if status != nil {
+			return status
+		}
+		status = c.verdicts()
 		if status != nil {
 			return status
 		}
@@ -252,38 +313,84 @@ func (c *Controller) processSteps() *log.Status {
 	// Audio Proofing
 	if c.req.AudioProof.HTMLReport {
 		log.Info(c.ctx, "Perform audio proof Report.")
-		filename, status = c.audioProofing(audioFiles)
+		status = c.beginStage(stageAudioProof)
 		if status != nil {
 			return status
 		}
-		c.bucket.AddOutput(filename)
+		var filenames []string
+		filenames, status = c.audioProofing(audioFiles)
+		if status != nil {
+			return status
+		}
+		for _, filename = range filenames {
+			c.bucket.AddOutput(filename)
+		}
 	}
 	// Compare
 	if c.req.Compare.HTMLReport {
 		log.Info(c.ctx, "Perform text comparison.")
-		filename, status = c.matchText()
+		status = c.beginStage(stageCompare)
+		if status != nil {
+			return status
+		}
+		var filenames []string
+		filenames, status = c.matchText(audioFiles)
+		if status != nil {
+			return status
+		}
+		for _, filename = range filenames {
+			c.bucket.AddOutput(filename)
+		}
+	}
+	// Timestamp Ensemble
+	if len(c.req.TSEnsemble.Datasets) > 0 {
+		log.Info(c.ctx, "Compare timestamp sets.")
+		status = c.beginStage(stageTSEnsemble)
+		if status != nil {
+			return status
+		}
+		status = c.timestampEnsemble()
 		if status != nil {
 			return status
 		}
-		c.bucket.AddOutput(filename)
 	}
 	// Update DBP Timestamps
 	if len(c.req.UpdateDBP.Timestamps) > 0 {
 		log.Info(c.ctx, "Update DBP timestamps.")
+		status = c.beginStage(stageUpdateDBP)
+		if status != nil {
+			return status
+		}
 		upd := update.NewUpdateTimestamps(c.ctx, c.req, c.database)
-		status = upd.Process()
+		status = c.runStage(stageUpdateDBP, hashInputs(nil, c.req.UpdateDBP), upd.Process)
 		if status != nil {
 			return status
 		}
 	}
 	// Prepare output
 	log.Info(c.ctx, "Generate output.")
+	status = c.beginStage(stageOutput)
+	if status != nil {
+		return status
+	}
 	if c.req.Output.Sqlite {
 		c.bucket.AddOutput(c.database.DatabasePath)
 	}
 	if c.req.Output.CSV || c.req.Output.JSON {
 		status = c.output()
 		// added to bucket in c.output()
+		if status != nil {
+			return status
+		}
+	}
+	if c.req.Output.WebVTT || c.req.Output.SRT {
+		status = c.outputSubtitles()
+		if status != nil {
+			return status
+		}
+	}
+	if len(c.req.Output.Corpus.Formats) > 0 {
+		status = c.outputCorpus(audioFiles)
 	}
 	return status
 }
@@ -376,6 +483,12 @@ func (c *Controller) readText(textFiles []input.InputFile) *log.Status {
 		if status != nil {
 			return status
 		}
+	} else if textFiles[0].MediaType == request.TextUSFM {
+		reader := read.NewUSFMParser(c.database)
+		status = reader.ProcessFiles(textFiles)
+		if status != nil {
+			return status
+		}
 	} else if textFiles[0].MediaType == request.TextPlainEdit {
 		reader := read.NewDBPTextEditReader(c.database, c.req)
 		status = reader.Process()
@@ -410,91 +523,198 @@ func (c *Controller) readText(textFiles []input.InputFile) *log.Status {
 	return status
 }
 
-func (c *Controller) timestamps(audioFiles []input.InputFile) *log.Status {
+// checkpointTimestamps runs the timestamp stage.  The chain is run in segments of consecutive
+// providers that are alike.  Those that compute timestamps from the audio files are checkpointed
+// by chapter, and those that process the whole dataset, such as bible_brain, are checkpointed once.
+// A segment after the first is only given the chapters that are still without timestamps.
+func (c *Controller) checkpointTimestamps(audioFiles []input.InputFile) *log.Status {
 	var status *log.Status
-	if c.req.Timestamps.BibleBrain {
-		//
-		// Why isn't Bible Brain just processing input??
-		//
-		var filesetIds = []string{c.ident.AudioOTId, c.ident.AudioNTId}
-		for _, filesetId := range filesetIds {
-			if filesetId != `` {
-				api := fetch.NewAPIDBPTimestamps(c.database, filesetId)
-				// Load returns bool, which could be used to invoke aeneas
-				_, status = api.LoadTimestamps(c.req.Testament)
-				if status != nil {
-					return status
-				}
+	ts := c.req.Timestamps
+	chain, _ := provider.Chain(ts)
+	if ts.NoTimestamps || len(chain) == 0 {
+		return c.timestamps(audioFiles, chain, false)
+	}
+	var files = audioFiles
+	segments := chainSegments(chain)
+	for i, segment := range segments {
+		stage := stageTimestamps
+		if i > 0 {
+			stage += `_` + strconv.Itoa(i+1) // each segment has its own checkpoints
+			files, status = c.withoutTimestamps(files)
+			if status != nil {
+				return status
+			}
+			if len(files) == 0 {
+				break
 			}
+			log.Info(c.ctx, "Timestamps by", segment[0].Name, "for", len(files), "files without timestamps.")
 		}
-	} else if c.req.Timestamps.Aeneas {
-		bibleId := c.req.BibleId
-		aeneas := encode.NewAeneas(c.ctx, c.database, bibleId, c.ident.LanguageISO, c.req.Detail)
-		status = aeneas.ProcessFiles(audioFiles)
-	} else if c.req.Timestamps.TSBucket {
-		var ts timestamp.TSBucket
-		ts, status = timestamp.NewTSBucket(c.ctx, c.database)
-		if status == nil {
-			status = ts.ProcessFiles(audioFiles)
+		process := func(files []input.InputFile) *log.Status {
+			return c.timestamps(files, segment, i < len(segments)-1)
 		}
-	} else if c.req.Timestamps.MMSFAVerse {
-		var ts mms.ForcedAlign
-		ts = mms.NewForcedAlign(c.ctx, c.database, c.ident.LanguageISO, c.req.AltLanguage)
-		status = ts.ProcessFiles(audioFiles)
-	} else if c.req.Timestamps.MMSAlign {
-		var ts mms_align.MMSAlign
-		ts = mms_align.NewMMSAlign(c.ctx, c.database, c.ident.LanguageISO, c.req.AltLanguage)
-		status = ts.ProcessFiles(audioFiles)
-		if status != nil {
-			return status
+		if segment[0].ByChapter {
+			status = c.runByChapter(stage, files, process, ts, c.req.AltLanguage)
+		} else {
+			hash := hashInputs(nil, ts, c.ident.AudioOTId, c.ident.AudioNTId)
+			status = c.runStage(stage, hash, func() *log.Status {
+				return process(files)
+			})
 		}
-	} else { // No timestamps, but has audio files
-		status = timestamp.UpdateFilenames(c.ctx, c.database, audioFiles)
 		if status != nil {
 			return status
 		}
 	}
-	return status
+	return nil
 }
 
-func (c *Controller) speechToText(audioFiles []input.InputFile) *log.Status {
+// chainSegments splits a chain of providers where ByChapter changes
+func chainSegments(chain []provider.Info) [][]provider.Info {
+	var segments [][]provider.Info
+	for i, info := range chain {
+		if i == 0 || info.ByChapter != chain[i-1].ByChapter {
+			segments = append(segments, []provider.Info{info})
+		} else {
+			segments[len(segments)-1] = append(segments[len(segments)-1], info)
+		}
+	}
+	return segments
+}
+
+// timestamps runs each provider of the chain in turn.  The providers after the first
+// are only given the files of chapters that are still without timestamps.
+// fallback is true when another segment of the chain follows this one.
+func (c *Controller) timestamps(audioFiles []input.InputFile, chain []provider.Info, fallback bool) *log.Status {
 	var status *log.Status
-	bibleId := c.req.BibleId
-	if c.req.SpeechToText.MMS {
-		var asr mms_asr.MMSASR
-		asr = mms_asr.NewMMSASR(c.ctx, c.database, c.ident.LanguageISO, c.req.AltLanguage, false)
-		status = asr.ProcessFiles(audioFiles)
-	} else if c.req.SpeechToText.MMSAdapter {
-		var asr mms_asr.MMSASR
-		asr = mms_asr.NewMMSASR(c.ctx, c.database, c.ident.LanguageISO, c.req.AltLanguage, true)
-		status = asr.ProcessFiles(audioFiles)
-	} else if c.req.SpeechToText.Wav2Vec2ASR {
-		var asr asr2.Wav2Vec2ASR
-		asr = asr2.NewWav2Vec2ASR(c.ctx, c.database, c.ident.LanguageISO, c.req.AltLanguage)
-		status = asr.ProcessFiles(audioFiles)
-	} else if c.req.SpeechToText.MMSASRAlign {
-		var asr asr_align.ASRAlign
-		asr = asr_align.NewASRAlign(c.ctx, c.database, c.ident.LanguageISO, c.req.AltLanguage, false)
-		status = asr.ProcessFiles(audioFiles)
-	} else {
-		var whisperModel = c.req.SpeechToText.Whisper.Model.String()
-		if whisperModel != `` {
-			var lang2 = c.req.AltLanguage
-			var whisper = speech_to_text.NewWhisper(bibleId, c.database, whisperModel, lang2)
-			status = whisper.ProcessFiles(audioFiles)
+	if c.req.Timestamps.NoTimestamps || len(chain) == 0 { // No timestamps, but has audio files
+		return timestamp.UpdateFilenames(c.ctx, c.database, audioFiles)
+	}
+	var cfg provider.Config
+	cfg.Ctx = c.ctx
+	cfg.Conn = c.database
+	cfg.BibleId = c.req.BibleId
+	cfg.LanguageISO = c.ident.LanguageISO
+	cfg.AltLanguage = c.req.AltLanguage
+	cfg.AudioOTId = c.ident.AudioOTId
+	cfg.AudioNTId = c.ident.AudioNTId
+	cfg.Workers = c.req.Workers
+	cfg.Detail = c.req.Detail
+	cfg.Testament = c.req.Testament
+	var files = audioFiles
+	for i, info := range chain {
+		if i > 0 {
+			files, status = c.withoutTimestamps(files)
 			if status != nil {
 				return status
 			}
-			c.ident.TextSource = request.TextSTT
-			if len(c.ident.AudioOTId) >= 10 {
-				c.ident.TextOTId = c.ident.AudioOTId[:7] + `_TT`
+			if len(files) == 0 {
+				break
 			}
-			if len(c.ident.AudioNTId) >= 10 {
-				c.ident.TextNTId = c.ident.AudioNTId[:7] + `_TT`
+			log.Info(c.ctx, "Timestamps by", info.Name, "for", len(files), "files without timestamps.")
+		}
+		cfg.Fallback = i < len(chain)-1 || fallback
+		cfg.Ctx = c.ctx
+		if i < len(chain)-1 {
+			// A chapter is not done until the providers after this one have had it
+			cfg.Ctx = input.WithFileDone(c.ctx, nil)
+		}
+		var ts provider.Provider
+		ts, status = info.New(cfg)
+		if status != nil {
+			return status
+		}
+		status = ts.ProcessFiles(files)
+		if status != nil {
+			return status
+		}
+	}
+	return status
+}
+
+// withoutTimestamps returns the files of those chapters that have no script timestamps
+func (c *Controller) withoutTimestamps(files []input.InputFile) ([]input.InputFile, *log.Status) {
+	var results []input.InputFile
+	for _, book := range groupByBookChapter(files) {
+		for _, chapter := range book {
+			count, status := c.database.SelectScriptTimestampCount(chapter[0].BookId, chapter[0].Chapter)
+			if status != nil {
+				return results, status
+			}
+			if count == 0 {
+				results = append(results, chapter...)
 			}
-			_ = c.database.UpdateIdent(c.ident)
 		}
 	}
+	return results, nil
+}
+
+// copyForSTT makes a copy of the database named *_audio to hold the speech to text results.
+// When a prior run made the copy, and no earlier stage has changed the database in this run,
+// the existing copy is reopened, so that its speech to text checkpoints are kept.
+func (c *Controller) copyForSTT() *log.Status {
+	var status *log.Status
+	c.req.Compare.BaseDataset = c.database.Project
+	c.req.AudioProof.BaseDataset = c.database.Project // ? should there be one BaseDataset ?
+	audioProject := c.database.Project + `_audio`
+	hash := hashInputs(nil, c.req.SpeechToText)
+	if c.stageDone(stageCopyForSTT, ``, 0, hash) &&
+		db.DatabaseExists(c.req.Username, audioProject) {
+		log.Info(c.ctx, "Skip", stageCopyForSTT, "completed by prior run.")
+		c.database.Close()
+		c.database, status = db.NewerDBAdapter(c.ctx, false, c.req.Username, audioProject)
+		if status != nil {
+			return status
+		}
+		c.bucket.AddDatabase(c.database)
+		return nil
+	}
+	c.stageRerun(stageCopyForSTT)
+	// The checkpoint is recorded before the copy, because the copy becomes c.database
+	status = c.stageComplete(stageCopyForSTT, ``, 0, hash)
+	if status != nil {
+		return status
+	}
+	// This makes a copy of database, and closes it.  Names the new database *_audio, and returns new
+	c.database, status = c.database.CopyDatabase(`_audio`)
+	if status != nil {
+		return status
+	}
+	c.bucket.AddDatabase(c.database)
+	status = c.database.DeleteCheckpoints(stageSpeechToText)
+	if status != nil {
+		return status
+	}
+	status = c.database.UpdateEraseScriptText()
+	return status
+}
+
+func (c *Controller) speechToText(audioFiles []input.InputFile) *log.Status {
+	var status *log.Status
+	engine, ok := stt.Find(c.req.SpeechToText)
+	if !ok {
+		return status
+	}
+	var cfg stt.Config
+	cfg.Ctx = c.ctx
+	cfg.Conn = c.database
+	cfg.BibleId = c.req.BibleId
+	cfg.LanguageISO = c.ident.LanguageISO
+	cfg.AltLanguage = c.req.AltLanguage
+	cfg.Workers = c.req.Workers
+	cfg.SpeechToText = c.req.SpeechToText
+	status = engine.New(cfg).ProcessFiles(audioFiles)
+	if status != nil {
+		return status
+	}
+	if engine.CreatesText {
+		c.ident.TextSource = request.TextSTT
+		if len(c.ident.AudioOTId) >= 10 {
+			c.ident.TextOTId = c.ident.AudioOTId[:7] + `_TT`
+		}
+		if len(c.ident.AudioNTId) >= 10 {
+			c.ident.TextNTId = c.ident.AudioNTId[:7] + `_TT`
+		}
+		_ = c.database.UpdateIdent(c.ident)
+	}
 	return status
 }
 
@@ -520,52 +740,194 @@ func (c *Controller) encodeText() *log.Status {
 	return status
 }
 
-func (c *Controller) audioProofing(audioFiles []input.InputFile) (string, *log.Status) {
+// verdicts imports the verdict files of reviewers, and outputs the false positive rate of
+// each language over the user's datasets, when stats are requested
+func (c *Controller) verdicts() *log.Status {
+	languageISO := c.ident.LanguageISO
+	if languageISO == `` {
+		languageISO = c.req.LanguageISO
+	}
+	_, status := review.Import(c.ctx, c.database, languageISO, c.req.Verdicts.Import)
+	if status != nil {
+		return status
+	}
+	if c.req.Verdicts.Stats {
+		stats, status := review.Stats(c.ctx, c.req.Username)
+		if status != nil {
+			return status
+		}
+		filename, status := review.WriteStats(c.ctx, c.req.DatasetName, stats)
+		if status != nil {
+			return status
+		}
+		c.bucket.AddOutput(filename)
+	}
+	return nil
+}
+
+// audioProofing writes the audio proof as HTML, JSON and XLSX, and returns their filenames
+func (c *Controller) audioProofing(audioFiles []input.InputFile) ([]string, *log.Status) {
 	// Using audioFiles here should be temporary, once the timestamps are updated with duration
 	// there should be no need for the audio files to be present.
-	var filename string
+	var filenames []string
 	var status *log.Status
 	if len(audioFiles) == 0 {
-		return filename, log.ErrorNoErr(c.ctx, 400, "There are no audio files to AudioProof")
+		return filenames, log.ErrorNoErr(c.ctx, 400, "There are no audio files to AudioProof")
 	}
 	audioDir := audioFiles[0].Directory
 	var textConn db.DBAdapter
 	textConn, status = db.NewerDBAdapter(c.ctx, false, c.req.Username, c.req.AudioProof.BaseDataset)
 	if status != nil {
-		return filename, status
+		return filenames, status
 	}
 	calc := align.NewAlignSilence(c.ctx, textConn, c.database) // c.database is ASR result
 	faLines, filenameMap, status := calc.Process(audioDir)
 	if status != nil {
-		return filename, status
+		return filenames, status
+	}
+	verdicts, status := c.database.SelectVerdicts(review.ReportAudioProof)
+	if status != nil {
+		return filenames, status
+	}
+	assets, status := report.NewAssets(c.ctx, c.req.AudioProof.Offline || c.req.AudioProof.Bundle)
+	if status != nil {
+		return filenames, status
+	}
+	var bundleName string
+	if c.req.AudioProof.Bundle {
+		// The bundled report plays its audio from report.AudioDirectory, it is zipped before
+		// the report that is uploaded by itself is written in its place.
+		bundleWriter := align.NewAlignWriter(c.ctx, textConn)
+		bundleWriter.SetVerdicts(verdicts)
+		bundleWriter.SetAssets(assets, report.AudioDirectory)
+		filename, status := bundleWriter.WriteReport(c.req.DatasetName, faLines, filenameMap)
+		if status != nil {
+			return filenames, status
+		}
+		bundleName, status = report.Bundle(c.ctx, filename, audioPaths(audioFiles))
+		if status != nil {
+			return filenames, status
+		}
 	}
 	writer := align.NewAlignWriter(c.ctx, textConn)
-	filename, status = writer.WriteReport(c.req.DatasetName, faLines, filenameMap)
-	return filename, status
+	writer.SetVerdicts(verdicts)
+	writer.SetAssets(assets, ``)
+	filename, status := writer.WriteReport(c.req.DatasetName, faLines, filenameMap)
+	if status != nil {
+		return filenames, status
+	}
+	filenames = append(filenames, filename)
+	if bundleName != `` {
+		filenames = append(filenames, bundleName)
+	}
+	proof, status := writer.ProofReport(c.req.DatasetName, faLines)
+	if status != nil {
+		return filenames, status
+	}
+	filename, status = writer.WriteJSON(proof)
+	if status != nil {
+		return filenames, status
+	}
+	filenames = append(filenames, filename)
+	filename, status = writer.WriteXLSX(proof)
+	if status != nil {
+		return filenames, status
+	}
+	filenames = append(filenames, filename)
+	return filenames, nil
 }
 
-func (c *Controller) matchText() (string, *log.Status) {
+// matchText writes the compare report, and its bundle when requested, and returns their filenames
+func (c *Controller) matchText(audioFiles []input.InputFile) ([]string, *log.Status) {
+	var filenames []string
 	var records []diff.Pair
 	var fileMap string
 	var languageISO string
 	var status *log.Status
 	compare := diff.NewCompare(c.ctx, c.req.Username, c.req.Compare.BaseDataset, c.database, c.ident.LanguageISO, c.req.Testament, c.req.Compare.CompareSettings)
+	compare.SetWordLevel(c.req.Compare.WordLevel)
 	records, fileMap, languageISO, status = compare.Process()
 	if status != nil {
-		return "", status
+		return filenames, status
 	}
 	if c.req.Compare.GordonFilter > 0 {
 		records, status = diff.GordonFilter(c.ctx, records, c.req.Username, c.req.Compare.BaseDataset, c.req.Compare.GordonFilter)
 		if status != nil {
-			return "", status
+			return filenames, status
 		}
 	}
 	tempFilePath := filepath.Join(os.TempDir(), c.database.Project+"_compare.json")
 	c.bucket.AddJson(records, tempFilePath)
+	verdicts, status := c.database.SelectVerdicts(review.ReportCompare)
+	if status != nil {
+		return filenames, status
+	}
+	assets, status := report.NewAssets(c.ctx, c.req.Compare.Offline || c.req.Compare.Bundle)
+	if status != nil {
+		return filenames, status
+	}
+	var modelTitle string
+	if engine, ok := stt.Find(c.req.SpeechToText); ok {
+		modelTitle = engine.Title
+	}
+	var bundleName string
+	if c.req.Compare.Bundle {
+		// The bundled report plays its audio from report.AudioDirectory, it is zipped before
+		// the report that is uploaded by itself is written in its place.
+		bundleWriter := diff.NewHTMLWriter(c.ctx, c.database.Project)
+		bundleWriter.SetVerdicts(verdicts)
+		bundleWriter.SetAssets(assets, report.AudioDirectory)
+		filename, status := bundleWriter.WriteReport(c.req.Compare.BaseDataset, records, languageISO, fileMap, modelTitle)
+		if status != nil {
+			return filenames, status
+		}
+		bundleName, status = report.Bundle(c.ctx, filename, audioPaths(audioFiles))
+		if status != nil {
+			return filenames, status
+		}
+	}
 	writer := diff.NewHTMLWriter(c.ctx, c.database.Project)
-	filename, status := writer.WriteReport(c.req.Compare.BaseDataset, records, languageISO, fileMap,
-		c.req.SpeechToText)
-	return filename, status
+	writer.SetVerdicts(verdicts)
+	writer.SetAssets(assets, ``)
+	filename, status := writer.WriteReport(c.req.Compare.BaseDataset, records, languageISO, fileMap, modelTitle)
+	if status != nil {
+		return filenames, status
+	}
+	filenames = append(filenames, filename)
+	if bundleName != `` {
+		filenames = append(filenames, bundleName)
+	}
+	return filenames, nil
+}
+
+// audioPaths are the paths of the audio files of a report bundle
+func audioPaths(audioFiles []input.InputFile) []string {
+	var paths []string
+	for _, file := range audioFiles {
+		paths = append(paths, file.FilePath())
+	}
+	return paths
+}
+
+// timestampEnsemble reports the verses whose timestamps disagree across datasets.  It fails
+// when more are flagged than max_flagged_pct allows, so that update_dbp does not run.
+func (c *Controller) timestampEnsemble() *log.Status {
+	ens := ensemble.NewEnsemble(c.ctx, c.database, c.req.Username, c.req.TSEnsemble)
+	verses, status := ens.Process()
+	if status != nil {
+		return status
+	}
+	filename, status := ens.WriteHTML(c.req.DatasetName, verses)
+	if status != nil {
+		return status
+	}
+	c.bucket.AddOutput(filename)
+	filename, status = ens.WriteCSV(c.req.DatasetName, verses)
+	if status != nil {
+		return status
+	}
+	c.bucket.AddOutput(filename)
+	return ens.CheckGate(verses)
 }
 
 func (c *Controller) output() *log.Status {
@@ -597,6 +959,39 @@ func (c *Controller) output() *log.Status {
 	return status
 }
 
+func (c *Controller) outputSubtitles() *log.Status {
+	var out = output.NewOutput(c.ctx, c.database, c.req.DatasetName, false, false)
+	cues, status := out.PrepareCues(c.req.Output.Cues, c.req.Output.Karaoke)
+	if status != nil {
+		return status
+	}
+	filenames, status := out.WriteSubtitles(cues, c.req.Output.WebVTT, c.req.Output.SRT, c.req.Output.Karaoke)
+	if status != nil {
+		return status
+	}
+	for _, filename := range filenames {
+		c.bucket.AddOutput(filename)
+	}
+	return nil
+}
+
+func (c *Controller) outputCorpus(audioFiles []input.InputFile) *log.Status {
+	var out = output.NewOutput(c.ctx, c.database, c.req.DatasetName, false, false)
+	segments, status := out.PrepareCorpus(audioFiles, c.req.Output.Corpus)
+	if status != nil {
+		return status
+	}
+	if len(segments) == 0 {
+		log.Warn(c.ctx, `The speech corpus has no segments, check timestamps and min_fa_score`)
+	}
+	filename, status := out.WriteCorpus(segments, c.req.Output.Corpus)
+	if status != nil {
+		return status
+	}
+	c.bucket.AddOutput(filename)
+	return nil
+}
+
 func (c *Controller) outputStatus(status log.Status) string {
 	var filename string
 	var status2 *log.Status
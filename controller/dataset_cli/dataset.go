@@ -1,21 +1,45 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"flag"
 	"fmt"
 	"github.com/faithcomesbyhearing/fcbh-dataset-io/controller"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/decode_yaml"
 	"os"
 )
 
 func main() {
-	if len(os.Args) < 2 {
-		_, _ = fmt.Fprintln(os.Stdout, "Usage: dataset  request.yaml")
+	dryRun := flag.Bool(`dry_run`, false, `Check the request, and report what it would do, without processing it`)
+	schema := flag.Bool(`schema`, false, `Print the JSON Schema of a request`)
+	flag.Parse()
+	if *schema {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent(``, `  `)
+		_ = encoder.Encode(decode_yaml.Schema())
+		return
+	}
+	if flag.NArg() < 1 {
+		_, _ = fmt.Fprintln(os.Stdout, "Usage: dataset [-dry_run] request.yaml, or dataset -schema")
 		os.Exit(1)
 	}
-	var content, err = os.ReadFile(os.Args[1])
+	var content, err = os.ReadFile(flag.Arg(0))
 	if err != nil {
 		_, _ = fmt.Fprintln(os.Stdout, "Error reading yaml request file.")
 		os.Exit(1)
 	}
+	if *dryRun {
+		var ctx = context.WithValue(context.Background(), `runType`, `cli`)
+		plan := controller.DryRun(ctx, content)
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent(``, `  `)
+		_ = encoder.Encode(plan)
+		if len(plan.Problems) > 0 {
+			os.Exit(1)
+		}
+		return
+	}
 	outputFile, status := controller.CLIProcessEntry(content)
 	if status != nil {
 		_, _ = fmt.Fprintln(os.Stderr, status.String())
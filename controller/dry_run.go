@@ -0,0 +1,288 @@
+package controller
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/decode_yaml"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/decode_yaml/request"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/fetch"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/input"
+	log "github.com/faithcomesbyhearing/fcbh-dataset-io/logger"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/mms"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/speech_to_text/stt"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/timestamp/provider"
+)
+
+// Plan is the result of a dry run.  It is what a request would do, and every problem
+// that was found that would cause it to fail.
+type Plan struct {
+	DatasetName string                        `json:"dataset_name"`
+	Stages      []string                      `json:"stages"`
+	Filesets    []string                      `json:"filesets,omitempty"`
+	TextFiles   []string                      `json:"text_files,omitempty"`
+	AudioFiles  []string                      `json:"audio_files,omitempty"`
+	Chapters    int                           `json:"chapters"`
+	Languages   map[string]string             `json:"languages,omitempty"` // language chosen for each AI tool
+	Estimate    string                        `json:"estimated_runtime"`
+	Problems    []string                      `json:"problems,omitempty"`
+	Errors      []decode_yaml.ValidationError `json:"errors,omitempty"` // the problems of the request itself, located in the YAML
+}
+
+// secondsPerChapter are rough times for one chapter on a GPU server, used for the estimate.
+var secondsPerChapter = map[string]float64{
+	stageFetch:         2.0,
+	stageReadText:      0.2,
+	stageTimestamps:    20.0,
+	stageTraining:      60.0,
+	stageCopyForSTT:    0.2,
+	stageSpeechToText:  30.0,
+	stageAudioEncoding: 5.0,
+	stageTextEncoding:  1.0,
+	stageVerdicts:      0.0,
+	stageAudioProof:    2.0,
+	stageCompare:       1.0,
+	stageTSEnsemble:    0.5,
+	stageUpdateDBP:     1.0,
+	stageOutput:        0.5,
+}
+
+// DryRun resolves the inputs of a request without processing it.  It does not download
+// files, nor open the database.
+func DryRun(ctx context.Context, yamlContent []byte) Plan {
+	var p Plan
+	p.Languages = make(map[string]string)
+	reqDecoder := decode_yaml.NewRequestDecoder(ctx)
+	req, status := reqDecoder.Process(yamlContent)
+	p.DatasetName = req.DatasetName
+	if status != nil {
+		p.Problems = append(p.Problems, strings.Split(status.Message, "\n")...)
+		p.Errors = reqDecoder.Errors()
+		return p
+	}
+	languageISO := req.LanguageISO
+	var hasAudio = !req.AudioData.NoAudio
+	if req.TextData.AnyBibleBrain() || req.AudioData.AnyBibleBrain() {
+		languageISO = p.checkBibleBrain(ctx, req, languageISO)
+	}
+	if req.TextData.File != `` || req.TextData.AWSS3 != `` {
+		files := p.checkFiles(ctx, req, `text_data`, req.TextData.File, req.TextData.AWSS3)
+		for _, file := range files {
+			p.TextFiles = append(p.TextFiles, file.Filename)
+		}
+	}
+	if req.AudioData.File != `` || req.AudioData.AWSS3 != `` {
+		files := p.checkFiles(ctx, req, `audio_data`, req.AudioData.File, req.AudioData.AWSS3)
+		var chapters = make(map[string]bool)
+		for _, file := range files {
+			p.AudioFiles = append(p.AudioFiles, file.Filename)
+			chapters[file.BookId+` `+strconv.Itoa(file.Chapter)] = true
+		}
+		p.Chapters = len(chapters)
+	}
+	if req.TextData.POST != `` || req.AudioData.POST != `` {
+		log.Info(ctx, `Dry run does not check files that are posted with the request`)
+	}
+	p.Stages = plannedStages(req, hasAudio)
+	p.checkExecutables(req, hasAudio)
+	p.checkLanguages(ctx, req, languageISO)
+	p.Estimate = p.estimate(req).String()
+	return p
+}
+
+// plannedStages lists the stages that processSteps would run, in the same order.
+func plannedStages(req request.Request, hasAudio bool) []string {
+	var stages = []string{stageDecode, stageFetch}
+	if !req.TextData.NoText {
+		stages = append(stages, stageReadText)
+	}
+	if hasAudio {
+		stages = append(stages, stageTimestamps)
+	}
+	if !req.Training.NoTraining {
+		stages = append(stages, stageTraining)
+	}
+	if !req.SpeechToText.NoSpeechToText {
+		stages = append(stages, stageCopyForSTT, stageSpeechToText)
+	}
+	if !req.AudioEncoding.NoEncoding {
+		stages = append(stages, stageAudioEncoding)
+	}
+	if !req.TextEncoding.NoEncoding {
+		stages = append(stages, stageTextEncoding)
+	}
+	if len(req.Verdicts.Import) > 0 || req.Verdicts.Stats {
+		stages = append(stages, stageVerdicts)
+	}
+	if req.AudioProof.HTMLReport {
+		stages = append(stages, stageAudioProof)
+	}
+	if req.Compare.HTMLReport {
+		stages = append(stages, stageCompare)
+	}
+	if len(req.TSEnsemble.Datasets) > 0 {
+		stages = append(stages, stageTSEnsemble)
+	}
+	if len(req.UpdateDBP.Timestamps) > 0 {
+		stages = append(stages, stageUpdateDBP)
+	}
+	return append(stages, stageOutput)
+}
+
+// checkBibleBrain finds the filesets of the request, and the number of chapters they contain.
+// It returns the language of the Bible, when the request does not have one.
+func (p *Plan) checkBibleBrain(ctx context.Context, req request.Request, languageISO string) string {
+	client := fetch.NewAPIDBPClient(ctx, req.BibleId)
+	info, status := client.BibleInfo()
+	if status != nil {
+		p.Problems = append(p.Problems, `Bible Brain has no Bible `+req.BibleId+`: `+status.Message)
+		return languageISO
+	}
+	client.FindFilesets(&info, req.AudioData.BibleBrain, req.TextData.BibleBrain, req.Testament)
+	hasOT := req.Testament.OT || len(req.Testament.OTBooks) > 0
+	hasNT := req.Testament.NT || len(req.Testament.NTBooks) > 0
+	if req.AudioData.AnyBibleBrain() {
+		p.checkFileset(`audio`, `OT`, hasOT, info.AudioOTFileset)
+		p.checkFileset(`audio`, `NT`, hasNT, info.AudioNTFileset)
+		for _, book := range info.Books {
+			if req.Testament.Has(book.Testament, book.BookId) {
+				p.Chapters += len(book.Chapters)
+			}
+		}
+	}
+	if req.TextData.AnyBibleBrain() {
+		textOT, textNT := info.TextOTPlainFileset, info.TextNTPlainFileset
+		if req.TextData.BibleBrain.TextUSXEdit {
+			textOT, textNT = info.TextOTUSXFileset, info.TextNTUSXFileset
+		}
+		p.checkFileset(`text`, `OT`, hasOT, textOT)
+		p.checkFileset(`text`, `NT`, hasNT, textNT)
+	}
+	if languageISO == `` {
+		languageISO = strings.ToLower(info.LanguageISO)
+	}
+	return languageISO
+}
+
+func (p *Plan) checkFileset(media string, testament string, requested bool, fileset fetch.FilesetType) {
+	if !requested {
+		return
+	}
+	if fileset.Id == `` {
+		p.Problems = append(p.Problems, `Bible Brain has no `+testament+` `+media+` fileset of the type requested`)
+	} else {
+		p.Filesets = append(p.Filesets, fileset.Id)
+	}
+}
+
+// checkFiles finds the files of a file: or aws_s3: glob, and checks that their names can be parsed.
+func (p *Plan) checkFiles(ctx context.Context, req request.Request, field string, file string, awsS3 string) []input.InputFile {
+	var files []input.InputFile
+	var status *log.Status
+	var path = file
+	if file != `` {
+		files, status = input.FileInput(ctx, file)
+	} else {
+		path = awsS3
+		files, status = input.AWSS3List(ctx, awsS3)
+	}
+	if status != nil {
+		p.Problems = append(p.Problems, field+`: `+path+`: `+status.Message)
+		return files
+	}
+	if len(files) == 0 {
+		p.Problems = append(p.Problems, field+`: `+path+` matches no files`)
+		return files
+	}
+	if file == `` && field == `text_data` {
+		return files // the names of some text files are found in their content, which is not downloaded
+	}
+	files, status = input.FillInputFile(ctx, req.Testament, files)
+	if status != nil {
+		p.Problems = append(p.Problems, field+`: `+status.Message)
+	} else if len(files) == 0 {
+		p.Problems = append(p.Problems, field+`: `+path+` has no files in the testament requested`)
+	}
+	return files
+}
+
+// checkExecutables checks that ffmpeg, and the programs of each AI tool requested, can be run.
+func (p *Plan) checkExecutables(req request.Request, hasAudio bool) {
+	var names []string
+	if hasAudio {
+		_, err := exec.LookPath(`ffmpeg`)
+		if err != nil {
+			p.Problems = append(p.Problems, `ffmpeg is not found: `+err.Error())
+		}
+	}
+	if hasAudio && !req.Timestamps.NoTimestamps {
+		chain, _ := provider.Chain(req.Timestamps)
+		for _, info := range chain {
+			names = append(names, info.Executables...)
+		}
+	}
+	if !req.SpeechToText.NoSpeechToText {
+		engine, ok := stt.Find(req.SpeechToText)
+		if ok {
+			names = append(names, engine.Executables...)
+		}
+	}
+	if !req.Training.NoTraining {
+		names = append(names, `FCBH_MMS_ADAPTER_PYTHON`)
+	}
+	if req.AudioEncoding.MFCC {
+		names = append(names, `FCBH_LIBROSA_PYTHON`)
+	}
+	sort.Strings(names)
+	var last string
+	for _, name := range names {
+		if name == last {
+			continue
+		}
+		last = name
+		path := os.Getenv(name)
+		if path == `` {
+			p.Problems = append(p.Problems, name+` is not set`)
+			continue
+		}
+		_, err := exec.LookPath(path)
+		if err != nil {
+			p.Problems = append(p.Problems, name+`=`+path+` is not executable: `+err.Error())
+		}
+	}
+}
+
+// checkLanguages finds the language the speech to text engine would use.
+func (p *Plan) checkLanguages(ctx context.Context, req request.Request, languageISO string) {
+	if req.SpeechToText.NoSpeechToText {
+		return
+	}
+	engine, ok := stt.Find(req.SpeechToText)
+	if !ok || engine.LanguageSearch == `` {
+		return
+	}
+	lang, status := mms.CheckLanguage(ctx, languageISO, req.AltLanguage, engine.LanguageSearch)
+	if status != nil {
+		p.Problems = append(p.Problems, engine.Name+`: `+status.Message)
+	} else {
+		p.Languages[engine.Name] = lang
+	}
+}
+
+func (p *Plan) estimate(req request.Request) time.Duration {
+	var seconds float64
+	var workers = float64(max(req.Workers, 1))
+	for _, stage := range p.Stages {
+		perChapter := secondsPerChapter[stage] * float64(p.Chapters)
+		if stage == stageTimestamps || stage == stageSpeechToText {
+			perChapter /= workers
+		}
+		seconds += perChapter
+	}
+	return time.Duration(seconds * float64(time.Second)).Round(time.Second)
+}
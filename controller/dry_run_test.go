@@ -0,0 +1,47 @@
+package controller
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDryRun(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, `MAT.usfm`), []byte("\\id MAT\n\\c 1\n\\p\n\\v 1 The book\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(`FCBH_WHISPER_EXE`, ``)
+	var yaml = `is_new: yes
+dataset_name: DryRunTest
+username: GaryNTest
+bible_id: ENGWEB
+text_data:
+  file: ` + filepath.Join(dir, `*.usfm`) + `
+audio_data:
+  file: ` + filepath.Join(dir, `*.mp3`) + `
+timestamps:
+  no_timestamps: yes
+speech_to_text:
+  engine: whisper
+`
+	plan := DryRun(context.Background(), []byte(yaml))
+	expect := []string{stageDecode, stageFetch, stageReadText, stageTimestamps, stageCopyForSTT, stageSpeechToText, stageOutput}
+	if !reflect.DeepEqual(plan.Stages, expect) {
+		t.Error(`Expected stages`, expect, `got`, plan.Stages)
+	}
+	if len(plan.TextFiles) != 1 || plan.TextFiles[0] != `MAT.usfm` {
+		t.Error(`Expected text file MAT.usfm, got`, plan.TextFiles)
+	}
+	problems := strings.Join(plan.Problems, "\n")
+	if !strings.Contains(problems, `matches no files`) {
+		t.Error(`Expected a problem with the audio files`, plan.Problems)
+	}
+	if !strings.Contains(problems, `FCBH_WHISPER_EXE is not set`) {
+		t.Error(`Expected a problem with whisper`, plan.Problems)
+	}
+}
@@ -0,0 +1,57 @@
+package controller
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/faithcomesbyhearing/fcbh-dataset-io/logger"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/metrics"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/speech_to_text/stt"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/timestamp/provider"
+)
+
+// recordJob counts a finished job by its result.  A failed job is also counted by the stage it stopped in.
+func (c *Controller) recordJob(status *log.Status) {
+	if status == nil {
+		metrics.JobsFinished.Inc(`succeeded`, ``, `200`)
+		return
+	}
+	var result = `failed`
+	if status.IsCancelled() {
+		result = `cancelled`
+	}
+	var stage = c.stage
+	if stage == `` {
+		stage = stageDecode
+	}
+	metrics.JobsFinished.Inc(result, stage, strconv.Itoa(status.Status))
+}
+
+// recordChapters counts the chapters processed by a stage that runs chapter by chapter
+func (c *Controller) recordChapters(stage string, chapters int, start time.Time) {
+	backend := c.backend(stage)
+	metrics.ChaptersProcessed.Add(float64(chapters), stage, backend)
+	metrics.ChapterSeconds.Add(time.Since(start).Seconds(), stage, backend)
+}
+
+// backend is the AI backend of a stage, e.g. the timestamp chain mms_fa_verse+mms_align
+func (c *Controller) backend(stage string) string {
+	switch stage {
+	case stageTimestamps:
+		chain, _ := provider.Chain(c.req.Timestamps)
+		var names []string
+		for _, info := range chain {
+			names = append(names, info.Name)
+		}
+		return strings.Join(names, `+`)
+	case stageSpeechToText:
+		info, _ := stt.Find(c.req.SpeechToText)
+		return info.Name
+	case stageAudioEncoding:
+		if c.req.AudioEncoding.MFCC {
+			return `mfcc`
+		}
+	}
+	return ``
+}
@@ -0,0 +1,98 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	log "github.com/faithcomesbyhearing/fcbh-dataset-io/logger"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/metrics"
+)
+
+// Stage names reported to Progress, in addition to the checkpointed stages.
+const (
+	stageDecode     = `decode`
+	stageFetch      = `fetch`
+	stageTraining   = `training`
+	stageVerdicts   = `verdicts`
+	stageAudioProof = `audio_proof`
+	stageCompare    = `compare`
+	stageTSEnsemble = `timestamp_ensemble`
+	stageOutput     = `output`
+)
+
+// Progress receives the stage that is running, and for stages that process audio
+// chapter by chapter, the number of chapters done and the total number of chapters.
+type Progress func(stage string, chaptersDone int, chaptersTotal int)
+
+func (c *Controller) SetProgress(progress Progress) {
+	c.progress = progress
+}
+
+// beginStage reports the start of a stage, and stops the run if the job was cancelled or timed out.
+// It gives the stage its own context, which has the stage timeout of the request, if any.
+func (c *Controller) beginStage(stage string) *log.Status {
+	c.endStage()
+	c.reportChapters(stage, 0, 0)
+	if c.jobCtx.Err() != nil {
+		return log.ContextError(c.jobCtx, `Request stopped before`, stage)
+	}
+	c.ctx, c.stageCancel = withTimeout(log.WithStage(c.jobCtx, stage), c.stageTimeout(stage))
+	metrics.JobsStarted.Inc(stage)
+	c.stage = stage
+	c.stageStart = time.Now()
+	return nil
+}
+
+// endStage releases the context of the current stage, and records if it was cancelled or timed out.
+func (c *Controller) endStage() {
+	if c.stageCancel == nil {
+		return
+	}
+	if c.ctx.Err() != nil && c.stopped == nil {
+		c.stopped = log.ContextError(c.ctx, `Request stopped during`, c.stage)
+	}
+	metrics.StageDuration.Observe(time.Since(c.stageStart).Seconds(), c.stage)
+	c.stageCancel()
+	c.stageCancel = nil
+	c.ctx = c.jobCtx
+}
+
+func (c *Controller) stageTimeout(stage string) string {
+	var t = c.req.Timeouts
+	switch stage {
+	case stageTimestamps:
+		return t.Timestamps
+	case stageTraining:
+		return t.Training
+	case stageSpeechToText:
+		return t.SpeechToText
+	case stageAudioEncoding:
+		return t.AudioEncoding
+	case stageTextEncoding:
+		return t.TextEncoding
+	case stageAudioProof:
+		return t.AudioProof
+	case stageCompare:
+		return t.Compare
+	case stageUpdateDBP:
+		return t.UpdateDBP
+	}
+	return ``
+}
+
+// withTimeout returns a cancellable context, with a deadline when duration is not empty.
+// The duration was checked when the request was validated.
+func withTimeout(ctx context.Context, duration string) (context.Context, context.CancelFunc) {
+	limit, err := time.ParseDuration(duration)
+	if duration == `` || err != nil || limit <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, limit)
+}
+
+func (c *Controller) reportChapters(stage string, chaptersDone int, chaptersTotal int) {
+	c.bucket.AddChapters(chaptersTotal)
+	if c.progress != nil {
+		c.progress(stage, chaptersDone, chaptersTotal)
+	}
+}
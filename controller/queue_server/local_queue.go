@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/faithcomesbyhearing/fcbh-dataset-io/logger"
+)
+
+const runningFolder = `running/`
+
+// defaultLease is how long a request may be in running/ without a heartbeat before it is presumed to
+// belong to a worker that crashed, and is put back in input/ when a worker starts.
+// FCBH_DATASET_QUEUE_LEASE, e.g. 12h, sets it, but it is never less than 3 heartbeats.
+const defaultLease = 24 * time.Hour
+
+// defaultHeartbeat is how often the worker running a request touches it in running/, to renew its lease
+const defaultHeartbeat = 5 * time.Minute
+
+// claimedName matches the name of a request in running/, which is {hostname}_{pid}-{filename}
+var claimedName = regexp.MustCompile(`^.+?_[0-9]+-(.+)$`)
+
+// LocalQueue watches the input/ directory of a local queue directory.  A request is claimed
+// by renaming it into running/, which is atomic, so several workers can share one queue
+// without processing a request twice.  The worker renews its claim with a heartbeat until
+// the request is completed.
+type LocalQueue struct {
+	directory string
+	worker    string
+	heartbeat time.Duration
+	mutex     sync.Mutex
+	stops     map[string]chan struct{} // stops the heartbeat of each claimed key
+}
+
+func NewLocalQueue(ctx context.Context, directory string) (*LocalQueue, *log.Status) {
+	var q LocalQueue
+	q.directory = directory
+	hostname, _ := os.Hostname()
+	q.worker = hostname + "_" + strconv.Itoa(os.Getpid())
+	q.heartbeat = defaultHeartbeat
+	q.stops = make(map[string]chan struct{})
+	for _, folder := range []string{inputFolder, runningFolder, sucessFolder, failedFolder} {
+		err := os.MkdirAll(filepath.Join(directory, folder), 0755)
+		if err != nil {
+			return &q, log.Error(ctx, 500, err, "Error creating queue folder", folder)
+		}
+	}
+	lease, err := time.ParseDuration(os.Getenv(`FCBH_DATASET_QUEUE_LEASE`))
+	if err != nil || lease <= 0 {
+		lease = defaultLease
+	}
+	if lease < 3*q.heartbeat {
+		log.Warn(ctx, "FCBH_DATASET_QUEUE_LEASE", lease, "is less than 3 heartbeats, using", 3*q.heartbeat)
+		lease = 3 * q.heartbeat
+	}
+	return &q, q.requeueExpired(ctx, lease)
+}
+
+// requeueExpired puts requests whose last heartbeat is older than lease back in input/,
+// because the worker that claimed them has stopped without completing them.
+func (q *LocalQueue) requeueExpired(ctx context.Context, lease time.Duration) *log.Status {
+	runDir := filepath.Join(q.directory, runningFolder)
+	entries, err := os.ReadDir(runDir)
+	if err != nil {
+		return log.Error(ctx, 500, err, "Error reading Queue Running Folder")
+	}
+	for _, entry := range entries {
+		info, err2 := entry.Info()
+		if err2 != nil || entry.IsDir() || time.Since(info.ModTime()) < lease {
+			continue
+		}
+		filename := entry.Name()
+		if match := claimedName.FindStringSubmatch(filename); match != nil {
+			filename = match[1]
+		}
+		source := filepath.Join(runDir, entry.Name())
+		target := filepath.Join(q.directory, inputFolder, filename)
+		err = moveNew(source, target)
+		if errors.Is(err, os.ErrNotExist) {
+			continue // requeued by another worker
+		}
+		if err != nil {
+			return log.Error(ctx, 500, err, "Error requeueing", entry.Name(), "from Queue Running Folder")
+		}
+		log.Warn(ctx, "Requeued", entry.Name(), "claimed", info.ModTime().Format(time.RFC3339), "by a stopped worker")
+	}
+	return nil
+}
+
+// moveNew renames source to target, or to target with a nanosecond time prefix when
+// target exists, so that it never replaces another file.
+func moveNew(source string, target string) error {
+	err := os.Link(source, target)
+	if errors.Is(err, os.ErrExist) {
+		nanos := strconv.FormatInt(time.Now().UnixNano(), 10)
+		target = filepath.Join(filepath.Dir(target), nanos+"-"+filepath.Base(target))
+		err = os.Link(source, target)
+	}
+	if err != nil {
+		return err
+	}
+	return os.Remove(source)
+}
+
+func (q *LocalQueue) Name() string {
+	return q.directory
+}
+
+func (q *LocalQueue) Next(ctx context.Context) ([]byte, string, *log.Status) {
+	inDir := filepath.Join(q.directory, inputFolder)
+	entries, err := os.ReadDir(inDir)
+	if err != nil {
+		return nil, ``, log.Error(ctx, 500, err, "Error reading Queue Input Folder")
+	}
+	type queued struct {
+		name    string
+		modTime time.Time
+	}
+	var files []queued
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), `.`) {
+			continue
+		}
+		info, err2 := entry.Info()
+		if err2 != nil {
+			continue // claimed by another worker
+		}
+		files = append(files, queued{name: entry.Name(), modTime: info.ModTime()})
+	}
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].modTime.Equal(files[j].modTime) {
+			return files[i].name < files[j].name
+		}
+		return files[i].modTime.Before(files[j].modTime)
+	})
+	for _, file := range files {
+		key := runningFolder + q.worker + "-" + file.name
+		err = os.Rename(filepath.Join(inDir, file.name), filepath.Join(q.directory, key))
+		if errors.Is(err, os.ErrNotExist) {
+			continue // claimed by another worker
+		}
+		if err != nil {
+			return nil, ``, log.Error(ctx, 500, err, "Error claiming", file.name, "in Queue Input Folder")
+		}
+		now := time.Now()
+		_ = os.Chtimes(filepath.Join(q.directory, key), now, now) // start of the lease
+		content, err2 := os.ReadFile(filepath.Join(q.directory, key))
+		if err2 != nil {
+			return nil, key, log.Error(ctx, 500, err2, "Error reading yaml file from Queue Running Folder.")
+		}
+		q.startHeartbeat(key)
+		return content, key, nil
+	}
+	return nil, ``, nil
+}
+
+// startHeartbeat touches a claimed request every heartbeat until it is completed, so that
+// a request that runs longer than the lease is not requeued while it is running.
+func (q *LocalQueue) startHeartbeat(key string) {
+	stop := make(chan struct{})
+	q.mutex.Lock()
+	q.stops[key] = stop
+	q.mutex.Unlock()
+	go func() {
+		ticker := time.NewTicker(q.heartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				now := time.Now()
+				_ = os.Chtimes(filepath.Join(q.directory, key), now, now)
+			}
+		}
+	}()
+}
+
+func (q *LocalQueue) stopHeartbeat(key string) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if stop, ok := q.stops[key]; ok {
+		close(stop)
+		delete(q.stops, key)
+	}
+}
+
+func (q *LocalQueue) Complete(ctx context.Context, key string, folder string) *log.Status {
+	q.stopHeartbeat(key)
+	dateTime := time.Now().Local().Format("2006-01-02T15:04:05")
+	filename := strings.TrimPrefix(filepath.Base(key), q.worker+"-")
+	target := filepath.Join(q.directory, folder, dateTime+"-"+filename)
+	err := moveNew(filepath.Join(q.directory, key), target)
+	if err != nil {
+		return log.Error(ctx, 500, err, "Error Moving File to", folder, "Folder")
+	}
+	return nil
+}
+
+func (q *LocalQueue) Depth(ctx context.Context) (int, time.Time, *log.Status) {
+	var depth int
+	var oldest time.Time
+	entries, err := os.ReadDir(filepath.Join(q.directory, inputFolder))
+	if err != nil {
+		return depth, oldest, log.Error(ctx, 500, err, "Error reading Queue Input Folder")
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), `.`) {
+			continue
+		}
+		info, err2 := entry.Info()
+		if err2 != nil {
+			continue // claimed by another worker
+		}
+		depth++
+		if oldest.IsZero() || info.ModTime().Before(oldest) {
+			oldest = info.ModTime()
+		}
+	}
+	return depth, oldest, nil
+}
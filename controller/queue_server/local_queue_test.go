@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLocalQueue(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	queue, status := NewLocalQueue(ctx, dir)
+	if status != nil {
+		t.Fatal(status)
+	}
+	older := filepath.Join(dir, inputFolder, "b_request.yaml")
+	newer := filepath.Join(dir, inputFolder, "a_request.yaml")
+	_ = os.WriteFile(older, []byte("dataset_name: older"), 0644)
+	_ = os.WriteFile(newer, []byte("dataset_name: newer"), 0644)
+	_ = os.Chtimes(older, time.Now().Add(-time.Hour), time.Now().Add(-time.Hour))
+	other, _ := NewLocalQueue(ctx, dir)
+	other.worker = "other_1"
+	depth, oldest, status := queue.Depth(ctx)
+	if status != nil || depth != 2 || time.Since(oldest) < 59*time.Minute {
+		t.Error("Expected 2 requests, the oldest an hour old", depth, oldest, status)
+	}
+	content, key, status := queue.Next(ctx)
+	if status != nil {
+		t.Fatal(status)
+	}
+	if string(content) != "dataset_name: older" {
+		t.Error("Expected oldest request first, got", string(content))
+	}
+	content2, key2, status := other.Next(ctx)
+	if status != nil {
+		t.Fatal(status)
+	}
+	if string(content2) != "dataset_name: newer" {
+		t.Error("Expected second worker to claim the other request, got", string(content2))
+	}
+	content3, _, status := queue.Next(ctx)
+	if status != nil || content3 != nil {
+		t.Error("Expected empty queue", string(content3), status)
+	}
+	status = queue.Complete(ctx, key, sucessFolder)
+	if status != nil {
+		t.Fatal(status)
+	}
+	status = other.Complete(ctx, key2, failedFolder)
+	if status != nil {
+		t.Fatal(status)
+	}
+	success, _ := filepath.Glob(filepath.Join(dir, sucessFolder, "*-b_request.yaml"))
+	failed, _ := filepath.Glob(filepath.Join(dir, failedFolder, "*-a_request.yaml"))
+	if len(success) != 1 || len(failed) != 1 {
+		t.Error("Expected requests in success and failed folders", success, failed)
+	}
+}
+
+func TestLocalQueueRequeue(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	queue, status := NewLocalQueue(ctx, dir)
+	if status != nil {
+		t.Fatal(status)
+	}
+	stale := filepath.Join(dir, runningFolder, "crashed_123-stale.yaml")
+	current := filepath.Join(dir, runningFolder, "running_456-current.yaml")
+	_ = os.WriteFile(stale, []byte("dataset_name: stale"), 0644)
+	_ = os.WriteFile(current, []byte("dataset_name: current"), 0644)
+	_ = os.Chtimes(stale, time.Now().Add(-25*time.Hour), time.Now().Add(-25*time.Hour))
+	_, status = NewLocalQueue(ctx, dir)
+	if status != nil {
+		t.Fatal(status)
+	}
+	content, key, status := queue.Next(ctx)
+	if status != nil || string(content) != "dataset_name: stale" {
+		t.Fatal("Expected the stale request to be requeued", string(content), status)
+	}
+	if _, err := os.Stat(current); err != nil {
+		t.Error("Expected the current request to stay in running", err)
+	}
+	// Two requests of the same name completed in the same second are both kept
+	_ = queue.Complete(ctx, key, sucessFolder)
+	_ = os.WriteFile(filepath.Join(dir, inputFolder, "stale.yaml"), []byte("dataset_name: again"), 0644)
+	_, key, status = queue.Next(ctx)
+	if status != nil {
+		t.Fatal(status)
+	}
+	_ = queue.Complete(ctx, key, sucessFolder)
+	success, _ := filepath.Glob(filepath.Join(dir, sucessFolder, "*stale.yaml"))
+	if len(success) != 2 {
+		t.Error("Expected both requests in success folder", success)
+	}
+}
+
+func TestLocalQueueHeartbeat(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	queue, status := NewLocalQueue(ctx, dir)
+	if status != nil {
+		t.Fatal(status)
+	}
+	queue.heartbeat = 20 * time.Millisecond
+	_ = os.WriteFile(filepath.Join(dir, inputFolder, "long.yaml"), []byte("dataset_name: long"), 0644)
+	_, key, status := queue.Next(ctx)
+	if status != nil {
+		t.Fatal(status)
+	}
+	lease := 100 * time.Millisecond
+	time.Sleep(3 * lease) // a job that runs longer than its lease
+	other, _ := NewLocalQueue(ctx, dir)
+	status = other.requeueExpired(ctx, lease)
+	if status != nil {
+		t.Fatal(status)
+	}
+	if _, err := os.Stat(filepath.Join(dir, key)); err != nil {
+		t.Fatal("Expected the running request to keep its claim", err)
+	}
+	content, _, _ := other.Next(ctx)
+	if content != nil {
+		t.Error("Expected the running request not to be requeued", string(content))
+	}
+	status = queue.Complete(ctx, key, sucessFolder)
+	if status != nil {
+		t.Fatal(status)
+	}
+	if len(queue.stops) != 0 {
+		t.Error("Expected the heartbeat to stop when the request is completed")
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"os"
+	"strings"
+	"time"
+
+	log "github.com/faithcomesbyhearing/fcbh-dataset-io/logger"
+)
+
+const (
+	inputFolder  = `input/`
+	sucessFolder = `success/`
+	failedFolder = `failed/`
+)
+
+// Queue is a source of YAML requests.  Requests are placed in the input folder,
+// and are moved to the success or failed folder when they have been processed.
+type Queue interface {
+	// Name identifies the queue in log messages
+	Name() string
+	// Next claims the oldest request in the input folder, and returns its content and key.
+	// When the queue is empty, content is nil.
+	Next(ctx context.Context) ([]byte, string, *log.Status)
+	// Complete moves a claimed request to the success or failed folder.
+	Complete(ctx context.Context, key string, folder string) *log.Status
+	// Depth returns the number of requests in the input folder, and when the oldest was queued.
+	Depth(ctx context.Context) (int, time.Time, *log.Status)
+}
+
+// NewQueue returns a LocalQueue when FCBH_DATASET_QUEUE is a directory path,
+// and otherwise an S3Queue, which uses it as a bucket name.
+func NewQueue(ctx context.Context) (Queue, *log.Status) {
+	location := os.Getenv("FCBH_DATASET_QUEUE")
+	if strings.HasPrefix(location, `file://`) {
+		return NewLocalQueue(ctx, strings.TrimPrefix(location, `file://`))
+	}
+	if strings.HasPrefix(location, `/`) || strings.HasPrefix(location, `.`) {
+		return NewLocalQueue(ctx, location)
+	}
+	return NewS3Queue(ctx, strings.TrimPrefix(location, `s3://`))
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	log "github.com/faithcomesbyhearing/fcbh-dataset-io/logger"
+)
+
+// S3Queue polls the input/ prefix of an S3 bucket.  The region is taken from AWS_REGION,
+// and defaults to us-west-2.
+type S3Queue struct {
+	client *s3.Client
+	bucket string
+}
+
+func NewS3Queue(ctx context.Context, bucket string) (*S3Queue, *log.Status) {
+	var q S3Queue
+	q.bucket = bucket
+	region := os.Getenv("AWS_REGION")
+	if region == `` {
+		region = "us-west-2"
+	}
+	cfg, err := config.LoadDefaultConfig(ctx,
+		config.WithRegion(region),
+		//config.WithLogger(logger),
+	)
+	if err != nil {
+		return &q, log.Error(ctx, 500, err, "aws LoadDefaultConfig Failed In Queue Main")
+	}
+	q.client = s3.NewFromConfig(cfg)
+	return &q, nil
+}
+
+func (q *S3Queue) Name() string {
+	return "s3://" + q.bucket
+}
+
+func (q *S3Queue) Next(ctx context.Context) ([]byte, string, *log.Status) {
+	var content []byte
+	var key string
+	input := &s3.ListObjectsV2Input{
+		Bucket: &q.bucket,
+		Prefix: aws.String(q.inFolder()),
+	}
+	result, err := q.client.ListObjectsV2(ctx, input)
+	if err != nil {
+		return content, key, log.Error(ctx, 500, err, "Error Listing Objects in Queue Input Folder")
+	}
+	if len(result.Contents) == 0 {
+		return content, key, nil
+	}
+	sort.Slice(result.Contents, func(i, j int) bool {
+		return result.Contents[i].LastModified.Before(*result.Contents[j].LastModified)
+	})
+	key = *result.Contents[0].Key
+	getInput := &s3.GetObjectInput{
+		Bucket: &q.bucket,
+		Key:    &key,
+	}
+	object, err := q.client.GetObject(ctx, getInput)
+	if err != nil {
+		return content, key, log.Error(ctx, 500, err, "Error Getting Object in Queue Input Folder")
+	}
+	content, err = io.ReadAll(object.Body)
+	_ = object.Body.Close()
+	if err != nil {
+		return content, key, log.Error(ctx, 500, err, "Error reading yaml file from Queue Input Folder.")
+	}
+	return content, key, nil
+}
+
+func (q *S3Queue) Complete(ctx context.Context, key string, folder string) *log.Status {
+	source := q.bucket + "/" + key
+	dateTime := time.Now().Local().Format("2006-01-02T15:04:05")
+	target := folder + dateTime + "-" + strings.Split(key, "/")[1]
+	_, err := q.client.CopyObject(ctx, &s3.CopyObjectInput{
+		Bucket:     &q.bucket,
+		CopySource: &source,
+		Key:        &target,
+	})
+	if err != nil {
+		return log.Error(ctx, 500, err, "Error Moving File to", folder, "Folder")
+	}
+	_, err = q.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: &q.bucket,
+		Key:    &key,
+	})
+	if err != nil {
+		return log.Error(ctx, 500, err, "Error Deleting Object in Queue Input Folder")
+	}
+	return nil
+}
+
+func (q *S3Queue) Depth(ctx context.Context) (int, time.Time, *log.Status) {
+	var depth int
+	var oldest time.Time
+	paginator := s3.NewListObjectsV2Paginator(q.client, &s3.ListObjectsV2Input{
+		Bucket: &q.bucket,
+		Prefix: aws.String(q.inFolder()),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return depth, oldest, log.Error(ctx, 500, err, "Error Listing Objects in Queue Input Folder")
+		}
+		for _, object := range page.Contents {
+			if strings.HasSuffix(*object.Key, `/`) || object.LastModified == nil {
+				continue
+			}
+			depth++
+			if oldest.IsZero() || object.LastModified.Before(oldest) {
+				oldest = *object.LastModified
+			}
+		}
+	}
+	return depth, oldest, nil
+}
+
+func (q *S3Queue) inFolder() string {
+	if runtime.GOOS == "darwin" {
+		return "input_test/"
+	}
+	return inputFolder
+}
@@ -14,24 +14,26 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/feature/ec2/imds"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/faithcomesbyhearing/fcbh-dataset-io/db"
 	log "github.com/faithcomesbyhearing/fcbh-dataset-io/logger"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/storage"
 )
 
 type Courier struct {
-	ctx         context.Context
-	IsUnitTest  bool // Set to true by run_bucket_test.
-	start       time.Time
-	bucket      string
-	username    string
-	dataset     string
-	run         int
-	yamlContent string
-	logFile     string
-	databases   []string
-	outputs     []string
-	outputKeys  []string
+	ctx           context.Context
+	IsUnitTest    bool // Set to true by run_bucket_test.
+	start         time.Time
+	bucket        string
+	username      string
+	dataset       string
+	run           int
+	yamlContent   string
+	logFile       string
+	databases     []string
+	outputs       []string
+	outputKeys    []string
+	jobId         string
+	chaptersTotal int
 }
 
 func NewCourier(ctx context.Context, yaml []byte) Courier {
@@ -92,6 +94,16 @@ func (b *Courier) AddPerJobLogFile(logDir string) {
 	_ = os.Symlink(filepath.Base(jobLogFile), latestLink) // Ignore error on systems without symlink support
 }
 
+// SetJobId sets the job id of the run, which is sent with its completion message
+func (b *Courier) SetJobId(jobId string) {
+	b.jobId = jobId
+}
+
+// AddChapters keeps the largest number of chapters processed by a stage
+func (b *Courier) AddChapters(chaptersTotal int) {
+	b.chaptersTotal = max(b.chaptersTotal, chaptersTotal)
+}
+
 func (b *Courier) AddDatabase(conn db.DBAdapter) {
 	b.databases = append(b.databases, conn.DatabasePath)
 }
@@ -130,38 +142,45 @@ func (b *Courier) GetOutputByExt() []string {
 	return results
 }
 
+// PersistToBucket saves the request, log, databases and outputs of a run to the storage of
+// FCBH_DATASET_IO_BUCKET, which is an S3 bucket, or a directory, see storage.NewStorage.
 func (b *Courier) PersistToBucket() *log.Status {
 	var allStatus []*log.Status
 	var status *log.Status
 	if !testing.Testing() || b.IsUnitTest {
-		cfg, err := config.LoadDefaultConfig(b.ctx, config.WithRegion("us-west-2"))
-		if err != nil {
-			return log.Error(b.ctx, 500, err, "Error loading AWS config.")
+		var store storage.Storage
+		store, status = storage.NewStorage(b.ctx, b.bucket)
+		if status != nil {
+			return status
 		}
-		client := s3.NewFromConfig(cfg)
 		var run int
-		run, status = b.findLastRun(client)
+		run, status = b.findLastRun(store)
 		allStatus = append(allStatus, status)
 		run++
-		_, status = b.uploadString(client, run, "request", b.dataset+".yaml", b.yamlContent)
+		b.run = run
+		_, status = b.uploadString(store, run, "request", b.dataset+".yaml", b.yamlContent)
 		allStatus = append(allStatus, status)
-		_, status = b.uploadFile(client, run, "log", b.logFile)
+		_, status = b.uploadFile(store, run, "log", b.logFile)
 		allStatus = append(allStatus, status)
 		for _, database := range b.databases {
-			_, status = b.uploadFile(client, run, "database", database)
+			_, status = b.uploadFile(store, run, "database", database)
 			allStatus = append(allStatus, status)
 		}
 		for _, output := range b.outputs {
-			outputKey, status2 := b.uploadFile(client, run, "output", output)
+			outputKey, status2 := b.uploadFile(store, run, "output", output)
 			allStatus = append(allStatus, status2)
 			b.outputKeys = append(b.outputKeys, outputKey)
 		}
 
 		loc, _ := time.LoadLocation("America/Denver")
-		info := b.ServerInfo(cfg)
-		_, status = b.uploadString(client, run, "runtime", b.start.In(loc).Format(`Mon Jan 2 2006 03:04:05 pm MST`), info)
+		var info string
+		cfg, err := config.LoadDefaultConfig(b.ctx, config.WithRegion("us-west-2"))
+		if err == nil {
+			info = b.ServerInfo(cfg)
+		}
+		_, status = b.uploadString(store, run, "runtime", b.start.In(loc).Format(`Mon Jan 2 2006 03:04:05 pm MST`), info)
 		allStatus = append(allStatus, status)
-		_, status = b.uploadString(client, run, "duration", time.Since(b.start).String(), "")
+		_, status = b.uploadString(store, run, "duration", time.Since(b.start).String(), "")
 		allStatus = append(allStatus, status)
 		for _, stat := range allStatus {
 			if stat != nil {
@@ -173,6 +192,20 @@ func (b *Courier) PersistToBucket() *log.Status {
 	return status
 }
 
+// PersistLog uploads the log again to the run of PersistToBucket, so that what was logged
+// after it, such as the results of notification, is in the persisted log.
+func (b *Courier) PersistLog() *log.Status {
+	if b.run == 0 || b.logFile == `` {
+		return nil
+	}
+	store, status := storage.NewStorage(b.ctx, b.bucket)
+	if status != nil {
+		return status
+	}
+	_, status = b.uploadFile(store, b.run, "log", b.logFile)
+	return status
+}
+
 func (b *Courier) parseYaml(name string) string {
 	var result string
 	index := strings.Index(b.yamlContent, name+":")
@@ -186,26 +219,21 @@ func (b *Courier) parseYaml(name string) string {
 	return result
 }
 
-func (b *Courier) findLastRun(client *s3.Client) (int, *log.Status) {
+func (b *Courier) findLastRun(store storage.Storage) (int, *log.Status) {
 	var result int
-	var status *log.Status
 	prefix := b.username + "/" + b.dataset + "/"
-	output, err := client.ListObjectsV2(b.ctx, &s3.ListObjectsV2Input{
-		Bucket: &b.bucket,
-		Prefix: &prefix,
-	})
-	if err != nil {
-		return result, log.Error(b.ctx, 500, err, "Error listing bucket objects.")
+	objects, status := store.List(b.ctx, prefix)
+	if status != nil {
+		return result, status
 	}
 	maxRun := 0
-	for _, obj := range output.Contents {
-		parts := strings.Split(*obj.Key, "/")
+	for _, obj := range objects {
+		parts := strings.Split(obj.Key, "/")
 		if len(parts) < 4 {
 			continue
 		}
 		runStr := parts[2]
-		var runNum int
-		runNum, err = strconv.Atoi(runStr)
+		runNum, err := strconv.Atoi(runStr)
 		if err != nil {
 			return result, log.Error(b.ctx, 500, err, "Error converting run number to int.")
 		}
@@ -213,43 +241,26 @@ func (b *Courier) findLastRun(client *s3.Client) (int, *log.Status) {
 			maxRun = runNum
 		}
 	}
-	return maxRun, status
+	return maxRun, nil
 }
 
-func (b *Courier) uploadString(client *s3.Client, run int, typ string, filename string, content string) (string, *log.Status) {
-	var objectKey string
-	var status *log.Status
-	objectKey = b.createKey(run, typ, filename)
-	input := &s3.PutObjectInput{
-		Bucket: &b.bucket,
-		Key:    &objectKey,
-		Body:   strings.NewReader(content),
-	}
-	_, err := client.PutObject(b.ctx, input)
-	if err != nil {
-		status = log.Error(b.ctx, 500, err, "Error uploading string content.")
-	}
+func (b *Courier) uploadString(store storage.Storage, run int, typ string, filename string, content string) (string, *log.Status) {
+	objectKey := b.createKey(run, typ, filename)
+	status := store.Upload(b.ctx, objectKey, strings.NewReader(content))
 	return objectKey, status
 }
 
-func (b *Courier) uploadFile(client *s3.Client, run int, typ string, filePath string) (string, *log.Status) {
+func (b *Courier) uploadFile(store storage.Storage, run int, typ string, filePath string) (string, *log.Status) {
 	var objectKey string
 	var status *log.Status
 	file, err := os.Open(filePath)
 	if err != nil {
-		log.Warn(b.ctx, 500, err, "Error opening file to upload to S3.")
+		log.Warn(b.ctx, 500, err, "Error opening file to upload to", store.Name())
 		return objectKey, status
 	}
 	defer file.Close()
 	objectKey = b.createKey(run, typ, filePath)
-	_, err = client.PutObject(b.ctx, &s3.PutObjectInput{
-		Bucket: &b.bucket,
-		Key:    &objectKey,
-		Body:   file,
-	})
-	if err != nil {
-		status = log.Error(b.ctx, 500, err, "Error uploading file to S3.")
-	}
+	status = store.Upload(b.ctx, objectKey, file)
 	return objectKey, status
 }
 
@@ -70,3 +70,27 @@ func TestCourier(t *testing.T) {
 		t.Fatal(status)
 	}
 }
+
+func TestPersistToLocal(t *testing.T) {
+	ctx := context.Background()
+	directory := t.TempDir()
+	t.Setenv(`FCBH_DATASET_IO_BUCKET`, `file://`+directory)
+	t.Setenv(`FCBH_DATASET_LOG_DIR`, ``)
+	t.Setenv(`FCBH_DATASET_LOG_FILE`, ``)
+	t.Setenv(`AWS_EC2_METADATA_DISABLED`, `true`)
+	for run := 1; run <= 2; run++ {
+		b := NewCourier(ctx, []byte(runBucketTest))
+		b.IsUnitTest = true
+		b.AddOutput(`courier.go`)
+		status := b.PersistToBucket()
+		if status != nil {
+			t.Fatal(status)
+		}
+	}
+	for _, key := range []string{`00001/request/MyProject.yaml`, `00001/output/courier.go`, `00002/output/courier.go`} {
+		_, err := os.Stat(filepath.Join(directory, `GaryNTest`, `MyProject`, filepath.FromSlash(key)))
+		if err != nil {
+			t.Error(`Missing run file`, key, err)
+		}
+	}
+}
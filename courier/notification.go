@@ -1,6 +1,7 @@
 package courier
 
 import (
+	"context"
 	"strings"
 	"testing"
 	"time"
@@ -14,16 +15,17 @@ func (b *Courier) Notification(req request.Request, status *log.Status, duration
 	if !testing.Testing() || b.IsUnitTest {
 		var emailRecip []string
 		var sqsURLS []string
+		var webhooks []string
 		var subject string
 		var message string
 		var attachments []string
 		if status == nil {
-			emailRecip, sqsURLS = b.groupRecipients(req.NotifyOk)
+			emailRecip, sqsURLS, webhooks = b.groupRecipients(req.NotifyOk)
 			subject = "SUCCESS: " + b.dataset
 			message = b.successMsg(duration)
 			attachments = b.GetOutputByExt()
 		} else {
-			emailRecip, sqsURLS = b.groupRecipients(req.NotifyErr)
+			emailRecip, sqsURLS, webhooks = b.groupRecipients(req.NotifyErr)
 			subject = "FAILED: " + b.dataset
 			message = b.failureMsg(status, duration)
 			attachments = append(attachments, b.logFile)
@@ -31,8 +33,17 @@ func (b *Courier) Notification(req request.Request, status *log.Status, duration
 		if len(emailRecip) > 0 {
 			_ = GoMailSendMail(b.ctx, emailRecip, subject, message, attachments)
 		}
+		// Webhooks are sent before SQS, because an SQS error ends the notification.
+		// A cancelled job's context is done, but its notify_err webhook must still be sent.
+		if len(webhooks) > 0 {
+			jsonMessage := b.jsonMsg(duration, status)
+			webhookCtx := context.WithoutCancel(b.ctx)
+			for _, webhookURL := range webhooks {
+				_ = WebhookDeliver(webhookCtx, webhookURL, jsonMessage) // result is in the log
+			}
+		}
 		if len(sqsURLS) > 0 {
-			jsonMessage := b.jsonMsg(duration, status == nil)
+			jsonMessage := b.jsonMsg(duration, status)
 			for _, queueURL := range sqsURLS {
 				_, status = SQSEnqueue(b.ctx, queueURL, jsonMessage)
 				if status != nil {
@@ -71,18 +82,43 @@ func (b *Courier) successMsg(duration time.Duration) string {
 }
 
 type CompletionMsg struct {
-	DatasetName string `yaml:"dataset_name"`
-	Success     bool   `yaml:"success"`
-	Completion  string `yaml:"completion"`
-	Duration    string `yaml:"duration"`
-	Bucket      string `yaml:"bucket"`
-	Object      string `yaml:"object"`
+	DatasetName string          `yaml:"dataset_name"`
+	Success     bool            `yaml:"success"`
+	Completion  string          `yaml:"completion"`
+	Duration    string          `yaml:"duration"`
+	Bucket      string          `yaml:"bucket"`
+	Object      string          `yaml:"object"`
+	JobId       string          `yaml:"job_id"`
+	Username    string          `yaml:"username"`
+	Outputs     []string        `yaml:"outputs"` // keys of the outputs in Bucket
+	Stats       CompletionStats `yaml:"stats"`
 }
 
-func (b *Courier) jsonMsg(duration time.Duration, success bool) CompletionMsg {
+// CompletionStats summarize a run
+type CompletionStats struct {
+	Status        int     `yaml:"status"` // http status of the error, 0 on success
+	Message       string  `yaml:"message"`
+	Seconds       float64 `yaml:"seconds"`
+	ChaptersTotal int     `yaml:"chapters_total"` // chapters of the largest stage
+	Outputs       int     `yaml:"outputs"`
+	Databases     int     `yaml:"databases"`
+}
+
+func (b *Courier) jsonMsg(duration time.Duration, status *log.Status) CompletionMsg {
 	var msg CompletionMsg
 	msg.DatasetName = b.dataset
-	msg.Success = success
+	msg.Success = status == nil
+	msg.JobId = b.jobId
+	msg.Username = b.username
+	msg.Outputs = b.outputKeys
+	msg.Stats.Seconds = duration.Seconds()
+	msg.Stats.ChaptersTotal = b.chaptersTotal
+	msg.Stats.Outputs = len(b.outputs)
+	msg.Stats.Databases = len(b.databases)
+	if status != nil {
+		msg.Stats.Status = status.Status
+		msg.Stats.Message = status.Message
+	}
 	denver, err := time.LoadLocation("America/Denver")
 	if err != nil {
 		msg.Completion = time.Now().UTC().Format("2006-01-02T15:04:05Z")
@@ -99,17 +135,22 @@ func (b *Courier) jsonMsg(duration time.Duration, success bool) CompletionMsg {
 	return msg
 }
 
-func (b *Courier) groupRecipients(recipients []string) ([]string, []string) {
+func (b *Courier) groupRecipients(recipients []string) ([]string, []string, []string) {
 	var email []string
 	var sqs []string
+	var webhooks []string
 	for _, recip := range recipients {
 		if strings.HasPrefix(recip, "sqs/") {
 			sqs = append(sqs, recip)
+		} else if isWebhook(recip) {
+			webhooks = append(webhooks, recip)
+		} else if strings.Contains(recip, "://") {
+			log.Warn(b.ctx, "Webhook must be https, it is not sent to", recip)
 		} else {
 			email = append(email, recip)
 		}
 	}
-	return email, sqs
+	return email, sqs, webhooks
 }
 
 //sqs/vessel_AP
@@ -0,0 +1,124 @@
+package courier
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	log "github.com/faithcomesbyhearing/fcbh-dataset-io/logger"
+)
+
+/*
+A webhook recipient of notify_ok or notify_err is an https:// URL.  It is sent the CompletionMsg
+of the run as JSON, in a POST.  Each delivery is signed with FCBH_WEBHOOK_SECRET, in the headers:
+X-FCBH-Timestamp: the unix time of the delivery
+X-FCBH-Signature: sha256={hex HMAC-SHA256 of "{timestamp}.{body}"}
+X-FCBH-Delivery: an id, which is the same for each retry of a delivery
+A delivery that fails with no response, 429 or 5xx is retried with backoff, up to FCBH_WEBHOOK_RETRIES
+(5) times.  http:// is only allowed for localhost, e.g. for testing.
+*/
+
+const (
+	signatureHeader = `X-FCBH-Signature`
+	timestampHeader = `X-FCBH-Timestamp`
+	deliveryHeader  = `X-FCBH-Delivery`
+)
+
+var webhookRetryDelay = time.Second // doubled after each attempt
+
+const maxWebhookRetryDelay = time.Minute
+
+func isWebhook(recipient string) bool {
+	parsed, err := url.Parse(recipient)
+	if err != nil {
+		return false
+	}
+	if parsed.Scheme == `https` {
+		return parsed.Host != ``
+	}
+	if parsed.Scheme == `http` {
+		host := parsed.Hostname()
+		return host == `localhost` || host == `127.0.0.1` || host == `::1`
+	}
+	return false
+}
+
+// SignWebhook returns the signature of a delivery, which a receiver computes to verify it
+func SignWebhook(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + `.`))
+	mac.Write(body)
+	return `sha256=` + hex.EncodeToString(mac.Sum(nil))
+}
+
+// WebhookDeliver posts data to a webhook, and logs the result of each attempt
+func WebhookDeliver(ctx context.Context, webhookURL string, data any) *log.Status {
+	secret := os.Getenv(`FCBH_WEBHOOK_SECRET`)
+	if secret == `` {
+		return log.ErrorNoErr(ctx, 500, `FCBH_WEBHOOK_SECRET is required to sign webhook`, webhookURL)
+	}
+	body, err := json.Marshal(data)
+	if err != nil {
+		return log.Error(ctx, 500, err, "Error Marshalling Webhook Message")
+	}
+	var buf = make([]byte, 12)
+	_, _ = rand.Read(buf)
+	deliveryId := hex.EncodeToString(buf)
+	retries, err := strconv.Atoi(os.Getenv(`FCBH_WEBHOOK_RETRIES`))
+	if err != nil || retries < 0 {
+		retries = 5
+	}
+	var client = http.Client{Timeout: 30 * time.Second}
+	var delay = webhookRetryDelay
+	for attempt := 1; ; attempt++ {
+		code, err := webhookPost(ctx, &client, webhookURL, secret, deliveryId, body)
+		if err == nil {
+			log.Info(ctx, "Webhook delivered to", webhookURL, "status", code, "attempt", attempt, "delivery", deliveryId)
+			return nil
+		}
+		retryable := code == 0 || code == http.StatusTooManyRequests || code >= 500
+		if !retryable || attempt > retries {
+			return log.ErrorNoErr(ctx, 502, "Webhook delivery to", webhookURL, "failed after", attempt,
+				"attempts, delivery", deliveryId, err.Error())
+		}
+		log.Warn(ctx, "Webhook delivery to", webhookURL, "failed, attempt", attempt, err.Error(), "retry in", delay)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return log.Error(ctx, 502, ctx.Err(), "Webhook delivery to", webhookURL, "was stopped")
+		}
+		delay = min(delay*2, maxWebhookRetryDelay)
+	}
+}
+
+// webhookPost returns the http status of the response, or 0 when there was none
+func webhookPost(ctx context.Context, client *http.Client, webhookURL string, secret string, deliveryId string, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, `POST`, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return 400, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set(`Content-Type`, `application/json`)
+	req.Header.Set(timestampHeader, timestamp)
+	req.Header.Set(signatureHeader, SignWebhook(secret, timestamp, body))
+	req.Header.Set(deliveryHeader, deliveryId)
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return resp.StatusCode, errors.New(resp.Status)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,113 @@
+package courier
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/decode_yaml/request"
+	log "github.com/faithcomesbyhearing/fcbh-dataset-io/logger"
+)
+
+func TestWebhookDeliver(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv(`FCBH_WEBHOOK_SECRET`, `test-secret`)
+	webhookRetryDelay = time.Millisecond
+	var lock sync.Mutex
+	var deliveries []string
+	var received CompletionMsg
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		body, _ := io.ReadAll(r.Body)
+		signature := SignWebhook(`test-secret`, r.Header.Get(timestampHeader), body)
+		if r.Header.Get(signatureHeader) != signature {
+			t.Error(`Signature should be`, signature, `it is`, r.Header.Get(signatureHeader))
+		}
+		deliveries = append(deliveries, r.Header.Get(deliveryHeader))
+		if len(deliveries) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_ = json.Unmarshal(body, &received)
+	}))
+	defer server.Close()
+	var msg = CompletionMsg{DatasetName: `MyProject`, Success: true, JobId: `abc123`, Outputs: []string{`a/b/00001/output/c.json`}}
+	status := WebhookDeliver(ctx, server.URL, msg)
+	if status != nil {
+		t.Fatal(status)
+	}
+	if len(deliveries) != 3 || deliveries[0] == `` || deliveries[0] != deliveries[2] {
+		t.Error(`Expected 3 attempts of one delivery`, deliveries)
+	}
+	if received.JobId != `abc123` || len(received.Outputs) != 1 {
+		t.Error(`Unexpected message`, received)
+	}
+}
+
+func TestWebhookNotRetried(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv(`FCBH_WEBHOOK_SECRET`, `test-secret`)
+	webhookRetryDelay = time.Millisecond
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+	status := WebhookDeliver(ctx, server.URL, CompletionMsg{})
+	if status == nil || attempts != 1 {
+		t.Error(`A 400 should fail without retry`, attempts, status)
+	}
+	t.Setenv(`FCBH_WEBHOOK_SECRET`, ``)
+	status = WebhookDeliver(ctx, server.URL, CompletionMsg{})
+	if status == nil || attempts != 1 {
+		t.Error(`A webhook without a secret should not be sent`, attempts)
+	}
+}
+
+func TestGroupRecipients(t *testing.T) {
+	var b = Courier{ctx: context.Background()}
+	email, sqs, webhooks := b.groupRecipients([]string{`gary@shortsands.com`, `sqs/vessel_AP.fifo/GaryNTest`,
+		`https://tracking.example.org/hooks/arti`, `http://localhost:8080/hook`, `http://example.org/hook`})
+	if len(email) != 1 {
+		t.Error(`Unexpected email`, email)
+	}
+	if len(sqs) != 1 {
+		t.Error(`Unexpected sqs`, sqs)
+	}
+	if len(webhooks) != 2 {
+		t.Error(`Unexpected webhooks`, webhooks)
+	}
+}
+
+func TestWebhookAfterCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel() // the job was cancelled
+	t.Setenv(`FCBH_WEBHOOK_SECRET`, `test-secret`)
+	webhookRetryDelay = time.Millisecond
+	var attempts int
+	var received CompletionMsg
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &received)
+	}))
+	defer server.Close()
+	var b = Courier{ctx: ctx, IsUnitTest: true, dataset: `TestWebhookAfterCancel`}
+	var req request.Request
+	req.NotifyErr = []string{server.URL}
+	b.Notification(req, log.ContextError(ctx, `Request stopped`), time.Second)
+	if attempts != 2 || received.Success || received.DatasetName != `TestWebhookAfterCancel` {
+		t.Error(`Expected the notify_err webhook to be retried and delivered after cancel`, attempts, received)
+	}
+}
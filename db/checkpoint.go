@@ -0,0 +1,93 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	log "github.com/faithcomesbyhearing/fcbh-dataset-io/logger"
+)
+
+// Checkpoint records that a pipeline stage completed for a book and chapter.
+// Stages that are not chapter based use an empty BookId and a ChapterNum of 0.
+type Checkpoint struct {
+	Stage      string
+	BookId     string
+	ChapterNum int
+	InputHash  string
+	Completed  string
+}
+
+func createCheckpointTable(w *schemaWriter) {
+	query := `CREATE TABLE IF NOT EXISTS checkpoints (
+		stage TEXT NOT NULL,
+		book_id TEXT NOT NULL DEFAULT '',
+		chapter_num INTEGER NOT NULL DEFAULT 0,
+		input_hash TEXT NOT NULL,
+		completed TEXT NOT NULL,
+		PRIMARY KEY (stage, book_id, chapter_num)) STRICT`
+	w.exec(query)
+}
+
+// SelectCheckpoint returns the input hash recorded when the stage last completed
+// for this book and chapter, or an empty string when it has not completed.
+func (d *DBAdapter) SelectCheckpoint(stage string, bookId string, chapterNum int) (string, *log.Status) {
+	var inputHash string
+	query := `SELECT input_hash FROM checkpoints WHERE stage = ? AND book_id = ? AND chapter_num = ?`
+	err := d.DB.QueryRow(query, stage, bookId, chapterNum).Scan(&inputHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return inputHash, log.Error(d.Ctx, 500, err, query)
+	}
+	return inputHash, nil
+}
+
+func (d *DBAdapter) SelectCheckpoints(stage string) ([]Checkpoint, *log.Status) {
+	var results []Checkpoint
+	query := `SELECT stage, book_id, chapter_num, input_hash, completed FROM checkpoints
+		WHERE stage = ? ORDER BY completed`
+	rows, err := d.DB.Query(query, stage)
+	if err != nil {
+		return results, log.Error(d.Ctx, 500, err, query)
+	}
+	defer d.closeDef(rows, "SelectCheckpoints stmt")
+	for rows.Next() {
+		var rec Checkpoint
+		err = rows.Scan(&rec.Stage, &rec.BookId, &rec.ChapterNum, &rec.InputHash, &rec.Completed)
+		if err != nil {
+			return results, log.Error(d.Ctx, 500, err, query)
+		}
+		results = append(results, rec)
+	}
+	err = rows.Err()
+	if err != nil {
+		log.Warn(d.Ctx, err, query)
+	}
+	return results, nil
+}
+
+func (d *DBAdapter) InsertCheckpoints(records []Checkpoint) *log.Status {
+	query := `REPLACE INTO checkpoints (stage, book_id, chapter_num, input_hash, completed)
+		VALUES (?,?,?,?,?)`
+	tx, stmt := d.prepareDML(query)
+	defer d.closeDef(stmt, "InsertCheckpoints stmt")
+	completed := time.Now().UTC().Format(time.RFC3339)
+	for _, rec := range records {
+		_, err := stmt.Exec(rec.Stage, rec.BookId, rec.ChapterNum, rec.InputHash, completed)
+		if err != nil {
+			return log.Error(d.Ctx, 500, err, `Error while inserting checkpoints.`)
+		}
+	}
+	return d.commitDML(tx, query)
+}
+
+func (d *DBAdapter) DeleteCheckpoints(stage string) *log.Status {
+	query := `DELETE FROM checkpoints WHERE stage = ?`
+	_, err := d.DB.Exec(query, stage)
+	if err != nil {
+		return log.Error(d.Ctx, 500, err, query)
+	}
+	return nil
+}
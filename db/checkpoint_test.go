@@ -0,0 +1,50 @@
+package db
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCheckpoints(t *testing.T) {
+	ctx := context.Background()
+	conn := NewDBAdapter(ctx, ":memory:")
+	defer conn.Close()
+	hash, status := conn.SelectCheckpoint(`timestamps`, `MAT`, 1)
+	if status != nil {
+		t.Fatal(status)
+	}
+	if hash != `` {
+		t.Fatal(`Expected no checkpoint, found`, hash)
+	}
+	var recs = []Checkpoint{
+		{Stage: `timestamps`, BookId: `MAT`, ChapterNum: 1, InputHash: `abc`},
+		{Stage: `timestamps`, BookId: `MAT`, ChapterNum: 2, InputHash: `def`},
+		{Stage: `read_text`, InputHash: `ghi`},
+	}
+	status = conn.InsertCheckpoints(recs)
+	if status != nil {
+		t.Fatal(status)
+	}
+	hash, status = conn.SelectCheckpoint(`timestamps`, `MAT`, 2)
+	if status != nil {
+		t.Fatal(status)
+	}
+	if hash != `def` {
+		t.Error(`Expected def, found`, hash)
+	}
+	status = conn.DeleteCheckpoints(`timestamps`)
+	if status != nil {
+		t.Fatal(status)
+	}
+	checkpoints, status := conn.SelectCheckpoints(`read_text`)
+	if status != nil {
+		t.Fatal(status)
+	}
+	if len(checkpoints) != 1 || checkpoints[0].InputHash != `ghi` {
+		t.Error(`Expected read_text checkpoint to remain`, checkpoints)
+	}
+	hash, _ = conn.SelectCheckpoint(`timestamps`, `MAT`, 1)
+	if hash != `` {
+		t.Error(`Expected timestamps checkpoint to be deleted`, hash)
+	}
+}
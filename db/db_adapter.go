@@ -82,7 +82,13 @@ func NewerDBAdapter(ctx context.Context, isNew bool, user string, project string
 	d.DatabasePath = filepath.Join(directory, d.Database)
 	_, err = os.Stat(d.DatabasePath)
 	doesExist := !os.IsNotExist(err)
+	var kept []Verdict
 	if isNew && doesExist {
+		var status *log.Status
+		kept, status = keepVerdicts(ctx, d.DatabasePath)
+		if status != nil {
+			return d, status
+		}
 		_ = os.Remove(d.DatabasePath)
 	}
 	if !isNew && !doesExist {
@@ -93,10 +99,11 @@ func NewerDBAdapter(ctx context.Context, isNew bool, user string, project string
 		return d, log.Error(ctx, 500, err, `Failed to open database`, d.DatabasePath)
 	}
 	log.Info(d.Ctx, "DB Opened", d.DatabasePath)
-	if isNew {
-		createDatabase(d.DB)
+	_, status := d.Migrate() // Creates a new dataset, or upgrades one created by an earlier version
+	if status != nil {
+		return d, status
 	}
-	return d, nil
+	return d, d.restoreVerdicts(kept)
 }
 
 // NewDBAdapter should be used for  :memory: database and test.
@@ -113,12 +120,16 @@ func NewDBAdapter(ctx context.Context, database string) DBAdapter {
 	d.Project = strings.Split(database, `.`)[0]
 	d.DatabasePath = databasePath
 	d.DB = db
-	createDatabase(db)
+	_, status := d.Migrate()
+	if status != nil {
+		log.Fatal(ctx, status)
+	}
 	return d
 }
 
-func createDatabase(db *sql.DB) {
-	execDDL(db, `PRAGMA temp_store = MEMORY;`)
+// createDatabase creates the tables of the latest schema that do not exist
+func createDatabase(w *schemaWriter) {
+	w.exec(`PRAGMA temp_store = MEMORY;`)
 	var query = `CREATE TABLE IF NOT EXISTS ident (
 		dataset_id INTEGER PRIMARY KEY AUTOINCREMENT,
 		bible_id TEXT NOT NULL,
@@ -135,9 +146,9 @@ func createDatabase(db *sql.DB) {
 		alphabet TEXT NOT NULL,
 		language_name TEXT NOT NULL,
 		version_name TEXT NOT NULL) STRICT`
-	execDDL(db, query)
+	w.exec(query)
 	query = `CREATE UNIQUE INDEX IF NOT EXISTS ident_bible_idx ON ident (bible_id)`
-	execDDL(db, query)
+	w.exec(query)
 	query = `CREATE TABLE IF NOT EXISTS scripts (
 		script_id INTEGER PRIMARY KEY AUTOINCREMENT,
 		dataset_id INTEGER NOT NULL,
@@ -158,14 +169,14 @@ func createDatabase(db *sql.DB) {
 		script_end_ts REAL NOT NULL DEFAULT 0.0,
 		fa_score REAL NOT NULL DEFAULT 0.0,
 		FOREIGN KEY(dataset_id) REFERENCES ident(dataset_id)) STRICT`
-	execDDL(db, query)
+	w.exec(query)
 	query = `CREATE UNIQUE INDEX IF NOT EXISTS scripts_idx
 		ON scripts (book_id, chapter_num, verse_str)`
-	execDDL(db, query)
+	w.exec(query)
 	query = `CREATE INDEX IF NOT EXISTS script_num_idx ON scripts (script_num)`
-	execDDL(db, query)
+	w.exec(query)
 	query = `CREATE INDEX IF NOT EXISTS scripts_file_idx ON scripts (audio_file)`
-	execDDL(db, query)
+	w.exec(query)
 	query = `CREATE TABLE IF NOT EXISTS words (
 		word_id INTEGER PRIMARY KEY AUTOINCREMENT,
 		script_id INTEGER NOT NULL,
@@ -182,24 +193,24 @@ func createDatabase(db *sql.DB) {
 		word_multi_enc TEXT NOT NULL DEFAULT '', -- planned
 		src_word_multi_enc TEXT NOT NULL DEFAULT '', -- planned
 		FOREIGN KEY(script_id) REFERENCES scripts(script_id)) STRICT`
-	execDDL(db, query)
+	w.exec(query)
 	query = `CREATE UNIQUE INDEX IF NOT EXISTS words_idx
 		ON words (script_id, word_seq)`
-	execDDL(db, query)
+	w.exec(query)
 	query = `CREATE TABLE IF NOT EXISTS script_mfcc (
 		script_id INTEGER PRIMARY KEY,
 		rows INTEGER NOT NULL,
 		cols INTEGER NOT NULL,
 		mfcc_json TEXT NOT NULL,
 		FOREIGN KEY (script_id) REFERENCES scripts(script_id)) STRICT`
-	execDDL(db, query)
+	w.exec(query)
 	query = `CREATE TABLE IF NOT EXISTS word_mfcc (
 		word_id INTEGER PRIMARY KEY,
 		rows INTEGER NOT NULL,
 		cols INTEGER NOT NULL,
 		mfcc_json TEXT NOT NULL,
 		FOREIGN KEY (word_id) REFERENCES words(word_id)) STRICT`
-	execDDL(db, query)
+	w.exec(query)
 	query = `CREATE TABLE IF NOT EXISTS chars (
 		char_id INTEGER PRIMARY KEY,
 		word_id INTEGER NOT NULL,
@@ -209,7 +220,9 @@ func createDatabase(db *sql.DB) {
 		end_ts REAL NOT NULL,
 		fa_score REAL NOT NULL,
 		FOREIGN KEY (word_id) REFERENCES words(word_id)) STRICT`
-	execDDL(db, query)
+	w.exec(query)
+	createCheckpointTable(w)
+	createVerdictTable(w)
 }
 
 // CopyDatabase copies a database, closes it and return a connection to the copy
@@ -219,6 +232,10 @@ func (d *DBAdapter) CopyDatabase(suffix string) (DBAdapter, *log.Status) {
 	endName := len(d.DatabasePath) - len(ext)
 	targetPath := d.DatabasePath[:endName] + suffix + ext
 	d.Close()
+	kept, status := keepVerdicts(d.Ctx, targetPath)
+	if status != nil {
+		return result, status
+	}
 	source, err := os.Open(d.DatabasePath)
 	if err != nil {
 		return result, log.Error(d.Ctx, 500, err, `Error Copying Database step 1`)
@@ -244,7 +261,7 @@ func (d *DBAdapter) CopyDatabase(suffix string) (DBAdapter, *log.Status) {
 		return result, log.Error(d.Ctx, 500, err, `Error Copying Database step 4`, result.DatabasePath)
 	}
 	log.Info(d.Ctx, "DB Copied", d.DatabasePath, "to", targetPath)
-	return result, nil
+	return result, result.restoreVerdicts(kept)
 }
 
 func (d *DBAdapter) EraseDatabase() {
@@ -254,6 +271,7 @@ func (d *DBAdapter) EraseDatabase() {
 	execDDL(d.DB, `DELETE FROM script_mfcc`)
 	execDDL(d.DB, `DELETE FROM word_mfcc`)
 	execDDL(d.DB, `DELETE FROM chars`)
+	execDDL(d.DB, `DELETE FROM checkpoints`)
 }
 
 func execDDL(db *sql.DB, sql string) {
@@ -872,6 +890,17 @@ func (d *DBAdapter) SelectScriptTimestamps(bookId string, chapter int) ([]Timest
 	return d.selectTimestamps(query, bookId, chapter)
 }
 
+// SelectScriptTimestampCount returns the number of scripts in a chapter that have timestamps
+func (d *DBAdapter) SelectScriptTimestampCount(bookId string, chapter int) (int, *log.Status) {
+	var count int
+	query := `SELECT count(*) FROM scripts WHERE book_id = ? AND chapter_num = ? AND script_end_ts > 0.0`
+	err := d.DB.QueryRow(query, bookId, chapter).Scan(&count)
+	if err != nil {
+		return count, log.Error(d.Ctx, 500, err, "Error during SelectScriptTimestampCount.")
+	}
+	return count, nil
+}
+
 func (d *DBAdapter) selectTimestamps(query string, bookId string, chapter int) ([]Timestamp, *log.Status) {
 	var results []Timestamp
 	rows, err := d.DB.Query(query, bookId, chapter)
@@ -944,6 +973,32 @@ func (d *DBAdapter) SelectWordsByBookChapter(bookId string, chapter int) ([]Word
 	return results, nil
 }
 
+// SelectWordsByScriptId is used by the word level compare
+func (d *DBAdapter) SelectWordsByScriptId(scriptId int) ([]Word, *log.Status) {
+	var results []Word
+	var query = `SELECT word_id, script_id, word_seq, word, uroman, word_begin_ts, word_end_ts
+		FROM words WHERE ttype = 'W' AND script_id = ? ORDER BY word_seq`
+	rows, err := d.DB.Query(query, scriptId)
+	if err != nil {
+		return results, log.Error(d.Ctx, 500, err, "Error during Select Words By Script Id.")
+	}
+	defer d.closeDef(rows, "SelectWordsByScriptId stmt")
+	for rows.Next() {
+		var rec Word
+		err = rows.Scan(&rec.WordId, &rec.ScriptId, &rec.WordSeq, &rec.Word, &rec.Uroman, &rec.WordBeginTS,
+			&rec.WordEndTS)
+		if err != nil {
+			return results, log.Error(d.Ctx, 500, err, "Error during Select Words By Script Id.")
+		}
+		results = append(results, rec)
+	}
+	err = rows.Err()
+	if err != nil {
+		log.Warn(d.Ctx, err, query)
+	}
+	return results, nil
+}
+
 func (d *DBAdapter) SelectWordTimestamps(bookId string, chapter int) ([]Timestamp, *log.Status) {
 	query := `SELECT w.word_id, s.verse_str, w.word_begin_ts, w.word_end_ts
 		FROM words w JOIN scripts s ON w.script_id = s.script_id
@@ -991,6 +1046,21 @@ func (d *DBAdapter) UpdateScriptTimestamps(scripts []Timestamp) *log.Status {
 	return status
 }
 
+// UpdateScriptBeginEndTS updates script timestamps, without changing the audio_file
+func (d *DBAdapter) UpdateScriptBeginEndTS(scripts []Timestamp) *log.Status {
+	query := `UPDATE scripts SET script_begin_ts = ?, script_end_ts = ? WHERE script_id = ?`
+	tx, stmt := d.prepareDML(query)
+	defer d.closeDef(stmt, "UpdateScriptBeginEndTS stmt")
+	for _, rec := range scripts {
+		_, err := stmt.Exec(rec.BeginTS, rec.EndTS, rec.Id)
+		if err != nil {
+			return log.Error(d.Ctx, 500, err, `Error while updating script begin and end timestamps.`)
+		}
+	}
+	status := d.commitDML(tx, query)
+	return status
+}
+
 func (d *DBAdapter) UpdateEraseScriptText() *log.Status {
 	query := `UPDATE scripts SET script_text = "", uroman = ""`
 	tx, stmt := d.prepareDML(query)
@@ -0,0 +1,237 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	log "github.com/faithcomesbyhearing/fcbh-dataset-io/logger"
+)
+
+/*
+Each dataset records the version of its schema in PRAGMA user_version.  Datasets created
+before versions were recorded are version 0.  When a dataset is opened, the migrations it
+does not have are applied in order.  A new dataset is created by the same migrations, so
+createDatabase must always have the latest schema, and each migration must be safe to
+apply to a dataset that already has its change, e.g. by using addColumn.
+*/
+
+type migration struct {
+	version     int
+	description string
+	apply       func(w *schemaWriter) *log.Status
+}
+
+// schemaConn is the *sql.DB of a dataset, or the *sql.Tx of a migration
+type schemaConn interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+// schemaWriter executes the statements of a migration, and keeps the first error,
+// so that a list of DDL statements need not check each one.
+type schemaWriter struct {
+	ctx    context.Context
+	conn   schemaConn
+	status *log.Status
+}
+
+func (w *schemaWriter) exec(query string) {
+	if w.status != nil {
+		return
+	}
+	_, err := w.conn.Exec(query)
+	if err != nil {
+		w.status = log.Error(w.ctx, 500, err, query)
+	}
+}
+
+var migrations = []migration{
+	{1, `Add the tables and columns added before schema versions`, migrateV1},
+	{2, `Add the verdicts table`, migrateV2},
+}
+
+// SchemaVersion is the version of the schema created by this program
+func SchemaVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+// Migration is the result of migrating one dataset
+type Migration struct {
+	DatabasePath string
+	From         int
+	To           int
+	Status       *log.Status
+}
+
+// Migrate applies each migration that the dataset does not have, and returns the version
+// that the dataset had.
+func (d *DBAdapter) Migrate() (int, *log.Status) {
+	version, status := d.SelectSchemaVersion()
+	if status != nil {
+		return version, status
+	}
+	if version > SchemaVersion() {
+		return version, log.ErrorNoErr(d.Ctx, 400, `Dataset schema version`, version,
+			`is newer than this program's`, SchemaVersion(), d.DatabasePath)
+	}
+	existing, status := d.selectColumns(`scripts`)
+	if status != nil {
+		return version, status
+	}
+	for _, m := range migrations {
+		if m.version <= version {
+			continue
+		}
+		status = d.applyMigration(m)
+		if status != nil {
+			return version, status
+		}
+		if len(existing) > 0 {
+			log.Info(d.Ctx, `Migrated`, d.DatabasePath, `to version`, m.version, m.description)
+		}
+	}
+	return version, nil
+}
+
+// applyMigration applies one migration and records its version in one transaction,
+// so that a failure leaves the dataset at the prior version, with the prior schema.
+func (d *DBAdapter) applyMigration(m migration) *log.Status {
+	tx, err := d.DB.Begin()
+	if err != nil {
+		return log.Error(d.Ctx, 500, err, `Error starting migration`, m.version)
+	}
+	var w = schemaWriter{ctx: d.Ctx, conn: tx}
+	status := m.apply(&w)
+	if status == nil {
+		status = w.status
+	}
+	if status == nil {
+		w.exec(`PRAGMA user_version = ` + strconv.Itoa(m.version))
+		status = w.status
+	}
+	if status != nil {
+		_ = tx.Rollback()
+		return status
+	}
+	err = tx.Commit()
+	if err != nil {
+		return log.Error(d.Ctx, 500, err, `Error committing migration`, m.version)
+	}
+	return nil
+}
+
+func (d *DBAdapter) SelectSchemaVersion() (int, *log.Status) {
+	var version int
+	query := `PRAGMA user_version`
+	err := d.DB.QueryRow(query).Scan(&version)
+	if err != nil {
+		return version, log.Error(d.Ctx, 500, err, query)
+	}
+	return version, nil
+}
+
+// MigrateAll migrates every dataset in directory, which are found as {user}/{project}.db.
+// Files without a scripts table are not datasets, and are skipped.
+func MigrateAll(ctx context.Context, directory string) ([]Migration, *log.Status) {
+	var results []Migration
+	files, err := filepath.Glob(filepath.Join(directory, `*`, `*.db`))
+	if err != nil {
+		return results, log.Error(ctx, 500, err, `Error finding datasets in`, directory)
+	}
+	for _, path := range files {
+		var d DBAdapter
+		d.Ctx = ctx
+		d.User = filepath.Base(filepath.Dir(path))
+		d.Database = filepath.Base(path)
+		d.Project = strings.TrimSuffix(d.Database, `.db`)
+		d.DatabasePath = path
+		d.DB, err = sql.Open("sqlite3", path)
+		if err != nil {
+			results = append(results, Migration{DatabasePath: path,
+				Status: log.Error(ctx, 500, err, `Failed to open database`, path)})
+			continue
+		}
+		columns, status := d.selectColumns(`scripts`)
+		if status == nil && len(columns) > 0 {
+			var result = Migration{DatabasePath: path}
+			result.From, result.Status = d.Migrate()
+			result.To, _ = d.SelectSchemaVersion()
+			results = append(results, result)
+		}
+		d.Close()
+	}
+	return results, nil
+}
+
+// migrateV1 adds the columns that were added to the schema over time, and any missing tables
+func migrateV1(w *schemaWriter) *log.Status {
+	var columns = [][3]string{
+		{`ident`, `asr_language_iso`, `TEXT NOT NULL DEFAULT ''`},
+		{`scripts`, `usfm_style`, `TEXT NOT NULL DEFAULT ''`},
+		{`scripts`, `person`, `TEXT NOT NULL DEFAULT ''`},
+		{`scripts`, `actor`, `TEXT NOT NULL DEFAULT ''`},
+		{`scripts`, `uroman`, `TEXT NOT NULL DEFAULT ''`},
+		{`scripts`, `script_begin_ts`, `REAL NOT NULL DEFAULT 0.0`},
+		{`scripts`, `script_end_ts`, `REAL NOT NULL DEFAULT 0.0`},
+		{`scripts`, `fa_score`, `REAL NOT NULL DEFAULT 0.0`},
+		{`words`, `ttype`, `TEXT NOT NULL DEFAULT 'W'`},
+		{`words`, `uroman`, `TEXT NOT NULL DEFAULT ''`},
+		{`words`, `word_begin_ts`, `REAL NOT NULL DEFAULT 0.0`},
+		{`words`, `word_end_ts`, `REAL NOT NULL DEFAULT 0.0`},
+		{`words`, `fa_score`, `REAL NOT NULL DEFAULT 0.0`},
+		{`words`, `word_enc`, `TEXT NOT NULL DEFAULT ''`},
+		{`words`, `src_word_enc`, `TEXT NOT NULL DEFAULT ''`},
+		{`words`, `word_multi_enc`, `TEXT NOT NULL DEFAULT ''`},
+		{`words`, `src_word_multi_enc`, `TEXT NOT NULL DEFAULT ''`},
+	}
+	for _, col := range columns {
+		status := w.addColumn(col[0], col[1], col[2])
+		if status != nil {
+			return status
+		}
+	}
+	createDatabase(w)
+	return w.status
+}
+
+// addColumn adds a column, unless it already exists.  A missing table is left to createDatabase.
+func (w *schemaWriter) addColumn(table string, column string, definition string) *log.Status {
+	columns, status := tableColumns(w.ctx, w.conn, table)
+	if status != nil || len(columns) == 0 || columns[column] {
+		return status
+	}
+	w.exec(`ALTER TABLE ` + table + ` ADD COLUMN ` + column + ` ` + definition)
+	return w.status
+}
+
+func (d *DBAdapter) selectColumns(table string) (map[string]bool, *log.Status) {
+	return tableColumns(d.Ctx, d.DB, table)
+}
+
+func tableColumns(ctx context.Context, conn schemaConn, table string) (map[string]bool, *log.Status) {
+	var columns = make(map[string]bool)
+	query := `SELECT name FROM pragma_table_info(?)`
+	rows, err := conn.Query(query, table)
+	if err != nil {
+		return columns, log.Error(ctx, 500, err, query)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		err = rows.Scan(&name)
+		if err != nil {
+			return columns, log.Error(ctx, 500, err, query)
+		}
+		columns[name] = true
+	}
+	return columns, nil
+}
+
+// migrateV2 adds the verdicts of reviewers on compare and audio_proof reports
+func migrateV2(w *schemaWriter) *log.Status {
+	createVerdictTable(w)
+	return w.status
+}
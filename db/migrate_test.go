@@ -0,0 +1,145 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// An ident and scripts table from before asr_language_iso, usfm_style and uroman were added
+func createOldDataset(t *testing.T, path string) {
+	_ = os.MkdirAll(filepath.Dir(path), os.ModePerm)
+	conn, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	execDDL(conn, `CREATE TABLE ident (
+		dataset_id INTEGER PRIMARY KEY AUTOINCREMENT,
+		bible_id TEXT NOT NULL,
+		audio_OT_id TEXT NOT NULL,
+		audio_NT_id TEXT NOT NULL,
+		text_OT_id TEXT NOT NULL,
+		text_NT_id TEXT NOT NULL,
+		text_source TEXT NOT NULL,
+		language_iso TEXT NOT NULL,
+		version_code TEXT NOT NULL,
+		language_id INTEGER NOT NULL,
+		rolv_id INTEGER NOT NULL,
+		alphabet TEXT NOT NULL,
+		language_name TEXT NOT NULL,
+		version_name TEXT NOT NULL) STRICT`)
+	execDDL(conn, `CREATE TABLE scripts (
+		script_id INTEGER PRIMARY KEY AUTOINCREMENT,
+		dataset_id INTEGER NOT NULL,
+		book_id TEXT NOT NULL,
+		chapter_num INTEGER NOT NULL,
+		chapter_end INTEGER NOT NULL,
+		verse_str TEXT NOT NULL,
+		verse_end TEXT NOT NULL,
+		verse_num INTEGER NOT NULL,
+		audio_file TEXT NOT NULL,
+		script_num TEXT NOT NULL,
+		script_text TEXT NOT NULL,
+		script_begin_ts REAL NOT NULL DEFAULT 0.0,
+		script_end_ts REAL NOT NULL DEFAULT 0.0) STRICT`)
+	execDDL(conn, `INSERT INTO scripts (dataset_id, book_id, chapter_num, chapter_end, verse_str, verse_end,
+		verse_num, audio_file, script_num, script_text) VALUES (1, 'JHN', 1, 1, '1', '1', 1, 'a.mp3', '1', 'In the beginning')`)
+}
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+	directory := t.TempDir()
+	t.Setenv(`FCBH_DATASET_DB`, directory)
+	createOldDataset(t, filepath.Join(directory, `GaryNTest`, `OldDataset.db`))
+	conn, status := NewerDBAdapter(ctx, false, `GaryNTest`, `OldDataset`)
+	if status != nil {
+		t.Fatal(status)
+	}
+	defer conn.Close()
+	version, status := conn.SelectSchemaVersion()
+	if status != nil {
+		t.Fatal(status)
+	}
+	if version != SchemaVersion() {
+		t.Error(`Expected version`, SchemaVersion(), `found`, version)
+	}
+	scripts, status := conn.SelectScripts()
+	if status != nil {
+		t.Fatal(status)
+	}
+	if len(scripts) != 1 || scripts[0].ScriptText != `In the beginning` {
+		t.Error(`Expected the script to be kept`, scripts)
+	}
+	_, status = conn.SelectIdent()
+	if status != nil {
+		t.Fatal(status)
+	}
+	count, status := conn.CountWordRows()
+	if status != nil || count != 0 {
+		t.Error(`Expected an empty words table`, count, status)
+	}
+}
+
+func TestMigrateAll(t *testing.T) {
+	ctx := context.Background()
+	directory := t.TempDir()
+	createOldDataset(t, filepath.Join(directory, `GaryNTest`, `OldDataset.db`))
+	other, err := sql.Open("sqlite3", filepath.Join(directory, `GaryNTest`, `other.db`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	execDDL(other, `CREATE TABLE notes (note TEXT)`)
+	_ = other.Close()
+	results, status := MigrateAll(ctx, directory)
+	if status != nil {
+		t.Fatal(status)
+	}
+	if len(results) != 1 {
+		t.Fatal(`Expected only the dataset to be migrated`, results)
+	}
+	if results[0].Status != nil || results[0].From != 0 || results[0].To != SchemaVersion() {
+		t.Error(`Expected migration from 0 to`, SchemaVersion(), results[0])
+	}
+	results, _ = MigrateAll(ctx, directory)
+	if len(results) != 1 || results[0].From != SchemaVersion() {
+		t.Error(`Expected a second migration to do nothing`, results)
+	}
+}
+
+func TestMigrateFailure(t *testing.T) {
+	ctx := context.Background()
+	directory := t.TempDir()
+	path := filepath.Join(directory, `GaryNTest`, `DuplicateVerse.db`)
+	createOldDataset(t, path)
+	conn, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A second JHN 1:1 prevents the unique index on scripts
+	execDDL(conn, `INSERT INTO scripts (dataset_id, book_id, chapter_num, chapter_end, verse_str, verse_end,
+		verse_num, audio_file, script_num, script_text) VALUES (1, 'JHN', 1, 1, '1', '1', 1, 'a.mp3', '2', 'was the Word')`)
+	_ = conn.Close()
+	results, status := MigrateAll(ctx, directory)
+	if status != nil {
+		t.Fatal(status)
+	}
+	if len(results) != 1 || results[0].Status == nil || results[0].To != 0 {
+		t.Fatal(`Expected a failed migration that stays at version 0`, results)
+	}
+	var d = DBAdapter{Ctx: ctx, DatabasePath: path}
+	d.DB, err = sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	columns, status := d.selectColumns(`scripts`)
+	if status != nil {
+		t.Fatal(status)
+	}
+	if columns[`usfm_style`] {
+		t.Error(`Expected the columns of the failed migration to be rolled back`)
+	}
+}
@@ -52,6 +52,7 @@ type Word struct {
 	VerseNum    int
 	TType       string
 	Word        string
+	Uroman      string
 	WordBeginTS float64
 	WordEndTS   float64
 	FAScore     float64
@@ -0,0 +1,182 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"time"
+
+	log "github.com/faithcomesbyhearing/fcbh-dataset-io/logger"
+)
+
+// Verdicts of reviewers on the items of compare and audio_proof reports
+const (
+	VerdictRealError     = `real_error`
+	VerdictFalsePositive = `false_positive`
+	VerdictFixed         = `fixed`
+)
+
+// Verdict is a reviewer's decision on one report item.  ItemId is stable across runs,
+// as long as the reference and the difference it reports are the same.
+type Verdict struct {
+	ItemId      string
+	Report      string // compare or audio_proof
+	Ref         string
+	Verdict     string
+	LanguageISO string
+	Reviewer    string
+	Note        string
+	Updated     string
+}
+
+// VerdictCount is the number of verdicts of one kind, for one language and report
+type VerdictCount struct {
+	LanguageISO string
+	Report      string
+	Verdict     string
+	Count       int
+}
+
+func createVerdictTable(w *schemaWriter) {
+	query := `CREATE TABLE IF NOT EXISTS verdicts (
+		item_id TEXT NOT NULL,
+		report TEXT NOT NULL,
+		ref TEXT NOT NULL DEFAULT '',
+		verdict TEXT NOT NULL,
+		language_iso TEXT NOT NULL DEFAULT '',
+		reviewer TEXT NOT NULL DEFAULT '',
+		note TEXT NOT NULL DEFAULT '',
+		updated TEXT NOT NULL,
+		PRIMARY KEY (report, item_id)) STRICT`
+	w.exec(query)
+}
+
+// InsertVerdicts records verdicts, and replaces an earlier verdict on the same item
+func (d *DBAdapter) InsertVerdicts(records []Verdict) *log.Status {
+	query := `REPLACE INTO verdicts (item_id, report, ref, verdict, language_iso, reviewer, note, updated)
+		VALUES (?,?,?,?,?,?,?,?)`
+	tx, stmt := d.prepareDML(query)
+	defer d.closeDef(stmt, "InsertVerdicts stmt")
+	updated := time.Now().UTC().Format(time.RFC3339)
+	for _, rec := range records {
+		if rec.Updated == `` {
+			rec.Updated = updated
+		}
+		_, err := stmt.Exec(rec.ItemId, rec.Report, rec.Ref, rec.Verdict, rec.LanguageISO, rec.Reviewer,
+			rec.Note, rec.Updated)
+		if err != nil {
+			return log.Error(d.Ctx, 500, err, `Error while inserting verdicts.`)
+		}
+	}
+	return d.commitDML(tx, query)
+}
+
+// SelectVerdicts returns the verdicts of a report by item id
+func (d *DBAdapter) SelectVerdicts(report string) (map[string]Verdict, *log.Status) {
+	var results = make(map[string]Verdict)
+	records, status := d.selectVerdicts(` WHERE report = ?`, report)
+	for _, rec := range records {
+		results[rec.ItemId] = rec
+	}
+	return results, status
+}
+
+func (d *DBAdapter) selectVerdicts(where string, args ...any) ([]Verdict, *log.Status) {
+	var results []Verdict
+	query := `SELECT item_id, report, ref, verdict, language_iso, reviewer, note, updated
+		FROM verdicts` + where
+	rows, err := d.DB.Query(query, args...)
+	if err != nil {
+		return results, log.Error(d.Ctx, 500, err, query)
+	}
+	defer d.closeDef(rows, "selectVerdicts stmt")
+	for rows.Next() {
+		var rec Verdict
+		err = rows.Scan(&rec.ItemId, &rec.Report, &rec.Ref, &rec.Verdict, &rec.LanguageISO, &rec.Reviewer,
+			&rec.Note, &rec.Updated)
+		if err != nil {
+			return results, log.Error(d.Ctx, 500, err, query)
+		}
+		results = append(results, rec)
+	}
+	return results, nil
+}
+
+// keepVerdicts reads the verdicts of the dataset at path, before it is replaced by a new dataset
+// or a copy, so that they can be restored into the replacement.  Reviewers' verdicts are not
+// recomputed by a run, and would otherwise be lost each time a dataset is recreated.
+// A dataset that does not exist, or has no verdicts table, has none.
+func keepVerdicts(ctx context.Context, path string) ([]Verdict, *log.Status) {
+	var results []Verdict
+	_, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return results, nil
+	}
+	var d DBAdapter
+	d.Ctx = ctx
+	d.DatabasePath = path
+	d.DB, err = sql.Open("sqlite3", path)
+	if err != nil {
+		return results, log.Error(ctx, 500, err, `Failed to open database`, path)
+	}
+	defer d.Close()
+	columns, status := d.selectColumns(`verdicts`)
+	if status != nil || len(columns) == 0 {
+		return results, status
+	}
+	return d.selectVerdicts(``)
+}
+
+// restoreVerdicts inserts the verdicts kept from the dataset that this one replaced
+func (d *DBAdapter) restoreVerdicts(kept []Verdict) *log.Status {
+	if len(kept) == 0 {
+		return nil
+	}
+	log.Info(d.Ctx, "Restore", len(kept), "verdicts into", d.DatabasePath)
+	return d.InsertVerdicts(kept)
+}
+
+// SelectVerdictCounts counts the verdicts of every dataset in directory, which is usually
+// the directory of one user.  Datasets without a verdicts table are skipped, so that old
+// datasets are not migrated just to be counted.
+func SelectVerdictCounts(ctx context.Context, directory string) ([]VerdictCount, *log.Status) {
+	var results []VerdictCount
+	files, err := filepath.Glob(filepath.Join(directory, `*.db`))
+	if err != nil {
+		return results, log.Error(ctx, 500, err, `Error finding datasets in`, directory)
+	}
+	query := `SELECT language_iso, report, verdict, COUNT(*) FROM verdicts GROUP BY language_iso, report, verdict`
+	for _, path := range files {
+		var d DBAdapter
+		d.Ctx = ctx
+		d.DatabasePath = path
+		d.DB, err = sql.Open("sqlite3", path)
+		if err != nil {
+			return results, log.Error(ctx, 500, err, `Failed to open database`, path)
+		}
+		columns, status := d.selectColumns(`verdicts`)
+		if status != nil || len(columns) == 0 {
+			d.Close()
+			continue
+		}
+		rows, err := d.DB.Query(query)
+		if err != nil {
+			d.Close()
+			return results, log.Error(ctx, 500, err, query, path)
+		}
+		for rows.Next() {
+			var rec VerdictCount
+			err = rows.Scan(&rec.LanguageISO, &rec.Report, &rec.Verdict, &rec.Count)
+			if err != nil {
+				_ = rows.Close()
+				d.Close()
+				return results, log.Error(ctx, 500, err, query, path)
+			}
+			results = append(results, rec)
+		}
+		_ = rows.Close()
+		d.Close()
+	}
+	return results, nil
+}
@@ -0,0 +1,82 @@
+package db
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestVerdicts(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	conn := NewDBAdapter(ctx, filepath.Join(dir, `TestVerdicts.db`))
+	var recs = []Verdict{
+		{ItemId: `MAT 1:1#aaa`, Report: `compare`, Ref: `MAT 1:1`, Verdict: VerdictFalsePositive, LanguageISO: `eng`},
+		{ItemId: `MAT 1:2#bbb`, Report: `compare`, Ref: `MAT 1:2`, Verdict: VerdictRealError, LanguageISO: `eng`},
+		{ItemId: `MAT 1:1#aaa`, Report: `audio_proof`, Ref: `MAT 1:1`, Verdict: VerdictFixed, LanguageISO: `eng`},
+	}
+	status := conn.InsertVerdicts(recs)
+	if status != nil {
+		t.Fatal(status)
+	}
+	recs[1].Verdict = VerdictFalsePositive
+	status = conn.InsertVerdicts(recs[1:2])
+	if status != nil {
+		t.Fatal(status)
+	}
+	verdicts, status := conn.SelectVerdicts(`compare`)
+	if status != nil {
+		t.Fatal(status)
+	}
+	if len(verdicts) != 2 || verdicts[`MAT 1:2#bbb`].Verdict != VerdictFalsePositive || verdicts[`MAT 1:1#aaa`].Updated == `` {
+		t.Error(`Unexpected verdicts`, verdicts)
+	}
+	conn.Close()
+	counts, status := SelectVerdictCounts(ctx, dir)
+	if status != nil {
+		t.Fatal(status)
+	}
+	if len(counts) != 2 {
+		t.Fatal(`Expected 2 counts, found`, counts)
+	}
+	for _, count := range counts {
+		if count.Report == `compare` && (count.Verdict != VerdictFalsePositive || count.Count != 2) {
+			t.Error(`Unexpected compare count`, count)
+		}
+	}
+}
+
+func TestVerdictsKeptByCopy(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv(`FCBH_DATASET_DB`, t.TempDir())
+	base, status := NewerDBAdapter(ctx, true, `GaryNTest`, `TestVerdictsKept`)
+	if status != nil {
+		t.Fatal(status)
+	}
+	audio, status := base.CopyDatabase(`_audio`)
+	if status != nil {
+		t.Fatal(status)
+	}
+	var rec = Verdict{ItemId: `MAT 1:1#aaa`, Report: `compare`, Ref: `MAT 1:1`, Verdict: VerdictFalsePositive}
+	status = audio.InsertVerdicts([]Verdict{rec})
+	if status != nil {
+		t.Fatal(status)
+	}
+	audio.Close()
+	base, status = NewerDBAdapter(ctx, true, `GaryNTest`, `TestVerdictsKept`)
+	if status != nil {
+		t.Fatal(status)
+	}
+	audio, status = base.CopyDatabase(`_audio`)
+	if status != nil {
+		t.Fatal(status)
+	}
+	defer audio.Close()
+	verdicts, status := audio.SelectVerdicts(`compare`)
+	if status != nil {
+		t.Fatal(status)
+	}
+	if verdicts[rec.ItemId].Verdict != VerdictFalsePositive {
+		t.Error(`Verdict lost when the dataset was copied again`, verdicts)
+	}
+}
@@ -3,15 +3,36 @@ package decode_yaml
 import (
 	"bytes"
 	"context"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+
 	"github.com/faithcomesbyhearing/fcbh-dataset-io/decode_yaml/request"
 	log "github.com/faithcomesbyhearing/fcbh-dataset-io/logger"
 	"gopkg.in/yaml.v3"
-	"strings"
 )
 
 type RequestDecoder struct {
 	ctx    context.Context
-	errors []string
+	root   *yaml.Node // the request as YAML nodes, to locate errors
+	errors []ValidationError
+}
+
+// ValidationError is one problem in a request.  Path is the YAML path of the field, such
+// as audio_data.bible_brain, and Line and Column are its location, when it is in the YAML.
+type ValidationError struct {
+	Path    string `json:"path"`
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+func (v ValidationError) String() string {
+	if v.Line > 0 {
+		return `line ` + strconv.Itoa(v.Line) + `, column ` + strconv.Itoa(v.Column) + `: ` + v.Message
+	}
+	return v.Message
 }
 
 func NewRequestDecoder(ctx context.Context) RequestDecoder {
@@ -20,6 +41,11 @@ func NewRequestDecoder(ctx context.Context) RequestDecoder {
 	return r
 }
 
+// Errors returns the problems found by the last Decode or Process
+func (r *RequestDecoder) Errors() []ValidationError {
+	return r.errors
+}
+
 func (r *RequestDecoder) Process(yamlRequest []byte) (request.Request, *log.Status) {
 	var request request.Request
 	var status *log.Status
@@ -33,7 +59,7 @@ func (r *RequestDecoder) Process(yamlRequest []byte) (request.Request, *log.Stat
 	if len(r.errors) > 0 {
 		status = &log.Status{}
 		status.Status = 400
-		status.Message = strings.Join(r.errors, "\n")
+		status.Message = r.errorMessage()
 		return request, status
 	}
 	request.BibleId = strings.ToUpper(request.BibleId)
@@ -46,12 +72,20 @@ func (r *RequestDecoder) Process(yamlRequest []byte) (request.Request, *log.Stat
 
 func (r *RequestDecoder) Decode(requestYaml []byte) (request.Request, *log.Status) {
 	var resp request.Request
+	r.errors = nil
+	r.root = nil
+	var root yaml.Node
+	err := yaml.Unmarshal(requestYaml, &root)
+	if err == nil && len(root.Content) > 0 {
+		r.root = &root
+	}
 	reader := bytes.NewReader(requestYaml)
 	decoder := yaml.NewDecoder(reader)
 	decoder.KnownFields(true)
-	err := decoder.Decode(&resp)
+	err = decoder.Decode(&resp)
 	if err != nil {
-		return resp, log.Error(r.ctx, 400, err, `Error decoding YAML to request`)
+		r.addDecodeErrors(err)
+		return resp, log.ErrorNoErr(r.ctx, 400, `Error decoding YAML to request`+"\n"+r.errorMessage())
 	}
 	resp.Testament.BuildBookMaps() // Builds Map for t.HasOT(bookId), t.HasNT(bookId)
 	return resp, nil
@@ -66,3 +100,113 @@ func (r *RequestDecoder) Encode(req request.Request) (string, *log.Status) {
 	result = string(d)
 	return result, nil
 }
+
+func (r *RequestDecoder) errorMessage() string {
+	var msgs = make([]string, 0, len(r.errors))
+	for _, e := range r.errors {
+		msgs = append(msgs, e.String())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// addError records a problem at path, which is located at the key of path in the YAML,
+// or at the nearest parent key when path is not in the YAML.
+func (r *RequestDecoder) addError(path string, message string) {
+	var v = ValidationError{Path: path, Message: message}
+	node := r.find(path)
+	if node != nil {
+		v.Line = node.Line
+		v.Column = node.Column
+	}
+	r.errors = append(r.errors, v)
+}
+
+// find returns the key node of a dotted YAML path, or of its nearest parent
+func (r *RequestDecoder) find(path string) *yaml.Node {
+	if r.root == nil {
+		return nil
+	}
+	var found = r.root.Content[0]
+	var mapping = found
+	if path == `` {
+		return found
+	}
+	for _, name := range strings.Split(path, `.`) {
+		var next *yaml.Node
+		if mapping.Kind == yaml.MappingNode {
+			for i := 0; i+1 < len(mapping.Content); i += 2 {
+				if mapping.Content[i].Value == name {
+					next = mapping.Content[i]
+					mapping = mapping.Content[i+1]
+					break
+				}
+			}
+		}
+		if next == nil {
+			break
+		}
+		found = next
+	}
+	return found
+}
+
+// findLine returns the path of the key on a line
+func (r *RequestDecoder) findLine(line int) (string, *yaml.Node) {
+	if r.root == nil {
+		return ``, nil
+	}
+	return findLineIn(r.root.Content[0], line, ``)
+}
+
+func findLineIn(mapping *yaml.Node, line int, prefix string) (string, *yaml.Node) {
+	if mapping.Kind != yaml.MappingNode {
+		return ``, nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		key := mapping.Content[i]
+		path := key.Value
+		if prefix != `` {
+			path = prefix + `.` + key.Value
+		}
+		if key.Line == line {
+			return path, key
+		}
+		found, node := findLineIn(mapping.Content[i+1], line, path)
+		if node != nil {
+			return found, node
+		}
+	}
+	return ``, nil
+}
+
+var yamlLineError = regexp.MustCompile(`line (\d+): (.*)$`)
+var yamlUnknownKey = regexp.MustCompile(`^field (\S+) not found in type`)
+
+// addDecodeErrors records each error of the YAML decoder, which reports all unknown keys
+// and mistyped values at once, each with its line.
+func (r *RequestDecoder) addDecodeErrors(err error) {
+	var messages []string
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		messages = typeErr.Errors
+	} else {
+		messages = []string{err.Error()}
+	}
+	for _, msg := range messages {
+		var v = ValidationError{Message: msg}
+		parts := yamlLineError.FindStringSubmatch(msg)
+		if parts != nil {
+			v.Line, _ = strconv.Atoi(parts[1])
+			v.Message = parts[2]
+			if key := yamlUnknownKey.FindStringSubmatch(v.Message); key != nil {
+				v.Message = `Unknown key ` + key[1]
+			}
+			path, node := r.findLine(v.Line)
+			if node != nil {
+				v.Path = path
+				v.Column = node.Column
+			}
+		}
+		r.errors = append(r.errors, v)
+	}
+}
@@ -222,3 +222,59 @@ update_dbp:
 		t.Fatalf("expected no error, got: %v", status)
 	}
 }
+
+func TestDecodeUnknownKeys(t *testing.T) {
+	yaml := `dataset_name: test
+bible_id: ENGWEB
+username: tester
+text_data:
+  bible_brain:
+    text_plain: yes
+  files: /tmp/*.usx
+output:
+  cvs: yes
+`
+	decoder := NewRequestDecoder(context.Background())
+	_, status := decoder.Decode([]byte(yaml))
+	if status == nil {
+		t.Fatal(`Expected unknown keys to be rejected`)
+	}
+	errs := decoder.Errors()
+	if len(errs) != 2 {
+		t.Fatal(`Expected an error for each unknown key`, errs)
+	}
+	if errs[0].Path != `text_data.files` || errs[0].Line != 7 || errs[0].Column != 3 {
+		t.Error(`Expected text_data.files at line 7, column 3`, errs[0])
+	}
+	if errs[1].Path != `output.cvs` || errs[1].Line != 9 {
+		t.Error(`Expected output.cvs at line 9`, errs[1])
+	}
+}
+
+func TestProcessErrorLocation(t *testing.T) {
+	yaml := `dataset_name: test
+bible_id: ENGWEB
+username: tester
+audio_data:
+  bible_brain:
+    mp3_64: yes
+text_data:
+  bible_brain:
+    text_plain: yes
+timestamps:
+  aeneas: yes
+  mms_align: yes
+`
+	decoder := NewRequestDecoder(context.Background())
+	_, status := decoder.Process([]byte(yaml))
+	if status == nil {
+		t.Fatal(`Expected two timestamp fields to be rejected`)
+	}
+	errs := decoder.Errors()
+	if len(errs) != 1 || errs[0].Path != `timestamps` || errs[0].Line != 10 || errs[0].Column != 1 {
+		t.Fatal(`Expected one error at timestamps, line 10`, errs)
+	}
+	if errs[0].Message != `Only 1 field can be set on timestamps: aeneas,mms_align` {
+		t.Error(`Unexpected message`, errs[0].Message)
+	}
+}
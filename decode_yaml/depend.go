@@ -1,65 +1,135 @@
 package decode_yaml
 
-import "github.com/faithcomesbyhearing/fcbh-dataset-io/decode_yaml/request"
+import (
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/decode_yaml/request"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/speech_to_text/stt"
+)
 
 func (r *RequestDecoder) Prereq(req *request.Request) {
-	if req.Timestamps.MMSAlign {
+	if req.Timestamps.Has(`mms_align`) {
 		req.Detail.Words = true
 	}
 }
 
-func (r *RequestDecoder) Depend(req request.Request) {
-	if req.Database.AWSS3 != "" {
-		if req.IsNew {
-			r.errors = append(r.errors, `When database.aws_s3 is set, is_new must be false`)
-		}
-	}
-	if !req.Timestamps.NoTimestamps {
-		if req.AudioData.NoAudio {
-			r.errors = append(r.errors, `Timestamps are requested, but there is no audio`)
-		}
-		if req.TextData.NoText && !req.Timestamps.BibleBrain {
-			r.errors = append(r.errors, `Timestamps are requested, but there is no text`)
-			// The need for text is not a real requirement, but the system is coded to store timestamps
-			// in the scripts table, and it cannot do this unless there is text.  If this becomes
-			// a problem the system could be changed to insert timestamp data without text.
-		}
-	}
-	if req.Timestamps.Aeneas || req.Timestamps.MMSFAVerse || req.Timestamps.MMSAlign {
-		if req.TextData.NoText {
-			r.errors = append(r.errors, `Timestamp estimation requested, but there is no text data`)
-		}
-	}
-	if !req.TextEncoding.NoEncoding {
-		if req.TextData.NoText {
-			r.errors = append(r.errors, `Text encoding requested, but there is no text data`)
-		}
-	}
-	if !req.SpeechToText.NoSpeechToText {
-		if req.AudioData.NoAudio {
-			r.errors = append(r.errors, `Speech to Text is requested, but there is no audio`)
-		}
-		//if req.Timestamps.NoTimestamps {
-		//	r.errors = append(r.errors, `Speech to Text is requested, but there are no timestamps`)
-		//}
-	}
-	if req.AudioEncoding.MFCC {
-		if req.Timestamps.NoTimestamps {
-			r.errors = append(r.errors, `MFCC's are requested', but there are no timestamps`)
-		}
-	}
-	if req.AudioProof.HTMLReport {
-		if req.IsNew {
-			if !req.Timestamps.MMSAlign {
-				r.errors = append(r.errors, `AudioProof is requested, but there is no mms_align`)
-			}
-			if !req.SpeechToText.MMS {
-				r.errors = append(r.errors, `AudioProof is requested, but there is no MMS_ASR`)
-			}
-		} else {
-			if req.AudioProof.BaseDataset == "" {
-				r.errors = append(r.errors, `AudioProof is requested on existing dataset, but there is no BaseDataset`)
+// dependRule is one rule of Depend.  broken finds the problem in a request, and schema returns
+// the same rule as the if and then of a JSON Schema, so that Schema is generated from these rules.
+type dependRule struct {
+	path    string
+	message string
+	broken  func(req request.Request) bool
+	schema  func(s schemaTerms) (map[string]any, map[string]any)
+}
+
+var dependRules = []dependRule{
+	{`database.aws_s3`, `When database.aws_s3 is set, is_new must be false`,
+		func(req request.Request) bool { return req.Database.AWSS3 != "" && req.IsNew },
+		func(s schemaTerms) (map[string]any, map[string]any) {
+			return at(`database.aws_s3`, nonEmpty()), not(s.isNew)
+		}},
+	{`timestamps`, `Timestamps are requested, but there is no audio`,
+		func(req request.Request) bool { return !req.Timestamps.NoTimestamps && req.AudioData.NoAudio },
+		func(s schemaTerms) (map[string]any, map[string]any) {
+			return s.hasTimestamps, s.hasAudio
+		}},
+	// The need for text is not a real requirement, but the system is coded to store timestamps
+	// in the scripts table, and it cannot do this unless there is text.  If this becomes
+	// a problem the system could be changed to insert timestamp data without text.
+	{`timestamps`, `Timestamps are requested, but there is no text`,
+		func(req request.Request) bool {
+			return !req.Timestamps.NoTimestamps && req.TextData.NoText && !req.Timestamps.Has(`bible_brain`)
+		},
+		func(s schemaTerms) (map[string]any, map[string]any) {
+			return allOf(s.hasTimestamps, not(hasProvider(`bible_brain`))), s.hasText
+		}},
+	{`timestamps`, `Timestamp estimation requested, but there is no text data`,
+		func(req request.Request) bool {
+			return (req.Timestamps.Has(`aeneas`) || req.Timestamps.Has(`mms_fa_verse`) ||
+				req.Timestamps.Has(`mms_align`)) && req.TextData.NoText
+		},
+		func(s schemaTerms) (map[string]any, map[string]any) {
+			return anyOf(hasProvider(`aeneas`), hasProvider(`mms_fa_verse`), hasProvider(`mms_align`)), s.hasText
+		}},
+	{`text_encoding`, `Text encoding requested, but there is no text data`,
+		func(req request.Request) bool { return !req.TextEncoding.NoEncoding && req.TextData.NoText },
+		func(s schemaTerms) (map[string]any, map[string]any) {
+			return s.anySelected(`text_encoding`, `no_encoding`), s.hasText
+		}},
+	{`speech_to_text`, `Speech to Text is requested, but there is no audio`,
+		func(req request.Request) bool { return !req.SpeechToText.NoSpeechToText && req.AudioData.NoAudio },
+		func(s schemaTerms) (map[string]any, map[string]any) {
+			return s.anySelected(`speech_to_text`, `no_speech_to_text`), s.hasAudio
+		}},
+	{`speech_to_text`, `Speech to Text is requested with an engine that needs timestamps, but there are no timestamps`,
+		func(req request.Request) bool {
+			engine, _ := stt.Find(req.SpeechToText)
+			return engine.NeedsTimestamps && req.IsNew && req.Timestamps.NoTimestamps
+		},
+		func(s schemaTerms) (map[string]any, map[string]any) {
+			var engines []map[string]any
+			for _, name := range stt.NeedsTimestamps() {
+				engines = append(engines, hasEngine(name))
 			}
+			return allOf(s.isNew, anyOf(engines...)), s.hasTimestamps
+		}},
+	{`output`, `Subtitles are requested, but there are no timestamps`,
+		func(req request.Request) bool {
+			return (req.Output.WebVTT || req.Output.SRT) && req.IsNew && req.Timestamps.NoTimestamps
+		},
+		func(s schemaTerms) (map[string]any, map[string]any) {
+			return allOf(s.isNew, anyOf(at(`output.webvtt`, isTrue()), at(`output.srt`, isTrue()))), s.hasTimestamps
+		}},
+	{`output.karaoke`, `Karaoke subtitles are requested, but there is no mms_align for word timestamps`,
+		func(req request.Request) bool {
+			return req.Output.Karaoke && req.IsNew && !req.Timestamps.Has(`mms_align`)
+		},
+		func(s schemaTerms) (map[string]any, map[string]any) {
+			return allOf(s.isNew, at(`output.karaoke`, isTrue())), hasProvider(`mms_align`)
+		}},
+	{`output.corpus`, `A speech corpus is requested, but there is no audio`,
+		func(req request.Request) bool { return len(req.Output.Corpus.Formats) > 0 && req.AudioData.NoAudio },
+		func(s schemaTerms) (map[string]any, map[string]any) {
+			return s.corpus, s.hasAudio
+		}},
+	{`output.corpus`, `A speech corpus is requested, but there are no timestamps`,
+		func(req request.Request) bool {
+			return len(req.Output.Corpus.Formats) > 0 && req.IsNew && req.Timestamps.NoTimestamps
+		},
+		func(s schemaTerms) (map[string]any, map[string]any) {
+			return allOf(s.isNew, s.corpus), s.hasTimestamps
+		}},
+	{`audio_encoding.mfcc`, `MFCC's are requested', but there are no timestamps`,
+		func(req request.Request) bool { return req.AudioEncoding.MFCC && req.Timestamps.NoTimestamps },
+		func(s schemaTerms) (map[string]any, map[string]any) {
+			return at(`audio_encoding.mfcc`, isTrue()), s.hasTimestamps
+		}},
+	{`audio_proof.html_report`, `AudioProof is requested, but there is no mms_align`,
+		func(req request.Request) bool {
+			return req.AudioProof.HTMLReport && req.IsNew && !req.Timestamps.Has(`mms_align`)
+		},
+		func(s schemaTerms) (map[string]any, map[string]any) {
+			return allOf(s.isNew, s.proof), hasProvider(`mms_align`)
+		}},
+	{`audio_proof.html_report`, `AudioProof is requested, but there is no mms_asr`,
+		func(req request.Request) bool {
+			engine, _ := stt.Find(req.SpeechToText)
+			return req.AudioProof.HTMLReport && req.IsNew && engine.Name != `mms_asr`
+		},
+		func(s schemaTerms) (map[string]any, map[string]any) {
+			return allOf(s.isNew, s.proof), hasEngine(`mms_asr`)
+		}},
+	{`audio_proof`, `AudioProof is requested on existing dataset, but there is no BaseDataset`,
+		func(req request.Request) bool {
+			return req.AudioProof.HTMLReport && !req.IsNew && req.AudioProof.BaseDataset == ""
+		},
+		func(s schemaTerms) (map[string]any, map[string]any) {
+			return allOf(not(s.isNew), s.proof), at(`audio_proof.base_dataset`, nonEmpty())
+		}},
+}
+
+func (r *RequestDecoder) Depend(req request.Request) {
+	for _, rule := range dependRules {
+		if rule.broken(req) {
+			r.addError(rule.path, rule.message)
 		}
 	}
 }
@@ -7,6 +7,7 @@ type Request struct {
 	BibleId       string        `yaml:"bible_id"`
 	LanguageISO   string        `yaml:"language_iso"`
 	AltLanguage   string        `yaml:"alt_language,omitempty"`
+	Workers       int           `yaml:"workers,omitempty"`
 	NotifyOk      []string      `yaml:"notify_ok"`
 	NotifyErr     []string      `yaml:"notify_err"`
 	Output        Output        `yaml:"output,omitempty"`
@@ -23,6 +24,9 @@ type Request struct {
 	AudioProof    AudioProof    `yaml:"audio_proof,omitempty"`
 	Compare       Compare       `yaml:"compare,omitempty"`
 	UpdateDBP     UpdateDBP     `yaml:"update_dbp,omitempty"`
+	Timeouts      Timeouts      `yaml:"timeouts,omitempty"`
+	TSEnsemble    TSEnsemble    `yaml:"timestamp_ensemble,omitempty"`
+	Verdicts      Verdicts      `yaml:"verdicts,omitempty"`
 }
 
 // GetTestUser is used for testing when there is no full request object.
@@ -35,6 +39,21 @@ type Output struct {
 	CSV       bool   `yaml:"csv,omitempty"`
 	JSON      bool   `yaml:"json,omitempty"`
 	Sqlite    bool   `yaml:"sqlite,omitempty"`
+	WebVTT    bool   `yaml:"webvtt,omitempty"`
+	SRT       bool   `yaml:"srt,omitempty"`
+	Cues      string `yaml:"cues,omitempty"` // verse or line
+	Karaoke   bool   `yaml:"karaoke,omitempty"`
+	Corpus    Corpus `yaml:"corpus,omitempty"`
+}
+
+// Corpus exports each aligned verse or line as 16 kHz mono audio, with manifests for training toolkits.
+type Corpus struct {
+	Formats     []string `yaml:"formats,omitempty"`      // huggingface, nemo, kaldi, ljspeech
+	Segments    string   `yaml:"segments,omitempty"`     // verse or line
+	AudioFormat string   `yaml:"audio_format,omitempty"` // wav or flac
+	MinFAScore  float64  `yaml:"min_fa_score,omitempty"`
+	DevBooks    []string `yaml:"dev_books,omitempty"`  // Books of the dev split, all others are train
+	TestBooks   []string `yaml:"test_books,omitempty"` // Books of the test split
 }
 
 type Testament struct {
@@ -145,6 +164,7 @@ const (
 	Audio         MediaType = "audio"
 	AudioDrama    MediaType = "audio_drama"
 	TextUSXEdit   MediaType = "text_usx_edit"
+	TextUSFM      MediaType = "text_usfm"
 	TextPlainEdit MediaType = "text_plain_edit"
 	TextPlain     MediaType = "text_plain"
 	TextScript    MediaType = "text_script"
@@ -215,6 +235,7 @@ type SpeechToText struct {
 	Wav2Vec2ASR    bool    `yaml:"wav2vec2_asr,omitempty"`
 	Whisper        Whisper `yaml:"whisper,omitempty"`
 	MMSASRAlign    bool    `yaml:"mms_asr_align,omitempty"`
+	Engine         string  `yaml:"engine,omitempty"` // Any registered engine, by name
 	NoSpeechToText bool    `yaml:"no_speech_to_text,omitempty"`
 }
 
@@ -251,12 +272,35 @@ type Detail struct {
 }
 
 type Timestamps struct {
-	BibleBrain   bool `yaml:"bible_brain,omitempty"`
-	Aeneas       bool `yaml:"aeneas,omitempty"`
-	TSBucket     bool `yaml:"ts_bucket,omitempty"`
-	MMSFAVerse   bool `yaml:"mms_fa_verse,omitempty"`
-	MMSAlign     bool `yaml:"mms_align,omitempty"`
-	NoTimestamps bool `yaml:"no_timestamps,omitempty"`
+	BibleBrain   bool     `yaml:"bible_brain,omitempty"`
+	Aeneas       bool     `yaml:"aeneas,omitempty"`
+	TSBucket     bool     `yaml:"ts_bucket,omitempty"`
+	MMSFAVerse   bool     `yaml:"mms_fa_verse,omitempty"`
+	MMSAlign     bool     `yaml:"mms_align,omitempty"`
+	Chain        []string `yaml:"chain,omitempty"` // Providers tried in order, for chapters still without timestamps
+	NoTimestamps bool     `yaml:"no_timestamps,omitempty"`
+}
+
+// Has is true when a timestamp provider is selected, either by its own field or in the chain.
+func (t Timestamps) Has(name string) bool {
+	for _, item := range t.Chain {
+		if item == name {
+			return true
+		}
+	}
+	switch name {
+	case `bible_brain`:
+		return t.BibleBrain
+	case `aeneas`:
+		return t.Aeneas
+	case `ts_bucket`:
+		return t.TSBucket
+	case `mms_fa_verse`:
+		return t.MMSFAVerse
+	case `mms_align`:
+		return t.MMSAlign
+	}
+	return false
 }
 
 type AudioEncoding struct {
@@ -272,12 +316,17 @@ type TextEncoding struct {
 type AudioProof struct {
 	HTMLReport  bool   `yaml:"html_report,omitempty"`
 	BaseDataset string `yaml:"base_dataset,omitempty"`
+	Offline     bool   `yaml:"offline,omitempty"` // CSS and scripts are inline
+	Bundle      bool   `yaml:"bundle,omitempty"`  // offline report and its audio in a zip
 }
 
 type Compare struct {
 	HTMLReport      bool            `yaml:"html_report,omitempty"`
 	BaseDataset     string          `yaml:"base_dataset,omitempty"`
 	GordonFilter    int             `yaml:"gordon_filter,omitempty"`
+	WordLevel       bool            `yaml:"word_level,omitempty"` // diff words, which play their own audio
+	Offline         bool            `yaml:"offline,omitempty"`    // CSS and scripts are inline
+	Bundle          bool            `yaml:"bundle,omitempty"`     // offline report and its audio in a zip
 	CompareSettings CompareSettings `yaml:"compare_settings,omitempty"`
 }
 
@@ -304,6 +353,34 @@ type DiacriticalChoice struct {
 	NormalizeNFKD bool `yaml:"normalize_nfkd,omitempty"`
 }
 
+// Timeouts limit how long the whole job, or one stage, may run.
+// Each is a duration such as 90m or 6h, and an empty value means no limit.
+type Timeouts struct {
+	Job           string `yaml:"job,omitempty"`
+	Timestamps    string `yaml:"timestamps,omitempty"`
+	Training      string `yaml:"training,omitempty"`
+	SpeechToText  string `yaml:"speech_to_text,omitempty"`
+	AudioEncoding string `yaml:"audio_encoding,omitempty"`
+	TextEncoding  string `yaml:"text_encoding,omitempty"`
+	AudioProof    string `yaml:"audio_proof,omitempty"`
+	Compare       string `yaml:"compare,omitempty"`
+	UpdateDBP     string `yaml:"update_dbp,omitempty"`
+}
+
+// TSEnsemble compares the timestamps of two or more datasets, verse by verse.
+type TSEnsemble struct {
+	Datasets      []string `yaml:"datasets,omitempty"`
+	Threshold     float64  `yaml:"threshold_sec,omitempty"`
+	Consensus     bool     `yaml:"consensus,omitempty"`
+	MaxFlaggedPct float64  `yaml:"max_flagged_pct,omitempty"`
+}
+
+// Verdicts imports the verdicts that reviewers exported from compare and audio_proof reports.
+type Verdicts struct {
+	Import []string `yaml:"import,omitempty"`
+	Stats  bool     `yaml:"stats,omitempty"`
+}
+
 type UpdateDBP struct {
 	Timestamps         string `yaml:"timestamps,omitempty"`
 	HLS                string `yaml:"hls,omitempty"`
@@ -0,0 +1,238 @@
+package decode_yaml
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/decode_yaml/request"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/speech_to_text/stt"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/timestamp/provider"
+)
+
+/*
+Schema is a JSON Schema of a request, so that editors and web forms can check a request
+before it is submitted.  It is generated from the yaml tags of request.Request, so it
+follows the model.  The one-of rules of Validate, and the rules of Depend, are added as
+if/then rules, each with the message of Validate or Depend as its $comment.
+Any YAML value can be empty, which is why each type also permits null.
+*/
+
+// oneOfSections are the sections where Validate permits only one field to be set
+var oneOfSections = []string{`audio_data`, `text_data`, `timestamps`, `speech_to_text`,
+	`audio_encoding`, `text_encoding`, `compare.compare_settings.double_quotes`,
+	`compare.compare_settings.apostrophe`, `compare.compare_settings.hyphen`,
+	`compare.compare_settings.diacritical_marks`}
+
+type schemaLeaf struct {
+	path     string
+	selected map[string]any
+}
+
+// schemaTerms are the conditions that are shared by the schema of the rules of Depend
+type schemaTerms struct {
+	schema        map[string]any
+	isNew         map[string]any
+	hasAudio      map[string]any
+	hasText       map[string]any
+	hasTimestamps map[string]any
+	proof         map[string]any
+	corpus        map[string]any
+}
+
+func newSchemaTerms(schema map[string]any) schemaTerms {
+	var s = schemaTerms{schema: schema}
+	s.isNew = at(`is_new`, isTrue())
+	s.hasAudio = s.anySelected(`audio_data`, `no_audio`)
+	s.hasText = s.anySelected(`text_data`, `no_text`)
+	s.hasTimestamps = s.anySelected(`timestamps`, `no_timestamps`)
+	s.proof = at(`audio_proof.html_report`, isTrue())
+	s.corpus = at(`output.corpus.formats`, map[string]any{`type`: `array`, `minItems`: 1})
+	return s
+}
+
+// Schema returns the JSON Schema of a YAML request
+func Schema() map[string]any {
+	var schema = typeSchema(reflect.TypeOf(request.Request{}))
+	schema[`$schema`] = `https://json-schema.org/draft/2020-12/schema`
+	schema[`title`] = `FCBH Dataset Request`
+	schema[`type`] = `object`
+	schema[`required`] = []string{`dataset_name`, `username`}
+	schema[`anyOf`] = []any{
+		at(`bible_id`, nonEmpty()),
+		at(`language_iso`, nonEmpty()),
+	}
+	property(schema, `dataset_name`)[`minLength`] = 1
+	property(schema, `username`)[`minLength`] = 1
+	property(schema, `workers`)[`minimum`] = 0
+	property(schema, `output.cues`)[`enum`] = []any{`verse`, `line`, nil}
+	property(schema, `output.corpus.formats`)[`items`] = map[string]any{`enum`: toAny(corpusFormats)}
+	property(schema, `output.corpus.segments`)[`enum`] = []any{`verse`, `line`, nil}
+	property(schema, `output.corpus.audio_format`)[`enum`] = []any{`wav`, `flac`, nil}
+	property(schema, `speech_to_text.engine`)[`enum`] = append(toAny(stt.Names()), nil)
+	property(schema, `timestamps.chain`)[`items`] = map[string]any{`enum`: toAny(provider.Names())}
+	property(schema, `timestamp_ensemble.datasets`)[`not`] = map[string]any{`minItems`: 1, `maxItems`: 1}
+	property(schema, `timestamp_ensemble.threshold_sec`)[`minimum`] = 0
+	property(schema, `timestamp_ensemble.max_flagged_pct`)[`minimum`] = 0
+	property(schema, `verdicts.import`)[`items`] = map[string]any{`type`: `string`, `minLength`: 1}
+	for _, timeout := range property(schema, `timeouts`)[`properties`].(map[string]any) {
+		timeout.(map[string]any)[`pattern`] = `^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`
+	}
+	for _, section := range oneOfSections {
+		var leaves = schemaLeaves(property(schema, section), ``)
+		var rules []any
+		for i, leaf := range leaves {
+			var others []any
+			for j, other := range leaves {
+				if j != i {
+					others = append(others, at(other.path, other.selected))
+				}
+			}
+			rules = append(rules, map[string]any{
+				`$comment`: `Only 1 field can be set on ` + section,
+				`if`:       at(leaf.path, leaf.selected),
+				`then`:     map[string]any{`not`: map[string]any{`anyOf`: others}},
+			})
+		}
+		property(schema, section)[`allOf`] = rules
+	}
+	var rules []any
+	var terms = newSchemaTerms(schema)
+	for _, rule := range dependRules {
+		when, then := rule.schema(terms)
+		rules = append(rules, map[string]any{`$comment`: rule.message, `if`: when, `then`: then})
+	}
+	schema[`allOf`] = rules
+	return schema
+}
+
+// typeSchema is the schema of a Go type, using the yaml names of struct fields
+func typeSchema(t reflect.Type) map[string]any {
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{`type`: []string{`boolean`, `null`}}
+	case reflect.String:
+		return map[string]any{`type`: []string{`string`, `null`}}
+	case reflect.Int:
+		return map[string]any{`type`: []string{`integer`, `null`}}
+	case reflect.Float64:
+		return map[string]any{`type`: []string{`number`, `null`}}
+	case reflect.Slice:
+		return map[string]any{`type`: []string{`array`, `null`}, `items`: typeSchema(t.Elem())}
+	case reflect.Struct:
+		var properties = make(map[string]any)
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.IsExported() {
+				properties[yamlName(field)] = typeSchema(field.Type)
+			}
+		}
+		return map[string]any{`type`: []string{`object`, `null`}, `properties`: properties,
+			`additionalProperties`: false}
+	}
+	return map[string]any{}
+}
+
+// property returns the schema of a dotted path
+func property(schema map[string]any, path string) map[string]any {
+	for _, name := range strings.Split(path, `.`) {
+		schema = schema[`properties`].(map[string]any)[name].(map[string]any)
+	}
+	return schema
+}
+
+// schemaLeaves lists the fields of a one-of section, with the schema that is true when it is set.
+// SetTypeCode is skipped, as it is in checkForOne.
+func schemaLeaves(schema map[string]any, prefix string) []schemaLeaf {
+	var leaves []schemaLeaf
+	var properties = schema[`properties`].(map[string]any)
+	var names = make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if name == `set_type_code` {
+			continue
+		}
+		prop := properties[name].(map[string]any)
+		switch prop[`type`].([]string)[0] {
+		case `object`:
+			leaves = append(leaves, schemaLeaves(prop, prefix+name+`.`)...)
+		case `boolean`:
+			leaves = append(leaves, schemaLeaf{prefix + name, isTrue()})
+		case `string`:
+			leaves = append(leaves, schemaLeaf{prefix + name, nonEmpty()})
+		case `array`:
+			leaves = append(leaves, schemaLeaf{prefix + name, map[string]any{`type`: `array`, `minItems`: 1}})
+		}
+	}
+	return leaves
+}
+
+// anySelected is true when any field of a section, other than the one that selects none, is set
+func (s schemaTerms) anySelected(section string, none string) map[string]any {
+	var selected []map[string]any
+	for _, leaf := range schemaLeaves(property(s.schema, section), ``) {
+		if leaf.path != none {
+			selected = append(selected, at(section+`.`+leaf.path, leaf.selected))
+		}
+	}
+	return anyOf(selected...)
+}
+
+// hasProvider is true when a timestamp provider is selected by its own field or in the chain,
+// like Timestamps.Has
+func hasProvider(name string) map[string]any {
+	return anyOf(at(`timestamps.`+name, isTrue()),
+		at(`timestamps.chain`, map[string]any{`type`: `array`, `contains`: map[string]any{`const`: name}}))
+}
+
+// hasEngine is true when a speech to text engine is selected by name, or by its own field,
+// like stt.Find for the engines whose field has their name
+func hasEngine(name string) map[string]any {
+	return anyOf(at(`speech_to_text.engine`, map[string]any{`const`: name}),
+		at(`speech_to_text.`+name, isTrue()))
+}
+
+// at is a schema that requires the dotted path to be present, and to match leaf
+func at(path string, leaf map[string]any) map[string]any {
+	var names = strings.Split(path, `.`)
+	var schema = leaf
+	for i := len(names) - 1; i >= 0; i-- {
+		schema = map[string]any{
+			`type`:       `object`,
+			`required`:   []string{names[i]},
+			`properties`: map[string]any{names[i]: schema},
+		}
+	}
+	return schema
+}
+
+func isTrue() map[string]any {
+	return map[string]any{`const`: true}
+}
+
+func nonEmpty() map[string]any {
+	return map[string]any{`type`: `string`, `minLength`: 1}
+}
+
+func not(schema map[string]any) map[string]any {
+	return map[string]any{`not`: schema}
+}
+
+func anyOf(schemas ...map[string]any) map[string]any {
+	return map[string]any{`anyOf`: schemas}
+}
+
+func allOf(schemas ...map[string]any) map[string]any {
+	return map[string]any{`allOf`: schemas}
+}
+
+func toAny(values []string) []any {
+	var result = make([]any, 0, len(values))
+	for _, value := range values {
+		result = append(result, value)
+	}
+	return result
+}
@@ -0,0 +1,27 @@
+package decode_yaml
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSchema(t *testing.T) {
+	schema := Schema()
+	_, err := json.Marshal(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if property(schema, `audio_data.bible_brain`)[`additionalProperties`] != false {
+		t.Error(`Expected unknown keys to be rejected`)
+	}
+	if _, ok := property(schema, `testament`)[`properties`].(map[string]any)[`otMap`]; ok {
+		t.Error(`Expected unexported fields to be skipped`)
+	}
+	// mp3_16, mp3_64, opus, file, aws_s3, post, no_audio, but not set_type_code
+	if len(property(schema, `audio_data`)[`allOf`].([]any)) != 7 {
+		t.Error(`Expected a one-of rule for each field of audio_data`)
+	}
+	if len(schema[`allOf`].([]any)) != len(dependRules) {
+		t.Error(`Expected a rule for each rule of Depend`)
+	}
+}
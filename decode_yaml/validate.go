@@ -2,38 +2,49 @@ package decode_yaml
 
 import (
 	"reflect"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/faithcomesbyhearing/fcbh-dataset-io/decode_yaml/request"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/speech_to_text/stt"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/timestamp/provider"
 )
 
 func (r *RequestDecoder) Validate(req *request.Request) {
 	r.checkRequired(req)
 	r.checkTestament(&req.Testament)
-	r.checkAudioData(&req.AudioData, `AudioData`)
-	r.checkTextData(&req.TextData, `TextData`)
-	r.checkSpeechToText(&req.SpeechToText, `SpeechToText`)
+	r.checkOutput(&req.Output)
+	r.checkAudioData(&req.AudioData, `audio_data`)
+	r.checkTextData(&req.TextData, `text_data`)
+	r.checkSpeechToText(&req.SpeechToText, `speech_to_text`)
 	r.checkDetail(&req.Detail)
-	r.checkTimestamps(&req.Timestamps, `Timestamps`)
-	r.checkTraining(&req.Training, `Training`)
-	r.checkAudioEncoding(&req.AudioEncoding, `AudioEncoding`)
-	r.checkTextEncoding(&req.TextEncoding, `TextEncoding`)
+	r.checkTimestamps(&req.Timestamps, `timestamps`)
+	r.checkTraining(&req.Training, `training`)
+	r.checkAudioEncoding(&req.AudioEncoding, `audio_encoding`)
+	r.checkTextEncoding(&req.TextEncoding, `text_encoding`)
+	r.checkTimeouts(req.Timeouts)
+	r.checkTSEnsemble(req.TSEnsemble)
+	r.checkVerdicts(req.Verdicts)
 	//checkCompare(req.Compare, &msgs)
-	r.checkForOne(reflect.ValueOf(req.Compare.CompareSettings.DoubleQuotes), `DoubleQuotes`, true)
-	r.checkForOne(reflect.ValueOf(req.Compare.CompareSettings.Apostrophe), `Apostrophe`, true)
-	r.checkForOne(reflect.ValueOf(req.Compare.CompareSettings.Hyphen), `Hyphen`, true)
-	r.checkForOne(reflect.ValueOf(req.Compare.CompareSettings.DiacriticalMarks), `DiscriticalMarks`, true)
+	r.checkForOne(reflect.ValueOf(req.Compare.CompareSettings.DoubleQuotes), `compare.compare_settings.double_quotes`, true)
+	r.checkForOne(reflect.ValueOf(req.Compare.CompareSettings.Apostrophe), `compare.compare_settings.apostrophe`, true)
+	r.checkForOne(reflect.ValueOf(req.Compare.CompareSettings.Hyphen), `compare.compare_settings.hyphen`, true)
+	r.checkForOne(reflect.ValueOf(req.Compare.CompareSettings.DiacriticalMarks), `compare.compare_settings.diacritical_marks`, true)
 }
 
 func (r *RequestDecoder) checkRequired(req *request.Request) {
 	if req.DatasetName == `` {
-		r.errors = append(r.errors, `Required field dataset_name is empty`)
+		r.addError(`dataset_name`, `Required field dataset_name is empty`)
 	}
 	if req.BibleId == `` && req.LanguageISO == `` {
-		r.errors = append(r.errors, `Required field bible_id: or language_iso: is empty`)
+		r.addError(`bible_id`, `Required field bible_id: or language_iso: is empty`)
 	}
 	if req.Username == `` {
-		r.errors = append(r.errors, `Required field username: is empty`)
+		r.addError(`username`, `Required field username: is empty`)
+	}
+	if req.Workers < 0 {
+		r.addError(`workers`, `Field workers: must not be negative`)
 	}
 	req.DatasetName = strings.Replace(req.DatasetName, ` `, `_`, -1)
 	if req.Compare.BaseDataset != `` {
@@ -48,6 +59,50 @@ func (r *RequestDecoder) checkTestament(req *request.Testament) {
 	}
 }
 
+func (r *RequestDecoder) checkOutput(req *request.Output) {
+	if req.WebVTT || req.SRT {
+		if req.Cues == `` {
+			req.Cues = `verse`
+		}
+		if req.Cues != `verse` && req.Cues != `line` {
+			r.addError(`output.cues`, `output.cues must be verse or line, not `+req.Cues)
+		}
+	}
+	if len(req.Corpus.Formats) > 0 {
+		r.checkCorpus(&req.Corpus)
+	}
+}
+
+var corpusFormats = []string{`huggingface`, `nemo`, `kaldi`, `ljspeech`}
+
+func (r *RequestDecoder) checkCorpus(req *request.Corpus) {
+	for _, format := range req.Formats {
+		if !slices.Contains(corpusFormats, format) {
+			r.addError(`output.corpus.formats`, `output.corpus.formats `+format+` is not one of: `+strings.Join(corpusFormats, `,`))
+		}
+	}
+	if req.Segments == `` {
+		req.Segments = `verse`
+	}
+	if req.Segments != `verse` && req.Segments != `line` {
+		r.addError(`output.corpus.segments`, `output.corpus.segments must be verse or line, not `+req.Segments)
+	}
+	if req.AudioFormat == `` {
+		req.AudioFormat = `wav`
+	}
+	if req.AudioFormat != `wav` && req.AudioFormat != `flac` {
+		r.addError(`output.corpus.audio_format`, `output.corpus.audio_format must be wav or flac, not `+req.AudioFormat)
+	}
+	if req.AudioFormat == `flac` && slices.Contains(req.Formats, `ljspeech`) {
+		r.addError(`output.corpus.audio_format`, `output.corpus format ljspeech requires audio_format wav`)
+	}
+	for _, book := range req.DevBooks {
+		if slices.Contains(req.TestBooks, book) {
+			r.addError(`output.corpus.test_books`, `output.corpus book `+book+` cannot be in both dev_books and test_books`)
+		}
+	}
+}
+
 // checkAudioData Is checking that no more than one item is selected.
 // if none are selected, it will set the default: NoAudio
 func (r *RequestDecoder) checkAudioData(req *request.AudioData, fieldName string) {
@@ -72,6 +127,12 @@ func (r *RequestDecoder) checkSpeechToText(req *request.SpeechToText, fieldName
 	if count == 0 {
 		req.NoSpeechToText = true
 	}
+	if req.Engine != `` {
+		_, ok := stt.Find(*req)
+		if !ok {
+			r.addError(fieldName+`.engine`, fieldName+`.engine `+req.Engine+` is not one of: `+strings.Join(stt.Names(), `,`))
+		}
+	}
 }
 
 func (r *RequestDecoder) checkDetail(req *request.Detail) {
@@ -85,6 +146,15 @@ func (r *RequestDecoder) checkTimestamps(req *request.Timestamps, fieldName stri
 	if count == 0 {
 		req.NoTimestamps = true
 	}
+	for i, name := range req.Chain {
+		info, ok := provider.Find(name)
+		if !ok {
+			r.addError(fieldName+`.chain`, fieldName+`.chain `+name+` is not one of: `+strings.Join(provider.Names(), `,`))
+		} else if i > 0 && !info.ByChapter {
+			// It would overwrite the timestamps of the providers before it in every chapter
+			r.addError(fieldName+`.chain`, fieldName+`.chain `+name+` loads every chapter, it can only be first in the chain`)
+		}
+	}
 }
 
 func (r *RequestDecoder) checkTraining(req *request.Training, fieldName string) {
@@ -110,27 +180,59 @@ func (r *RequestDecoder) checkTextEncoding(req *request.TextEncoding, fieldName
 	}
 }
 
+func (r *RequestDecoder) checkTSEnsemble(req request.TSEnsemble) {
+	if len(req.Datasets) == 1 {
+		r.addError(`timestamp_ensemble.datasets`, `timestamp_ensemble.datasets requires two or more datasets`)
+	}
+	if req.Threshold < 0.0 || req.MaxFlaggedPct < 0.0 {
+		r.addError(`timestamp_ensemble`, `timestamp_ensemble.threshold_sec and max_flagged_pct must not be negative`)
+	}
+}
+
+func (r *RequestDecoder) checkVerdicts(req request.Verdicts) {
+	for _, path := range req.Import {
+		if strings.TrimSpace(path) == `` {
+			r.addError(`verdicts.import`, `verdicts.import has an empty path`)
+		}
+	}
+}
+
+func (r *RequestDecoder) checkTimeouts(req request.Timeouts) {
+	sVal := reflect.ValueOf(req)
+	for i := 0; i < sVal.NumField(); i++ {
+		value := sVal.Field(i).String()
+		if value != `` {
+			_, err := time.ParseDuration(value)
+			if err != nil {
+				path := `timeouts.` + yamlName(sVal.Type().Field(i))
+				r.addError(path, path+` is not a duration, e.g. 90m or 6h: `+value)
+			}
+		}
+	}
+}
+
 func (r *RequestDecoder) checkForOne(structVal reflect.Value, fieldName string, recurse bool) int {
 	var errorCount int
 	var wasSet []string
-	r.checkForOneRecursive(structVal, &wasSet, recurse)
+	r.checkForOneRecursive(structVal, ``, &wasSet, recurse)
 	errorCount += len(wasSet)
 	if len(wasSet) > 1 && recurse {
 		msg := `Only 1 field can be set on ` + fieldName + `: ` + strings.Join(wasSet, `,`)
-		r.errors = append(r.errors, msg)
+		r.addError(fieldName, msg)
 	}
 	return errorCount
 }
 
-func (r *RequestDecoder) checkForOneRecursive(sVal reflect.Value, wasSet *[]string, recurse bool) {
+// checkForOneRecursive lists the fields that are set, by their YAML path within the struct
+func (r *RequestDecoder) checkForOneRecursive(sVal reflect.Value, prefix string, wasSet *[]string, recurse bool) {
 	for i := 0; i < sVal.NumField(); i++ {
 		field := sVal.Field(i)
-		fieldName := sVal.Type().Field(i).Name
 
 		// Skip SetTypeCode as it's a configuration option, not a mutually exclusive choice
-		if fieldName == "SetTypeCode" {
+		if sVal.Type().Field(i).Name == "SetTypeCode" {
 			continue
 		}
+		fieldName := prefix + yamlName(sVal.Type().Field(i))
 
 		if field.Kind() == reflect.String {
 			if field.String() != `` {
@@ -148,11 +250,24 @@ func (r *RequestDecoder) checkForOneRecursive(sVal reflect.Value, wasSet *[]stri
 			if field.Float() != 0 && len(*wasSet) == 0 {
 				*wasSet = append(*wasSet, fieldName)
 			}
+		} else if field.Kind() == reflect.Slice {
+			if field.Len() > 0 {
+				*wasSet = append(*wasSet, fieldName)
+			}
 		} else if field.Kind() == reflect.Struct {
-			r.checkForOneRecursive(field, wasSet, recurse)
+			r.checkForOneRecursive(field, fieldName+`.`, wasSet, recurse)
 		} else {
 			msg := fieldName + ` has unexpected type ` + field.Type().Name()
-			r.errors = append(r.errors, msg)
+			r.addError(fieldName, msg)
 		}
 	}
 }
+
+// yamlName is the key of a field in a YAML request
+func yamlName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get(`yaml`), `,`)
+	if name == `` {
+		return strings.ToLower(field.Name)
+	}
+	return name
+}
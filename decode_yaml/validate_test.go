@@ -5,6 +5,8 @@ import (
 	"os"
 	"strings"
 	"testing"
+
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/decode_yaml/request"
 )
 
 func TestValidate(t *testing.T) {
@@ -38,10 +40,99 @@ func TestValidate(t *testing.T) {
 	d.Prereq(&req)
 	d.Depend(req)
 	if len(d.errors) > 0 {
-		t.Fatal(strings.Join(d.errors, "\n"))
+		t.Fatal(d.errorMessage())
 	}
 }
 
 // I should have a test with multiple error
 // I shoud have a test with one selected, not error
 // I should have a test with none selected
+
+func TestValidateEngine(t *testing.T) {
+	var d = NewRequestDecoder(context.Background())
+	var req = request.SpeechToText{Engine: `vosk`}
+	d.checkSpeechToText(&req, `speech_to_text`)
+	if len(d.errors) != 1 || !strings.Contains(d.errors[0].Message, `mms_asr`) {
+		t.Error(`Expected an error that lists the engines`, d.errors)
+	}
+	d.errors = nil
+	req = request.SpeechToText{Engine: `mms_asr`}
+	d.checkSpeechToText(&req, `speech_to_text`)
+	if len(d.errors) != 0 || req.NoSpeechToText {
+		t.Error(`Expected mms_asr to be valid`, d.errors)
+	}
+}
+
+func TestValidateTimestampChain(t *testing.T) {
+	var d = NewRequestDecoder(context.Background())
+	var req = request.Timestamps{Chain: []string{`bible_brain`, `mms_align`}}
+	d.checkTimestamps(&req, `timestamps`)
+	if len(d.errors) != 0 || req.NoTimestamps {
+		t.Error(`Expected chain to be valid`, d.errors)
+	}
+	req = request.Timestamps{Chain: []string{`bible_brain`, `gentle`}, MMSAlign: true}
+	d.checkTimestamps(&req, `timestamps`)
+	if len(d.errors) != 2 {
+		t.Error(`Expected errors for both fields set, and for gentle`, d.errors)
+	}
+	d.errors = nil
+	req = request.Timestamps{Chain: []string{`mms_align`, `bible_brain`}}
+	d.checkTimestamps(&req, `timestamps`)
+	if len(d.errors) != 1 || !strings.Contains(d.errors[0].Message, `first`) {
+		t.Error(`Expected an error for bible_brain after mms_align`, d.errors)
+	}
+}
+
+func TestValidateTimeouts(t *testing.T) {
+	var d = NewRequestDecoder(context.Background())
+	var req request.Request
+	req.Timeouts.Job = `12h`
+	req.Timeouts.SpeechToText = `90 minutes`
+	d.checkTimeouts(req.Timeouts)
+	if len(d.errors) != 1 || d.errors[0].Path != `timeouts.speech_to_text` {
+		t.Error(`Expected one error for SpeechToText`, d.errors)
+	}
+}
+
+func TestValidateTSEnsemble(t *testing.T) {
+	var d = NewRequestDecoder(context.Background())
+	var req = request.TSEnsemble{Datasets: []string{`ENGWEB_aeneas`}, Threshold: -1.0}
+	d.checkTSEnsemble(req)
+	if len(d.errors) != 2 {
+		t.Error(`Expected errors for one dataset and a negative threshold`, d.errors)
+	}
+}
+
+func TestValidateVerdicts(t *testing.T) {
+	var d = NewRequestDecoder(context.Background())
+	var req = request.Verdicts{Import: []string{`ENGWEB_compare_verdicts.json`, ` `}}
+	d.checkVerdicts(req)
+	if len(d.errors) != 1 {
+		t.Error(`Expected one error for an empty path`, d.errors)
+	}
+}
+
+func TestDependEngine(t *testing.T) {
+	var d = NewRequestDecoder(context.Background())
+	var req request.Request
+	req.IsNew = true
+	req.SpeechToText.Engine = `mms_asr`
+	req.Timestamps.MMSAlign = true
+	req.AudioProof.HTMLReport = true
+	d.Depend(req)
+	if len(d.errors) != 0 {
+		t.Error(`Expected audio_proof with engine mms_asr to be valid`, d.errors)
+	}
+	req.Timestamps = request.Timestamps{NoTimestamps: true}
+	req.AudioProof.HTMLReport = false
+	d.Depend(req)
+	if len(d.errors) != 1 || !strings.Contains(d.errors[0].Message, `needs timestamps`) {
+		t.Error(`Expected an error for mms_asr without timestamps`, d.errors)
+	}
+	d.errors = nil
+	req.SpeechToText.Engine = `whisper`
+	d.Depend(req)
+	if len(d.errors) != 0 {
+		t.Error(`Expected whisper without timestamps to be valid`, d.errors)
+	}
+}
@@ -48,6 +48,9 @@ func (a *Aeneas) ProcessFiles(audioFiles []input.InputFile) *log.Status {
 
 func (a *Aeneas) processScripts(audioFiles []input.InputFile) *log.Status {
 	for _, aFile := range audioFiles {
+		if a.ctx.Err() != nil {
+			return log.ContextError(a.ctx, "Aeneas", aFile.BookId, aFile.Chapter)
+		}
 		scripts, status := a.conn.SelectScriptsByBookChapter(aFile.BookId, aFile.Chapter)
 		if status != nil {
 			return status
@@ -73,6 +76,9 @@ func (a *Aeneas) processScripts(audioFiles []input.InputFile) *log.Status {
 		if status != nil {
 			return status
 		}
+		if !a.detail.Words { // otherwise the file is done after processWords
+			input.FileDone(a.ctx, aFile)
+		}
 		scripts = nil
 	}
 	return nil
@@ -80,6 +86,9 @@ func (a *Aeneas) processScripts(audioFiles []input.InputFile) *log.Status {
 
 func (a *Aeneas) processWords(audioFiles []input.InputFile) *log.Status {
 	for _, aFile := range audioFiles {
+		if a.ctx.Err() != nil {
+			return log.ContextError(a.ctx, "Aeneas", aFile.BookId, aFile.Chapter)
+		}
 		words, status := a.conn.SelectWordsByBookChapter(aFile.BookId, aFile.Chapter)
 		if status != nil {
 			return status
@@ -105,6 +114,7 @@ func (a *Aeneas) processWords(audioFiles []input.InputFile) *log.Status {
 		if status != nil {
 			return status
 		}
+		input.FileDone(a.ctx, aFile)
 		words = nil
 	}
 	return nil
@@ -136,7 +146,7 @@ func (a *Aeneas) executeAeneas(language string, audioFile string, textFile strin
 	}
 	language = `epo` // Esperanto - This should only be used when a language is not supported
 	pythonPath := os.Getenv(`FCBH_AENEAS_PYTHON`)
-	cmd := exec.Command(pythonPath, `-m`, `aeneas.tools.execute_task`,
+	cmd := exec.CommandContext(a.ctx, pythonPath, `-m`, `aeneas.tools.execute_task`,
 		audioFile,
 		textFile,
 		`task_language=`+language+`|os_task_file_format=json|is_text_type=parsed`,
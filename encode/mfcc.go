@@ -36,6 +36,9 @@ func NewMFCC(ctx context.Context, conn db.DBAdapter, bibleId string,
 func (m *MFCC) ProcessFiles(audioFiles []input.InputFile) *log.Status {
 	var status *log.Status
 	for _, aFile := range audioFiles {
+		if m.ctx.Err() != nil {
+			return log.ContextError(m.ctx, "MFCC", aFile.BookId, aFile.Chapter)
+		}
 		var mfccResp MFCCResp
 		mfccResp, status = m.executeLibrosa(aFile.FilePath())
 		if status != nil {
@@ -53,6 +56,7 @@ func (m *MFCC) ProcessFiles(audioFiles []input.InputFile) *log.Status {
 				return status
 			}
 		}
+		input.FileDone(m.ctx, aFile)
 	}
 	return status
 }
@@ -71,7 +75,7 @@ func (m *MFCC) executeLibrosa(audioFile string) (MFCCResp, *log.Status) {
 	var result MFCCResp
 	pythonPath := os.Getenv(`FCBH_LIBROSA_PYTHON`)
 	mfccLibrosaPath := filepath.Join(os.Getenv(`GOPROJ`), `encode`, `mfcc_librosa.py`)
-	cmd := exec.Command(pythonPath, mfccLibrosaPath, audioFile, strconv.Itoa(m.numMFCC))
+	cmd := exec.CommandContext(m.ctx, pythonPath, mfccLibrosaPath, audioFile, strconv.Itoa(m.numMFCC))
 	var stdoutBuf, stderrBuf bytes.Buffer
 	cmd.Stdout = &stdoutBuf
 	cmd.Stderr = &stderrBuf
@@ -2,16 +2,34 @@ package fetch
 
 import (
 	"context"
+	"errors"
 	log "github.com/faithcomesbyhearing/fcbh-dataset-io/logger"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/metrics"
 	"io"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 )
 
-const (
-	HOST = "https://4.dbt.io/api/"
-)
+/*
+Requests to Bible Brain are retried with exponential backoff after a network error, a 429, or a 5xx.
+The defaults can be changed with these environment variables:
+FCBH_DBP_TIMEOUT is the time limit of one API request, default 60s
+FCBH_DBP_DOWNLOAD_TIMEOUT is the time limit of one attempt to download a file, default 30m
+FCBH_DBP_RETRIES is the number of retries after the first attempt, default 5
+FCBH_DBP_CACHE_TTL is how long Bible info and fileset listings are cached on disk, default 1h, 0 is no cache
+FCBH_DBP_HOST is the base URL of the DBP API, default https://4.dbt.io/api/, e.g. a fetch/fixture server
+*/
+
+// HOST is the base URL of the DBP API.  It can be set in a test, e.g. to an httptest server of fetch/fixture.
+var HOST = dbpHost()
+
+// retryDelay is the wait before the first retry, and it is doubled before each further retry
+var retryDelay = time.Second
+
+const maxRetryDelay = 30 * time.Second
 
 func HttpGet(ctx context.Context, url string, desc string) ([]byte, *log.Status) {
 	return httpGet(ctx, url, false, desc)
@@ -19,25 +37,118 @@ func HttpGet(ctx context.Context, url string, desc string) ([]byte, *log.Status)
 
 func httpGet(ctx context.Context, url string, ok403 bool, desc string) ([]byte, *log.Status) {
 	var body []byte
-	if strings.Contains(url, HOST) {
-		url += `&limit=100000&key=` + os.Getenv(`FCBH_DBP_KEY`)
+	var client = http.Client{Timeout: envDuration(ctx, `FCBH_DBP_TIMEOUT`, 60*time.Second)}
+	url = withKey(url)
+	for attempt := 0; ; attempt++ {
+		var code int
+		var err error
+		body, code, err = httpGetOnce(ctx, &client, url)
+		if err == nil {
+			return body, nil
+		}
+		if ok403 && code == 403 {
+			var status log.Status
+			status.Status = 403
+			return body, &status
+		}
+		if !retryable(code) || attempt >= envInt(ctx, `FCBH_DBP_RETRIES`, 5) || !waitToRetry(ctx, attempt) {
+			if code == 0 {
+				return body, log.Error(ctx, 500, err, "Error in DBP API request for:", desc)
+			}
+			return body, log.ErrorNoErr(ctx, code, err.Error(), desc)
+		}
+		metrics.DownloadRetries.Inc(`api`)
+		log.Warn(ctx, "Retrying DBP API request for:", desc, err)
 	}
-	resp, err := http.Get(url)
+}
+
+// httpGetOnce returns the http status, or 0 when there was no response
+func httpGetOnce(ctx context.Context, client *http.Client, url string) ([]byte, int, error) {
+	var body []byte
+	req, err := http.NewRequestWithContext(ctx, `GET`, url, nil)
 	if err != nil {
-		return body, log.Error(ctx, resp.StatusCode, err, "Error in DBP API request for:", desc)
+		return body, 500, err
 	}
-	defer resp.Body.Close()
-	if ok403 && resp.StatusCode == 403 {
-		var status log.Status
-		status.Status = 403
-		return body, &status
+	resp, err := client.Do(req)
+	if err != nil {
+		return body, 0, err
 	}
-	if resp.Status[0] != '2' {
-		return body, log.ErrorNoErr(ctx, resp.StatusCode, resp.Status, desc)
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return body, resp.StatusCode, errors.New(resp.Status)
 	}
 	body, err = io.ReadAll(resp.Body)
+	metrics.DownloadBytes.Add(float64(len(body)))
+	if err != nil {
+		return body, 0, err
+	}
+	return body, resp.StatusCode, nil
+}
+
+func dbpHost() string {
+	host := os.Getenv(`FCBH_DBP_HOST`)
+	if host == `` {
+		return "https://4.dbt.io/api/"
+	}
+	if !strings.HasSuffix(host, `/`) {
+		host += `/`
+	}
+	return host
+}
+
+// withKey adds the DBP key to requests of the DBP API
+func withKey(url string) string {
+	if strings.Contains(url, HOST) {
+		if !strings.Contains(url, `?`) {
+			url += `?` // e.g. a file of a fixture server
+		}
+		url += `&limit=100000&key=` + os.Getenv(`FCBH_DBP_KEY`)
+	}
+	return url
+}
+
+// retryable is true for a network error, which has no status, for too many requests, and for server errors.
+// 416 is retryable, because the partial file that caused it is removed.
+func retryable(code int) bool {
+	return code == 0 || code == 416 || code == 429 || code >= 500
+}
+
+// waitToRetry waits before a retry, and is false when the context is done first
+func waitToRetry(ctx context.Context, attempt int) bool {
+	var delay = retryDelay << attempt
+	if delay > maxRetryDelay || delay <= 0 {
+		delay = maxRetryDelay
+	}
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(delay):
+		return true
+	}
+}
+
+func envDuration(ctx context.Context, name string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == `` {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		log.Warn(ctx, name, `is not a duration, using`, defaultValue, err)
+		return defaultValue
+	}
+	return duration
+}
+
+func envInt(ctx context.Context, name string, defaultValue int) int {
+	value := os.Getenv(name)
+	if value == `` {
+		return defaultValue
+	}
+	number, err := strconv.Atoi(value)
 	if err != nil {
-		return body, log.Error(ctx, resp.StatusCode, err, "Error reading DBP API response for:", desc)
+		log.Warn(ctx, name, `is not a number, using`, defaultValue, err)
+		return defaultValue
 	}
-	return body, nil
+	return number
 }
@@ -4,7 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 func TestAPIClient(t *testing.T) {
@@ -22,3 +25,53 @@ func TestAPIClient(t *testing.T) {
 	}
 	fmt.Println(response)
 }
+
+func TestHttpGetRetry(t *testing.T) {
+	ctx := context.Background()
+	retryDelay = time.Millisecond
+	var count int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count++
+		if count < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":[]}`))
+	}))
+	defer server.Close()
+	content, status := HttpGet(ctx, server.URL, "test")
+	if status != nil {
+		t.Fatal(status)
+	}
+	if string(content) != `{"data":[]}` || count != 3 {
+		t.Error(`Expected success on the third attempt`, count, string(content))
+	}
+	count = -100
+	t.Setenv(`FCBH_DBP_RETRIES`, `2`)
+	_, status = HttpGet(ctx, server.URL, "test")
+	if status == nil || status.Status != 503 || count != -97 {
+		t.Error(`Expected 503 after 3 attempts`, count, status)
+	}
+}
+
+func TestHttpGetNoServer(t *testing.T) {
+	ctx := context.Background()
+	retryDelay = time.Millisecond
+	t.Setenv(`FCBH_DBP_RETRIES`, `1`)
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	_, status := HttpGet(ctx, server.URL, "test")
+	if status == nil || status.Status != 500 {
+		t.Error(`Expected a 500 error, when there is no response`, status)
+	}
+	var count int
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count++
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+	_, status = httpGet(ctx, server.URL, true, "test")
+	if status == nil || status.Status != 403 || count != 1 {
+		t.Error(`Expected a 403 without retries`, count, status)
+	}
+}
@@ -70,9 +70,9 @@ type BibleInfoRespType struct {
 func (d *APIDBPClient) BibleInfo() (BibleInfoType, *log.Status) {
 	var result BibleInfoType
 	var status *log.Status
-	var get = `https://4.dbt.io/api/bibles/` + d.bibleId + `?v=4`
+	var get = HOST + `bibles/` + d.bibleId + `?v=4`
 	var response BibleInfoRespType
-	body, status := httpGet(d.ctx, get, false, d.bibleId)
+	body, status := cachedGet(d.ctx, get, false, d.bibleId)
 	if status != nil {
 		return result, status
 	}
@@ -10,6 +10,10 @@ import (
 	"strings"
 )
 
+// NoTimestamps is the message of the status returned by LoadTimestamps when Bible Brain
+// has no timestamps for the fileset
+const NoTimestamps = `There are no timestamps available`
+
 type APIDBPTimestamps struct {
 	ctx     context.Context
 	conn    db.DBAdapter
@@ -35,7 +39,7 @@ func (a *APIDBPTimestamps) LoadTimestamps(testament request.Testament) (bool, *l
 	coreId := strings.Split(a.audioId, "-")[0]
 	_, ok := audioIdMap[coreId]
 	if !ok {
-		status = log.ErrorNoErr(a.ctx, 400, `There are no timestamps available`)
+		status = log.ErrorNoErr(a.ctx, 400, NoTimestamps)
 		return false, status
 	}
 	scripts, status := a.conn.SelectScriptIds()
@@ -90,7 +94,7 @@ func (a *APIDBPTimestamps) LoadTimestamps(testament request.Testament) (bool, *l
 func (a *APIDBPTimestamps) HavingTimestamps() (map[string]bool, *log.Status) {
 	var result = make(map[string]bool)
 	var status *log.Status
-	var get = `https://4.dbt.io/api/timestamps?v=4`
+	var get = HOST + `timestamps?v=4`
 	body, status := httpGet(a.ctx, get, false, `timestamps`)
 	if status != nil {
 		return result, status
@@ -130,7 +134,7 @@ func (a *APIDBPTimestamps) Timestamps(bookId string, chapter int) ([]Timestamp,
 	var status *log.Status
 	chapterStr := strconv.Itoa(chapter)
 	coreId := strings.Split(a.audioId, "-")[0]
-	var get = `https://4.dbt.io/api/timestamps/` + coreId + `/` + bookId + `/` + chapterStr + `?v=4`
+	var get = HOST + `timestamps/` + coreId + `/` + bookId + `/` + chapterStr + `?v=4`
 	body, status := httpGet(a.ctx, get, false, `timestamps`)
 	if status != nil {
 		return result, status
@@ -67,24 +67,12 @@ func (d *APIDownloadClient) Download(info BibleInfoType) *log.Status {
 				return status
 			}
 		} else {
-			var locations []LocationRec
-			locations, status = d.downloadLocation(rec.Id)
-			if status != nil {
-				if status.Status == 403 {
-					locations, status = d.downloadEachLocation(rec)
-				} else {
-					return status
-				}
-			}
-			if status != nil {
-				return status
+			status = d.downloadFileset(directory, rec)
+			if status != nil && status.Status == 403 {
+				// The signed urls of a cached listing might have expired
+				removeCached(d.locationURL(rec.Id))
+				status = d.downloadFileset(directory, rec)
 			}
-			locations, status = d.sortFileLocations(locations)
-			if status != nil {
-				return status
-			}
-			directory2 := filepath.Join(directory, rec.Id)
-			status = d.downloadFiles(directory2, locations)
 			if status != nil {
 				return status
 			}
@@ -93,8 +81,24 @@ func (d *APIDownloadClient) Download(info BibleInfoType) *log.Status {
 	return status
 }
 
+func (d *APIDownloadClient) downloadFileset(directory string, fileset FilesetType) *log.Status {
+	locations, status := d.downloadLocation(fileset.Id)
+	if status != nil {
+		if status.Status == 403 {
+			locations, status = d.downloadEachLocation(fileset)
+		}
+		if status != nil {
+			return status
+		}
+	}
+	locations, status = d.sortFileLocations(locations)
+	if status != nil {
+		return status
+	}
+	return d.downloadFiles(filepath.Join(directory, fileset.Id), locations)
+}
+
 func (d *APIDownloadClient) downloadPlainText(directory string, filesetId string) *log.Status {
-	var content []byte
 	var status *log.Status
 	filename := filesetId + ".json"
 	filePath := filepath.Join(directory, filename)
@@ -102,10 +106,7 @@ func (d *APIDownloadClient) downloadPlainText(directory string, filesetId string
 	if os.IsNotExist(err) {
 		var get = HOST + "download/" + filesetId + "?v=4&limit=100000"
 		fmt.Println("Downloading to", filePath)
-		content, status = httpGet(d.ctx, get, false, filesetId)
-		if status == nil {
-			d.saveFile(filePath, content)
-		}
+		status = downloadFile(d.ctx, get, filePath, 0, filesetId)
 	}
 	return status
 }
@@ -127,14 +128,8 @@ type LocationDownloadRec struct {
 func (d *APIDownloadClient) downloadLocation(filesetId string) ([]LocationRec, *log.Status) {
 	var result []LocationRec
 	var status *log.Status
-	var get string
-	if strings.Contains(filesetId, `usx`) {
-		get = HOST + "bibles/filesets/" + filesetId + "/ALL/1?v=4&limit=100000"
-	} else {
-		get = HOST + "download/" + filesetId + "?v=4"
-	}
 	var content []byte
-	content, status = httpGet(d.ctx, get, true, filesetId)
+	content, status = cachedGet(d.ctx, d.locationURL(filesetId), true, filesetId)
 	if status != nil {
 		return result, status
 	}
@@ -148,6 +143,13 @@ func (d *APIDownloadClient) downloadLocation(filesetId string) ([]LocationRec, *
 	return result, status
 }
 
+func (d *APIDownloadClient) locationURL(filesetId string) string {
+	if strings.Contains(filesetId, `usx`) {
+		return HOST + "bibles/filesets/" + filesetId + "/ALL/1?v=4&limit=100000"
+	}
+	return HOST + "download/" + filesetId + "?v=4"
+}
+
 // downloadEachLocation is used when downloadLocation fails on a 403 error.
 // It accesses the location of one chapter at a time using the /bibles/fileset path
 func (d *APIDownloadClient) downloadEachLocation(fileset FilesetType) ([]LocationRec, *log.Status) {
@@ -198,7 +200,6 @@ func (d *APIDownloadClient) sortFileLocations(locations []LocationRec) ([]Locati
 }
 
 func (d *APIDownloadClient) downloadFiles(directory string, locations []LocationRec) *log.Status {
-	var status *log.Status
 	_, err := os.Stat(directory)
 	if os.IsNotExist(err) {
 		err = os.MkdirAll(directory, 0755)
@@ -212,33 +213,12 @@ func (d *APIDownloadClient) downloadFiles(directory string, locations []Location
 			file, err := os.Stat(filePath)
 			if os.IsNotExist(err) || file.Size() != int64(loc.FileSize) {
 				fmt.Println("Downloading", loc.Filename)
-				var content []byte
-				content, status = httpGet(d.ctx, loc.URL, false, loc.Filename)
-				if status == nil {
-					if len(content) != loc.FileSize {
-						log.Warn(d.ctx, "Warning for", loc.Filename, "has an expected size of", loc.FileSize, "but, actual size is", len(content))
-					}
-					status = d.saveFile(filePath, content)
+				status := downloadFile(d.ctx, loc.URL, filePath, int64(loc.FileSize), loc.Filename)
+				if status != nil {
+					return status
 				}
 			}
 		}
 	}
-	return status
-}
-
-func (d *APIDownloadClient) saveFile(filePath string, content []byte) *log.Status {
-	var status *log.Status
-	fp, err := os.Create(filePath)
-	if err != nil {
-		return log.Error(d.ctx, 500, err, "Error Creating file during download.")
-	}
-	_, err = fp.Write(content)
-	if err != nil {
-		return log.Error(d.ctx, 500, err, "Error writing to file during download.")
-	}
-	err = fp.Close()
-	if err != nil {
-		return log.Error(d.ctx, 500, err, "Error closing file during download.")
-	}
-	return status
+	return nil
 }
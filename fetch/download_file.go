@@ -0,0 +1,146 @@
+package fetch
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	log "github.com/faithcomesbyhearing/fcbh-dataset-io/logger"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/metrics"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// downloadFile streams url to filePath.  It is written to filePath.part, which is renamed when
+// it is complete and verified, so that an interrupted download never leaves a partial file
+// under its real name.  A retry, or a later run, resumes the .part file with a Range request.
+// The size is checked when it is known.  The location listing has no checksum, so the MD5 is
+// checked against the ETag, when the ETag is an MD5, as it is for files on S3.
+func downloadFile(ctx context.Context, url string, filePath string, size int64, desc string) *log.Status {
+	var partPath = filePath + `.part`
+	var client = http.Client{Timeout: envDuration(ctx, `FCBH_DBP_DOWNLOAD_TIMEOUT`, 30*time.Minute)}
+	url = withKey(url)
+	for attempt := 0; ; attempt++ {
+		etag, code, err := downloadPart(ctx, &client, url, partPath, size)
+		if err == nil {
+			err = verifyFile(ctx, partPath, size, etag, desc)
+			if err != nil {
+				_ = os.Remove(partPath)
+				code = 502
+			}
+		}
+		if err == nil {
+			break
+		}
+		if !retryable(code) || attempt >= envInt(ctx, `FCBH_DBP_RETRIES`, 5) || !waitToRetry(ctx, attempt) {
+			if code == 0 {
+				return log.Error(ctx, 500, err, "Error downloading", desc)
+			}
+			return log.ErrorNoErr(ctx, code, err.Error(), desc)
+		}
+		metrics.DownloadRetries.Inc(`file`)
+		log.Warn(ctx, "Retrying download of", desc, err)
+	}
+	err := os.Rename(partPath, filePath)
+	if err != nil {
+		return log.Error(ctx, 500, err, "Error renaming file during download.")
+	}
+	return nil
+}
+
+// downloadPart appends the rest of the file to partPath, and returns the ETag of the file.
+// The http status is returned with an error, or 0 when there was no response.
+func downloadPart(ctx context.Context, client *http.Client, url string, partPath string, size int64) (string, int, error) {
+	var offset int64
+	info, err := os.Stat(partPath)
+	if err == nil {
+		offset = info.Size()
+	}
+	if size > 0 && offset == size {
+		return ``, 200, nil // complete, but not yet verified
+	}
+	if size > 0 && offset > size {
+		_ = os.Remove(partPath)
+		offset = 0
+	}
+	req, err := http.NewRequestWithContext(ctx, `GET`, url, nil)
+	if err != nil {
+		return ``, 500, err
+	}
+	if offset > 0 {
+		req.Header.Set(`Range`, `bytes=`+strconv.FormatInt(offset, 10)+`-`)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return ``, 0, err
+	}
+	defer resp.Body.Close()
+	var flags = os.O_CREATE | os.O_WRONLY
+	if resp.StatusCode == http.StatusPartialContent {
+		flags |= os.O_APPEND
+	} else if resp.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+		// The part is not of this file, so it is started again
+		_ = os.Remove(partPath)
+		return ``, resp.StatusCode, errors.New(resp.Status)
+	} else if resp.StatusCode/100 == 2 {
+		flags |= os.O_TRUNC
+	} else {
+		return ``, resp.StatusCode, errors.New(resp.Status)
+	}
+	fp, err := os.OpenFile(partPath, flags, 0644)
+	if err != nil {
+		return ``, 500, err
+	}
+	written, err := io.Copy(fp, resp.Body)
+	metrics.DownloadBytes.Add(float64(written))
+	closeErr := fp.Close()
+	if err != nil {
+		return ``, 0, err // the part is kept, so that the retry resumes
+	}
+	if closeErr != nil {
+		return ``, 500, closeErr
+	}
+	return resp.Header.Get(`ETag`), resp.StatusCode, nil
+}
+
+var md5ETag = regexp.MustCompile(`^[0-9a-fA-F]{32}$`)
+
+// verifyFile checks the MD5 when the ETag is one, and otherwise the size, when it is known.
+// When the MD5 matches, a different size is only a warning, because the listing can be wrong.
+func verifyFile(ctx context.Context, filePath string, size int64, etag string, desc string) error {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return err
+	}
+	etag = strings.Trim(strings.TrimPrefix(etag, `W/`), `"`)
+	if md5ETag.MatchString(etag) {
+		fp, err := os.Open(filePath)
+		if err != nil {
+			return err
+		}
+		defer fp.Close()
+		hash := md5.New()
+		_, err = io.Copy(hash, fp)
+		if err != nil {
+			return err
+		}
+		checksum := hex.EncodeToString(hash.Sum(nil))
+		if !strings.EqualFold(checksum, etag) {
+			return fmt.Errorf("%s has MD5 %s, but %s was expected", desc, checksum, etag)
+		}
+		if size > 0 && info.Size() != size {
+			log.Warn(ctx, "Warning for", desc, "has an expected size of", size, "but, actual size is", info.Size())
+		}
+		return nil
+	}
+	if size > 0 && info.Size() != size {
+		return fmt.Errorf("%s has size %d, but %d was expected", desc, info.Size(), size)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package fetch
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func fileServer(content string, etag string, ranges *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*ranges = append(*ranges, r.Header.Get(`Range`))
+		w.Header().Set(`ETag`, `"`+etag+`"`)
+		http.ServeContent(w, r, `MAT_001.mp3`, time.Time{}, strings.NewReader(content))
+	}))
+}
+
+func TestDownloadFileResume(t *testing.T) {
+	ctx := context.Background()
+	content := strings.Repeat(`In the beginning was the Word. `, 1000)
+	hash := md5.Sum([]byte(content))
+	var ranges []string
+	server := fileServer(content, hex.EncodeToString(hash[:]), &ranges)
+	defer server.Close()
+	filePath := filepath.Join(t.TempDir(), `MAT_001.mp3`)
+	err := os.WriteFile(filePath+`.part`, []byte(content[:1000]), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	status := downloadFile(ctx, server.URL, filePath, int64(len(content)), `MAT_001.mp3`)
+	if status != nil {
+		t.Fatal(status)
+	}
+	if len(ranges) != 1 || ranges[0] != `bytes=1000-` {
+		t.Error(`Expected one request for the rest of the file`, ranges)
+	}
+	result, err := os.ReadFile(filePath)
+	if err != nil || string(result) != content {
+		t.Error(`Downloaded file is not the content`, len(result), err)
+	}
+	_, err = os.Stat(filePath + `.part`)
+	if !os.IsNotExist(err) {
+		t.Error(`The .part file should have been renamed`)
+	}
+}
+
+func TestDownloadFileVerify(t *testing.T) {
+	ctx := context.Background()
+	retryDelay = time.Millisecond
+	t.Setenv(`FCBH_DBP_RETRIES`, `1`)
+	content := `In the beginning was the Word.`
+	var ranges []string
+	server := fileServer(content, `0123456789abcdef0123456789abcdef`, &ranges)
+	defer server.Close()
+	filePath := filepath.Join(t.TempDir(), `JHN_001.mp3`)
+	status := downloadFile(ctx, server.URL, filePath, int64(len(content)), `JHN_001.mp3`)
+	if status == nil || status.Status != 502 {
+		t.Error(`Expected a checksum error`, status)
+	}
+	if len(ranges) != 2 {
+		t.Error(`Expected a retry after a checksum error`, ranges)
+	}
+	_, err := os.Stat(filePath)
+	if !os.IsNotExist(err) {
+		t.Error(`A file that is not verified should not be saved`)
+	}
+	server2 := fileServer(content, `not-an-md5`, &ranges)
+	defer server2.Close()
+	status = downloadFile(ctx, server2.URL, filePath, int64(len(content))+1, `JHN_001.mp3`)
+	if status == nil {
+		t.Error(`Expected a size error`)
+	}
+	status = downloadFile(ctx, server2.URL, filePath, int64(len(content)), `JHN_001.mp3`)
+	if status != nil {
+		t.Error(`Expected the size to be verified`, status)
+	}
+}
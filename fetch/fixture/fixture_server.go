@@ -0,0 +1,290 @@
+package fixture
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	log "github.com/faithcomesbyhearing/fcbh-dataset-io/logger"
+)
+
+/*
+Server is a stand-in for the DBP API, which serves recorded responses from a fixtures directory,
+so that bible_brain requests can be run without network access.  It is used by setting
+FCBH_DBP_HOST, or fetch.HOST in a test, to its URL.
+
+A response is recorded as {directory}/api/{path}.json, e.g. api/bibles/ENGWEB.json and
+api/download/ENGWEBN2DA.json.  Query parameters other than v, key and limit are part of the
+name, e.g. api/download/list_page=2.json.  A response that is not 200 has its status in a
+.status file beside it.
+
+The download locations of a listing are recorded as {host}/files/{fileset}/{filename}, and
+the files are kept as {directory}/files/{fileset}/{filename}.  The recorded host is replaced
+by the server's own host when the listing is served.  Files support Range requests.
+
+When the server has an upstream, e.g. https://4.dbt.io/api/, it is in record mode.  A response
+that is not in the directory is fetched from upstream, saved and served.  A file is only fetched
+when it is requested, from the signed url of its listing, which is kept in files/urls.json.
+*/
+
+const recordedHost = `http://dbp-fixture`
+
+type Server struct {
+	ctx       context.Context
+	directory string
+	upstream  string
+	lock      sync.Mutex
+}
+
+// NewServer returns a server that replays directory, or that records into it when upstream is not empty
+func NewServer(ctx context.Context, directory string, upstream string) *Server {
+	var s Server
+	s.ctx = ctx
+	s.directory = directory
+	if upstream != `` && !strings.HasSuffix(upstream, `/`) {
+		upstream += `/`
+	}
+	s.upstream = upstream
+	return &s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.URL.Path, `/files/`) {
+		s.serveFile(w, r)
+	} else {
+		s.serveAPI(w, r)
+	}
+}
+
+func (s *Server) serveAPI(w http.ResponseWriter, r *http.Request) {
+	filename := filepath.Join(s.directory, `api`, fixtureName(r.URL))
+	body, err := os.ReadFile(filename)
+	if os.IsNotExist(err) && s.upstream != `` {
+		err = s.record(r.URL, filename)
+		if err == nil {
+			body, err = os.ReadFile(filename)
+		}
+	}
+	if os.IsNotExist(err) {
+		http.Error(w, `No fixture for `+r.URL.Path, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.error(w, err, `Error reading fixture`)
+		return
+	}
+	var statusCode = http.StatusOK
+	code, err := os.ReadFile(filename + `.status`)
+	if err == nil {
+		statusCode, _ = strconv.Atoi(strings.TrimSpace(string(code)))
+	}
+	body = bytes.ReplaceAll(body, []byte(recordedHost), []byte(`http://`+r.Host))
+	w.Header().Set(`Content-Type`, `application/json`)
+	w.WriteHeader(statusCode)
+	_, _ = w.Write(body)
+}
+
+// record saves the upstream response of a request
+func (s *Server) record(u *url.URL, filename string) error {
+	var get = s.upstream + strings.TrimPrefix(u.Path, `/`)
+	if u.RawQuery != `` {
+		get += `?` + u.RawQuery
+	}
+	log.Info(s.ctx, `Recording`, u.Path)
+	resp, err := http.Get(get)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusOK {
+		body, err = s.recordLocations(body)
+		if err != nil {
+			return err
+		}
+	}
+	err = os.MkdirAll(filepath.Dir(filename), 0755)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		err = os.WriteFile(filename+`.status`, []byte(strconv.Itoa(resp.StatusCode)), 0644)
+		if err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(filename, body, 0644)
+}
+
+// recordLocations replaces the path of each location in a listing with its fixture url,
+// and keeps its signed url, so that the file can be recorded when it is requested.
+func (s *Server) recordLocations(body []byte) ([]byte, error) {
+	var listing map[string]any
+	if json.Unmarshal(body, &listing) != nil {
+		return body, nil // not a listing
+	}
+	data, ok := listing[`data`].([]any)
+	if !ok {
+		return body, nil
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	urls, err := s.readURLs()
+	if err != nil {
+		return body, err
+	}
+	var changed bool
+	for _, item := range data {
+		location, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		signed, ok := location[`path`].(string)
+		if !ok || !strings.HasPrefix(signed, `http`) {
+			continue
+		}
+		parsed, err := url.Parse(signed)
+		if err != nil {
+			continue
+		}
+		name := path.Base(path.Dir(parsed.Path)) + `/` + path.Base(parsed.Path)
+		urls[name] = signed
+		location[`path`] = recordedHost + `/files/` + name
+		changed = true
+	}
+	if !changed {
+		return body, nil
+	}
+	err = s.writeURLs(urls)
+	if err != nil {
+		return body, err
+	}
+	return json.MarshalIndent(listing, ``, `  `)
+}
+
+func (s *Server) serveFile(w http.ResponseWriter, r *http.Request) {
+	name := path.Clean(strings.TrimPrefix(r.URL.Path, `/files/`))
+	if strings.HasPrefix(name, `..`) {
+		http.Error(w, `Invalid file `+r.URL.Path, http.StatusBadRequest)
+		return
+	}
+	filename := filepath.Join(s.directory, `files`, filepath.FromSlash(name))
+	_, err := os.Stat(filename)
+	if os.IsNotExist(err) && s.upstream != `` {
+		err = s.recordFile(name, filename)
+	}
+	if os.IsNotExist(err) {
+		http.Error(w, `No fixture for `+r.URL.Path, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.error(w, err, `Error recording file`)
+		return
+	}
+	http.ServeFile(w, r, filename)
+}
+
+func (s *Server) recordFile(name string, filename string) error {
+	s.lock.Lock()
+	urls, err := s.readURLs()
+	s.lock.Unlock()
+	if err != nil {
+		return err
+	}
+	signed, ok := urls[name]
+	if !ok {
+		return os.ErrNotExist
+	}
+	log.Info(s.ctx, `Recording`, name)
+	resp, err := http.Get(signed)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return os.ErrNotExist
+	}
+	err = os.MkdirAll(filepath.Dir(filename), 0755)
+	if err != nil {
+		return err
+	}
+	fp, err := os.Create(filename + `.part`)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(fp, resp.Body)
+	closeErr := fp.Close()
+	if err != nil {
+		return err
+	}
+	if closeErr != nil {
+		return closeErr
+	}
+	return os.Rename(filename+`.part`, filename)
+}
+
+func (s *Server) readURLs() (map[string]string, error) {
+	var urls = make(map[string]string)
+	content, err := os.ReadFile(filepath.Join(s.directory, `files`, `urls.json`))
+	if os.IsNotExist(err) {
+		return urls, nil
+	}
+	if err != nil {
+		return urls, err
+	}
+	err = json.Unmarshal(content, &urls)
+	return urls, err
+}
+
+func (s *Server) writeURLs(urls map[string]string) error {
+	content, err := json.MarshalIndent(urls, ``, `  `)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Join(s.directory, `files`), 0755)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(s.directory, `files`, `urls.json`), content, 0644)
+}
+
+func (s *Server) error(w http.ResponseWriter, err error, message string) {
+	status := log.Error(s.ctx, http.StatusInternalServerError, err, message)
+	http.Error(w, status.String(), http.StatusInternalServerError)
+}
+
+// fixtureName is the path of a request, and its query parameters other than v, key and limit
+func fixtureName(u *url.URL) string {
+	var name = strings.Trim(path.Clean(u.Path), `/`)
+	if name == `` || name == `.` {
+		name = `index`
+	}
+	var params []string
+	for key, values := range u.Query() {
+		if key == `v` || key == `key` || key == `limit` {
+			continue
+		}
+		for _, value := range values {
+			params = append(params, key+`=`+value)
+		}
+	}
+	sort.Strings(params)
+	if len(params) > 0 {
+		name += `_` + strings.Join(params, `_`)
+	}
+	name = strings.NewReplacer(`..`, `_`, `\`, `_`, `:`, `_`).Replace(name)
+	return filepath.FromSlash(name) + `.json`
+}
@@ -0,0 +1,125 @@
+package fixture
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/decode_yaml/request"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/fetch"
+)
+
+const testBible = `{"data":{"abbr":"ENGWEB","iso":"eng","name":"World English Bible",
+"filesets":{"dbp-prod":[{"id":"ENGWEBN2DA","type":"audio_drama","size":"NT","codec":"mp3","bitrate":"64kbps"}]}}}`
+
+// upstream is a stand-in for the DBP API, with a listing whose paths are signed urls
+func upstream(t *testing.T, requests *[]string) *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests = append(*requests, r.URL.Path)
+		switch r.URL.Path {
+		case `/bibles/ENGWEB`:
+			_, _ = w.Write([]byte(testBible))
+		case `/download/ENGWEBN2DA`:
+			_, _ = w.Write([]byte(`{"data":[{"book_id":"MAT","chapter_start":1,"filesize_in_bytes":11,
+"path":"` + server.URL + `/audio/ENGWEB/ENGWEBN2DA/B01___01_Matthew.mp3?Signature=abc"}]}`))
+		case `/audio/ENGWEB/ENGWEBN2DA/B01___01_Matthew.mp3`:
+			_, _ = w.Write([]byte(`mp3 content`))
+		case `/download/ENGWEBO2DA`:
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return server
+}
+
+func download(t *testing.T, host string) fetch.BibleInfoType {
+	ctx := context.Background()
+	t.Setenv(`FCBH_DATASET_FILES`, t.TempDir())
+	fetch.HOST = host + `/`
+	client := fetch.NewAPIDBPClient(ctx, `ENGWEB`)
+	info, status := client.BibleInfo()
+	if status != nil {
+		t.Fatal(status)
+	}
+	var audio request.BibleBrainAudio
+	audio.MP3_64 = true
+	var testament = request.Testament{NT: true}
+	client.FindFilesets(&info, audio, request.BibleBrainText{}, testament)
+	downloader := fetch.NewAPIDownloadClient(ctx, `ENGWEB`, testament)
+	status = downloader.Download(info)
+	if status != nil {
+		t.Fatal(status)
+	}
+	content, err := os.ReadFile(filepath.Join(os.Getenv(`FCBH_DATASET_FILES`), `ENGWEB`, `ENGWEBN2DA`, `B01___01_Matthew.mp3`))
+	if err != nil || string(content) != `mp3 content` {
+		t.Error(`Expected the recorded file`, string(content), err)
+	}
+	return info
+}
+
+func TestRecordReplay(t *testing.T) {
+	ctx := context.Background()
+	host := fetch.HOST
+	defer func() { fetch.HOST = host }()
+	directory := t.TempDir()
+	var requests []string
+	dbp := upstream(t, &requests)
+	recorder := httptest.NewServer(NewServer(ctx, directory, dbp.URL))
+	info := download(t, recorder.URL)
+	if info.AudioNTFileset.Id != `ENGWEBN2DA` {
+		t.Error(`Expected fileset ENGWEBN2DA`, info.AudioNTFileset)
+	}
+	recorder.Close()
+	dbp.Close()
+	if len(requests) != 3 {
+		t.Error(`Expected 3 requests to be recorded`, requests)
+	}
+	replay := httptest.NewServer(NewServer(ctx, directory, ``))
+	defer replay.Close()
+	info = download(t, replay.URL)
+	if info.AudioNTFileset.Id != `ENGWEBN2DA` {
+		t.Error(`Expected fileset ENGWEBN2DA on replay`, info.AudioNTFileset)
+	}
+	resp, err := http.Get(replay.URL + `/bibles/UNKNOWN?v=4`)
+	if err != nil || resp.StatusCode != http.StatusNotFound {
+		t.Error(`Expected 404 for a response that was not recorded`, err)
+	}
+}
+
+func TestRecordStatus(t *testing.T) {
+	ctx := context.Background()
+	directory := t.TempDir()
+	var requests []string
+	dbp := upstream(t, &requests)
+	recorder := httptest.NewServer(NewServer(ctx, directory, dbp.URL))
+	_, _ = http.Get(recorder.URL + `/download/ENGWEBO2DA?v=4`)
+	recorder.Close()
+	dbp.Close()
+	replay := httptest.NewServer(NewServer(ctx, directory, ``))
+	defer replay.Close()
+	resp, err := http.Get(replay.URL + `/download/ENGWEBO2DA?v=4&key=secret`)
+	if err != nil || resp.StatusCode != http.StatusForbidden {
+		t.Error(`Expected the recorded 403`, err)
+	}
+}
+
+func TestFixtureName(t *testing.T) {
+	var tests = map[string]string{
+		`/bibles/ENGWEB?v=4&limit=100000&key=abc`: `bibles/ENGWEB.json`,
+		`/download/list?page=2&v=4`:               `download/list_page=2.json`,
+		`/bibles/filesets/ENGWEBN2DA/MAT/1?v=4&`:  `bibles/filesets/ENGWEBN2DA/MAT/1.json`,
+		`/../../etc/passwd`:                       `etc/passwd.json`,
+	}
+	for get, expected := range tests {
+		u, _ := url.Parse(get)
+		if fixtureName(u) != filepath.FromSlash(expected) {
+			t.Error(get, `should be`, expected, `not`, fixtureName(u))
+		}
+	}
+}
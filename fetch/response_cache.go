@@ -0,0 +1,55 @@
+package fetch
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	log "github.com/faithcomesbyhearing/fcbh-dataset-io/logger"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// cachedGet is httpGet, with the response kept in FCBH_DATASET_FILES/dbp_cache for FCBH_DBP_CACHE_TTL.
+// Errors are not cached, and a cache that cannot be written is only a warning.
+func cachedGet(ctx context.Context, url string, ok403 bool, desc string) ([]byte, *log.Status) {
+	var ttl = envDuration(ctx, `FCBH_DBP_CACHE_TTL`, time.Hour)
+	if ttl <= 0 {
+		return httpGet(ctx, url, ok403, desc)
+	}
+	var cachePath = cacheFilePath(url)
+	info, err := os.Stat(cachePath)
+	if err == nil && time.Since(info.ModTime()) < ttl {
+		body, err := os.ReadFile(cachePath)
+		if err == nil {
+			return body, nil
+		}
+	}
+	body, status := httpGet(ctx, url, ok403, desc)
+	if status != nil {
+		return body, status
+	}
+	err = os.MkdirAll(filepath.Dir(cachePath), 0755)
+	if err == nil {
+		tempPath := cachePath + `.tmp`
+		err = os.WriteFile(tempPath, body, 0644)
+		if err == nil {
+			err = os.Rename(tempPath, cachePath)
+		}
+	}
+	if err != nil {
+		log.Warn(ctx, "Unable to cache DBP response for", desc, err)
+	}
+	return body, nil
+}
+
+// removeCached removes the cached response of url, e.g. when its signed urls have expired
+func removeCached(url string) {
+	_ = os.Remove(cacheFilePath(url))
+}
+
+// cacheFilePath is named by a hash of the url, which does not include the key
+func cacheFilePath(url string) string {
+	hash := sha256.Sum256([]byte(url))
+	return filepath.Join(os.Getenv(`FCBH_DATASET_FILES`), `dbp_cache`, hex.EncodeToString(hash[:])+`.json`)
+}
@@ -0,0 +1,35 @@
+package fetch
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCachedGet(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv(`FCBH_DATASET_FILES`, t.TempDir())
+	var count int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count++
+		_, _ = w.Write([]byte(`{"data":{"abbr":"ENGWEB"}}`))
+	}))
+	defer server.Close()
+	for i := 0; i < 2; i++ {
+		content, status := cachedGet(ctx, server.URL, false, `ENGWEB`)
+		if status != nil || string(content) != `{"data":{"abbr":"ENGWEB"}}` {
+			t.Error(`Unexpected response`, string(content), status)
+		}
+	}
+	if count != 1 {
+		t.Error(`Expected the second response from the cache`, count)
+	}
+	removeCached(server.URL)
+	_, _ = cachedGet(ctx, server.URL, false, `ENGWEB`)
+	t.Setenv(`FCBH_DBP_CACHE_TTL`, `0`)
+	_, _ = cachedGet(ctx, server.URL, false, `ENGWEB`)
+	if count != 3 {
+		t.Error(`Expected a request after removeCached, and with no cache`, count)
+	}
+}
@@ -2,11 +2,8 @@ package input
 
 import (
 	"context"
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
 	log "github.com/faithcomesbyhearing/fcbh-dataset-io/logger"
-	"io"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/storage"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -15,36 +12,16 @@ import (
 
 // DownloadFile is used by Controller to download a database file
 func DownloadFile(ctx context.Context, s3Path string, filePath string) *log.Status {
-	cfg, err := config.LoadDefaultConfig(ctx)
-	if err != nil {
-		return log.Error(ctx, 400, err, `Failed to load AWS configuration`)
-	}
-	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
-		o.Region = "us-west-2"
-	})
 	bucket, objectKey, _, status := parseGlob(ctx, s3Path)
 	if status != nil {
 		return status
 	}
-	log.Info(ctx, `Downloading file`, objectKey)
-	response, getErr := client.GetObject(ctx, &s3.GetObjectInput{
-		Bucket: aws.String(bucket),
-		Key:    aws.String(objectKey),
-	})
-	if getErr != nil {
-		return log.Error(ctx, 400, getErr, `Failed to get object`, objectKey)
-	}
-	defer response.Body.Close()
-	file, filErr := os.Create(filePath)
-	if filErr != nil {
-		return log.Error(ctx, 400, filErr, `Failed to create file`, filePath)
-	}
-	defer file.Close()
-	_, copErr := io.Copy(file, response.Body)
-	if copErr != nil {
-		return log.Error(ctx, 400, copErr, `Failed to copy object`, objectKey)
+	store, status := storage.NewStorage(ctx, bucket)
+	if status != nil {
+		return status
 	}
-	return nil
+	log.Info(ctx, `Downloading file`, objectKey)
+	return store.Download(ctx, objectKey, filePath)
 }
 
 // https://aws.github.io/aws-sdk-go-v2/docs/
@@ -52,62 +29,53 @@ func DownloadFile(ctx context.Context, s3Path string, filePath string) *log.Stat
 // AWSS3Input is given a path prefix, that it uses to identify files.
 // Saves each file found to disk, and returns an array of input files
 func AWSS3Input(ctx context.Context, path string) ([]InputFile, *log.Status) {
+	return awsS3Files(ctx, path, true)
+}
+
+// AWSS3List returns the files that AWSS3Input would return, without downloading them.
+func AWSS3List(ctx context.Context, path string) ([]InputFile, *log.Status) {
+	return awsS3Files(ctx, path, false)
+}
+
+func awsS3Files(ctx context.Context, path string, download bool) ([]InputFile, *log.Status) {
 	var files []InputFile
-	var status *log.Status
-	// Load the Shared AWS Configuration (~/.aws/config)
-	cfg, err := config.LoadDefaultConfig(ctx)
-	if err != nil {
-		status = log.Error(ctx, 400, err, `Failed to load AWS configuration`)
+	bucket, prefix, glob, status := parseGlob(ctx, path)
+	if status != nil {
 		return files, status
 	}
-	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
-		o.Region = "us-west-2"
-	})
-	bucket, prefix, glob, status := parseGlob(ctx, path)
+	store, status := storage.NewStorage(ctx, bucket)
 	if status != nil {
 		return files, status
 	}
-	list, err := client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
-		Bucket: aws.String(bucket),
-		Prefix: aws.String(prefix),
-	})
-	if err != nil {
-		status = log.Error(ctx, 400, err, `Failed to list AWSS3 objects`)
+	list, status := store.List(ctx, prefix)
+	if status != nil {
 		return files, status
 	}
 	bibleId, mediaId := findBibleIdMediaId(prefix)
 	directory := filepath.Join(os.Getenv(`FCBH_DATASET_FILES`), bibleId, mediaId)
-	status = EnsureDirectory(ctx, directory)
-	for _, object := range list.Contents {
-		objKey := aws.ToString(object.Key)
-		if glob == nil || glob.MatchString(objKey) {
+	if download {
+		status = EnsureDirectory(ctx, directory)
+		if status != nil {
+			return files, status
+		}
+	}
+	for _, object := range list {
+		if glob == nil || glob.MatchString(object.Key) {
 			var inFile InputFile
 			inFile.Directory = directory
-			inFile.Filename = filepath.Base(objKey)
+			inFile.Filename = filepath.Base(object.Key)
 			files = append(files, inFile)
+			if !download {
+				continue
+			}
 			filePath := inFile.FilePath()
 			fileInfo, stErr := os.Stat(filePath)
-			if os.IsNotExist(stErr) || fileInfo.Size() != *object.Size {
-				log.Info(ctx, `Downloading file`, objKey)
-				response, getErr := client.GetObject(ctx, &s3.GetObjectInput{
-					Bucket: aws.String(bucket),
-					Key:    aws.String(objKey),
-				})
-				if getErr != nil {
-					log.Warn(ctx, getErr, `Failed to get object`, object.Key)
-				}
-				file, filErr := os.Create(filePath)
-				if filErr != nil {
-					status = log.Error(ctx, 400, filErr, `Failed to create file`, filePath)
-					return files, status
-				}
-				_, copErr := io.Copy(file, response.Body)
-				if err != nil {
-					status = log.Error(ctx, 400, copErr, `Failed to copy object`, object.Key)
+			if os.IsNotExist(stErr) || fileInfo.Size() != object.Size {
+				log.Info(ctx, `Downloading file`, object.Key)
+				status = store.Download(ctx, object.Key, filePath)
+				if status != nil {
 					return files, status
 				}
-				err = response.Body.Close()
-				err = file.Close()
 			}
 		}
 	}
@@ -0,0 +1,23 @@
+package input
+
+import (
+	"context"
+)
+
+type fileDoneKey struct{}
+
+// WithFileDone returns a context in which FileDone calls done.  A stage that processes
+// files chapter by chapter sets it, so that it can checkpoint each chapter as soon as
+// its output is written, rather than when all of the files are done.
+func WithFileDone(ctx context.Context, done func(file InputFile)) context.Context {
+	return context.WithValue(ctx, fileDoneKey{}, done)
+}
+
+// FileDone is called by a process when it has written the output of a file.
+// It does nothing when the context has no WithFileDone.
+func FileDone(ctx context.Context, file InputFile) {
+	done, ok := ctx.Value(fileDoneKey{}).(func(file InputFile))
+	if ok && done != nil {
+		done(file)
+	}
+}
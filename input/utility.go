@@ -106,6 +106,8 @@ func setMediaType(ctx context.Context, file *InputFile) *log.Status {
 		file.MediaType = request.TextPlainEdit
 	} else if strings.HasSuffix(fN, `usx`) {
 		file.MediaType = request.TextUSXEdit
+	} else if strings.HasSuffix(strings.ToLower(fN), `.usfm`) || strings.HasSuffix(strings.ToLower(fN), `.sfm`) {
+		file.MediaType = request.TextUSFM
 	} else if (fN[0] == 'A' || fN[0] == 'B') && (fN[1] >= '0' && fN[1] <= '9') {
 		file.MediaType = request.Audio
 	} else if strings.HasSuffix(fN, `.xlsx`) || strings.HasSuffix(fN, `.xlsm`) {
@@ -165,6 +167,21 @@ func parseFilenames(ctx context.Context, file *InputFile) *log.Status {
 		file.BookSeq = tmpBookSeq
 		file.Testament = db.Testament(file.BookId)
 		file.FileExt = filepath.Ext(file.Filename)
+	} else if file.MediaType == request.TextUSFM {
+		parts := strings.Split(file.Directory, `/`)
+		file.MediaId = parts[len(parts)-1]
+		var tmpBookId string
+		tmpBookId, status = usfmBookId(ctx, file.FilePath())
+		if status != nil {
+			return status
+		}
+		file.BookId, status = validateBookId(ctx, tmpBookId)
+		if status != nil {
+			return status
+		}
+		file.BookSeq = strconv.Itoa(db.BookSeqMap[file.BookId])
+		file.Testament = db.Testament(file.BookId)
+		file.FileExt = filepath.Ext(file.Filename)
 	} else if file.MediaType == request.TextScript {
 		file.MediaId = strings.Split(file.Filename, `.`)[0]
 		test := file.Filename[6]
@@ -192,11 +209,29 @@ func parseFilenames(ctx context.Context, file *InputFile) *log.Status {
 			return status
 		}
 	} else {
-		status = log.ErrorNoErr(ctx, 400, `Type must be one of "text_plain", "text_plain_edit", "text_usx", "audio"`)
+		status = log.ErrorNoErr(ctx, 400, `Type must be one of "text_plain", "text_plain_edit", "text_usx", "text_usfm", "audio"`)
 	}
 	return status
 }
 
+// usfmBookId reads the book code from the \id line, because USFM filenames vary, e.g. 41MATENGWEB.SFM
+func usfmBookId(ctx context.Context, filePath string) (string, *log.Status) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return ``, log.Error(ctx, 500, err, `Error reading USFM file`, filePath)
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(strings.TrimPrefix(line, "\uFEFF"))
+		if strings.HasPrefix(line, `\id `) {
+			fields := strings.Fields(line)
+			if len(fields) > 1 {
+				return strings.ToUpper(fields[1]), nil
+			}
+		}
+	}
+	return ``, log.ErrorNoErr(ctx, 400, `USFM file has no \id line:`, filePath)
+}
+
 func parseV2AudioFilename(ctx context.Context, file *InputFile) *log.Status {
 	var status *log.Status
 	var err error
@@ -3,6 +3,8 @@ package input
 import (
 	"context"
 	"github.com/faithcomesbyhearing/fcbh-dataset-io/decode_yaml/request"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -50,3 +52,28 @@ func TestUtility_parseFilenames(t *testing.T) {
 		t.Error(status)
 	}
 }
+
+func TestUtility_parseUSFMFilename(t *testing.T) {
+	ctx := context.Background()
+	dir := filepath.Join(t.TempDir(), "ENGWEB")
+	_ = os.Mkdir(dir, 0755)
+	err := os.WriteFile(filepath.Join(dir, "41MATENGWEB.SFM"), []byte("\uFEFF\\id MAT World English Bible\n\\c 1\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := InputFile{Directory: dir, Filename: "41MATENGWEB.SFM"}
+	status := setMediaType(ctx, &file)
+	if status != nil {
+		t.Fatal(status)
+	}
+	if file.MediaType != request.TextUSFM {
+		t.Error("Media type should be text_usfm, not", file.MediaType)
+	}
+	status = parseFilenames(ctx, &file)
+	if status != nil {
+		t.Fatal(status)
+	}
+	if file.BookId != "MAT" || file.MediaId != "ENGWEB" || file.Testament != "NT" {
+		t.Error("Expected MAT, ENGWEB, NT, not", file.BookId, file.MediaId, file.Testament)
+	}
+}
@@ -0,0 +1,75 @@
+package logger
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// Fields identify the job, and the work within the job, that a log entry is about.
+// They are kept in the context, and are written with each entry of the json log format.
+type Fields struct {
+	JobId    string `json:"job_id,omitempty"`
+	Username string `json:"username,omitempty"`
+	Dataset  string `json:"dataset,omitempty"`
+	Stage    string `json:"stage,omitempty"`
+	BookId   string `json:"book_id,omitempty"`
+	Chapter  int    `json:"chapter,omitempty"`
+	Backend  string `json:"backend,omitempty"`
+}
+
+type fieldsKey struct{}
+
+func GetFields(ctx context.Context) Fields {
+	if ctx != nil {
+		fields, ok := ctx.Value(fieldsKey{}).(Fields)
+		if ok {
+			return fields
+		}
+	}
+	return Fields{}
+}
+
+func withFields(ctx context.Context, update func(f *Fields)) context.Context {
+	fields := GetFields(ctx)
+	update(&fields)
+	return context.WithValue(ctx, fieldsKey{}, fields)
+}
+
+// WithJob sets the job of the log entries of ctx.  A new job id is made when jobId is empty.
+func WithJob(ctx context.Context, jobId string, username string, dataset string) context.Context {
+	if jobId == `` {
+		var buf = make([]byte, 12)
+		_, _ = rand.Read(buf)
+		jobId = hex.EncodeToString(buf)
+	}
+	return withFields(ctx, func(f *Fields) {
+		f.JobId = jobId
+		f.Username = username
+		f.Dataset = dataset
+	})
+}
+
+// WithStage sets the pipeline stage, and clears the book and chapter of the prior stage
+func WithStage(ctx context.Context, stage string) context.Context {
+	return withFields(ctx, func(f *Fields) {
+		f.Stage = stage
+		f.BookId = ``
+		f.Chapter = 0
+	})
+}
+
+// WithChapter sets the book and chapter being processed, chapter is 0 when it is a whole book
+func WithChapter(ctx context.Context, bookId string, chapter int) context.Context {
+	return withFields(ctx, func(f *Fields) {
+		f.BookId = bookId
+		f.Chapter = chapter
+	})
+}
+
+// WithBackend sets the external program, e.g. a Python script, that produced the log entries
+func WithBackend(ctx context.Context, backend string) context.Context {
+	return withFields(ctx, func(f *Fields) {
+		f.Backend = backend
+	})
+}
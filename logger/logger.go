@@ -3,12 +3,14 @@ package logger
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"runtime"
 	"strconv"
 	"strings"
+	"time"
 )
 
 /**
@@ -18,6 +20,8 @@ Fatal will log a message and Goexit(), but osExit() if the caller is context.Bac
 Fatal should also be used in rare cases.
 Error will log a message an return, but it is expected that the transaction will fail.
 Warn, Info, and Debug log messages and continue.
+The format is text, or json when FCBH_DATASET_LOG_FORMAT=json.  In json, each entry is one line,
+which has the Fields of its context, e.g. job_id and stage, and the status code of an error.
 */
 
 type LogLevel int
@@ -37,6 +41,8 @@ var errorLog *log.Logger
 var warnLog *log.Logger
 var infoLog *log.Logger
 var debugLog *log.Logger
+var jsonLog *log.Logger
+var jsonFormat bool
 
 func init() {
 	setFile(os.Stderr)
@@ -46,6 +52,7 @@ func init() {
 	} else {
 		SetLevel(`INFO`)
 	}
+	SetFormat(os.Getenv("FCBH_DATASET_LOG_FORMAT"))
 	logFile := os.Getenv("FCBH_DATASET_LOG_FILE")
 	if logFile != `` {
 		SetOutput(logFile)
@@ -79,6 +86,12 @@ func setFile(file *os.File) {
 	warnLog = log.New(file, "WARN ", log.Ldate|log.Ltime)
 	infoLog = log.New(file, "INFO ", log.Ldate|log.Ltime)
 	debugLog = log.New(file, "DEBUG ", log.Ldate|log.Ltime|log.Lmicroseconds)
+	jsonLog = log.New(file, "", 0)
+}
+
+// SetFormat accepts: text or json
+func SetFormat(format string) {
+	jsonFormat = strings.ToLower(format) == "json"
 }
 
 // SetLevel set an error reporting level. The logger will process messages of this level and higher.
@@ -99,13 +112,22 @@ func SetDumpSkipLines(lines int) {
 
 // Panic will log the message with Println and then call panic()
 func Panic(ctx context.Context, param ...any) {
+	if jsonFormat {
+		message := joinParams(param)
+		writeJSON(ctx, "PANIC", message, nil)
+		panic(message)
+	}
 	panicLog.Panicln(param, requestInfo(ctx))
 }
 
 // Fatal will log the message with Println and call os.Exit is in background
 func Fatal(ctx context.Context, param ...any) {
-	fatalLog.Println(param, requestInfo(ctx))
-	fatalLog.Println(dumpLines())
+	if jsonFormat {
+		writeJSON(ctx, "FATAL", joinParams(param), &Status{Trace: dumpLines()})
+	} else {
+		fatalLog.Println(param, requestInfo(ctx))
+		fatalLog.Println(dumpLines())
+	}
 	runType := ctx.Value(`runType`)
 	if runType != `cli` {
 		runtime.Goexit()
@@ -133,12 +155,12 @@ func ExecError(ctx context.Context, http int, stderr string, param ...any) *Stat
 		jsonErr := json.Unmarshal([]byte(stderr), &s)
 		if jsonErr != nil {
 			Warn(ctx, jsonErr.Error())
-			Warn(ctx, param)
+			Warn(ctx, param...)
 			return nil
 		}
 		s.Status = http
 		s.Request = requestInfo(ctx)
-		errorLog.Printf("%+v", s)
+		logStatus(ctx, &s)
 		return &s
 	} else {
 		param = append([]any{stderr}, param...)
@@ -147,6 +169,18 @@ func ExecError(ctx context.Context, http int, stderr string, param ...any) *Stat
 	}
 }
 
+// ContextError returns a cancelled or timed out Status when the context is done, otherwise nil.
+func ContextError(ctx context.Context, param ...any) *Status {
+	err := ctx.Err()
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return errorImpl(ctx, StatusTimedOut, TimedOut, param...)
+	}
+	return errorImpl(ctx, StatusCancelled, Cancelled, param...)
+}
+
 func errorImpl(ctx context.Context, http int, err string, param ...any) *Status {
 	var result []byte
 	for _, p := range param {
@@ -159,14 +193,27 @@ func errorImpl(ctx context.Context, http int, err string, param ...any) *Status
 	e.Message = strings.TrimSpace(string(result))
 	e.Trace = dumpLines()
 	e.Request = requestInfo(ctx)
-	errorLog.Printf("%+v", e)
+	logStatus(ctx, &e)
 	return &e
 }
 
+// logStatus logs an error.  In json, the request is not logged, because the job_id identifies it.
+func logStatus(ctx context.Context, s *Status) {
+	if jsonFormat {
+		writeJSON(ctx, "ERROR", s.Message, s)
+	} else {
+		errorLog.Printf("%+v", *s)
+	}
+}
+
 // Warn will log the message with Println and then continue
 func Warn(ctx context.Context, param ...any) {
 	if logLevel >= LOGWARN {
-		warnLog.Println(param...)
+		if jsonFormat {
+			writeJSON(ctx, "WARN", joinParams(param), nil)
+		} else {
+			warnLog.Println(param...)
+		}
 		//warnLog.Println(dumpLines())
 	}
 }
@@ -174,7 +221,11 @@ func Warn(ctx context.Context, param ...any) {
 // Info will log the message with Println and then continue
 func Info(ctx context.Context, param ...any) {
 	if logLevel >= LOGINFO {
-		infoLog.Println(param...)
+		if jsonFormat {
+			writeJSON(ctx, "INFO", joinParams(param), nil)
+		} else {
+			infoLog.Println(param...)
+		}
 	}
 }
 
@@ -190,8 +241,44 @@ func Debug(ctx context.Context, param ...any) {
 		msg = append(msg, fmt.Sprintf("Frees = %v mb,", bToMb(m.Frees)))
 		msg = append(msg, fmt.Sprintf("NumGC = %v", m.NumGC))
 		param = append(param, strings.Join(msg, " "))
-		debugLog.Println(param)
+		if jsonFormat {
+			writeJSON(ctx, "DEBUG", joinParams(param), nil)
+		} else {
+			debugLog.Println(param)
+		}
+	}
+}
+
+type jsonEntry struct {
+	Time    string `json:"time"`
+	Level   string `json:"level"`
+	Message string `json:"message"`
+	Fields
+	Status int    `json:"status,omitempty"`
+	Error  string `json:"error,omitempty"`
+	Trace  string `json:"trace,omitempty"`
+}
+
+func writeJSON(ctx context.Context, level string, message string, status *Status) {
+	var entry jsonEntry
+	entry.Time = time.Now().Format("2006-01-02T15:04:05.000Z07:00")
+	entry.Level = level
+	entry.Message = message
+	entry.Fields = GetFields(ctx)
+	if status != nil {
+		entry.Status = status.Status
+		entry.Error = status.Err
+		entry.Trace = status.Trace
 	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		line = []byte(`{"level":"ERROR","message":` + strconv.Quote(err.Error()) + `}`)
+	}
+	jsonLog.Println(string(line))
+}
+
+func joinParams(param []any) string {
+	return strings.TrimSpace(fmt.Sprintln(param...))
 }
 
 func bToMb(b uint64) uint64 {
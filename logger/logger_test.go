@@ -2,8 +2,12 @@ package logger
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -36,3 +40,39 @@ AudioData:
 	derr := Error(ctx, 500, err, "Error Message", 123, "part3", 34.5)
 	fmt.Println(derr)
 }
+
+func TestJSONFormat(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), `log.json`)
+	SetOutput(filename)
+	SetFormat(`json`)
+	defer SetOutput(`stderr`)
+	defer SetFormat(`text`)
+	ctx := WithJob(context.Background(), `job1`, `GaryNTest`, `ENGWEB`)
+	ctx = WithStage(ctx, `timestamps`)
+	ctx = WithChapter(ctx, `MAT`, 2)
+	Info(ctx, "Processing", 3, "files")
+	_ = ErrorNoErr(WithBackend(ctx, `mms_align`), 400, "Bad input")
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 2 {
+		t.Fatal(`Expected 2 lines`, len(lines), string(content))
+	}
+	var info, failed jsonEntry
+	_ = json.Unmarshal([]byte(lines[0]), &info)
+	_ = json.Unmarshal([]byte(lines[1]), &failed)
+	if info.Level != `INFO` || info.Message != `Processing 3 files` || info.JobId != `job1` ||
+		info.Username != `GaryNTest` || info.Dataset != `ENGWEB` || info.Stage != `timestamps` ||
+		info.BookId != `MAT` || info.Chapter != 2 || info.Backend != `` {
+		t.Error(`Unexpected info entry`, lines[0])
+	}
+	if failed.Level != `ERROR` || failed.Status != 400 || failed.Backend != `mms_align` {
+		t.Error(`Unexpected error entry`, lines[1])
+	}
+	ctx = WithStage(ctx, `output`)
+	if GetFields(ctx).BookId != `` || GetFields(ctx).JobId != `job1` {
+		t.Error(`A new stage should keep the job, and clear the chapter`, GetFields(ctx))
+	}
+}
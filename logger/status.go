@@ -5,6 +5,14 @@ import (
 	"strings"
 )
 
+// Status codes and errors reported when a request's context is cancelled or its deadline passes
+const (
+	StatusCancelled = 499
+	StatusTimedOut  = 504
+	Cancelled       = `cancelled`
+	TimedOut        = `timed out`
+)
+
 type Status struct {
 	Message string `json:"message"`
 	Status  int    `json:"status"`
@@ -18,6 +26,11 @@ func (e *Status) Error() string {
 	return e.String()
 }
 
+// IsCancelled is true when the status was caused by cancellation or a timeout
+func (e *Status) IsCancelled() bool {
+	return e.Err == Cancelled || e.Err == TimedOut
+}
+
 // Status implements the Stringer interface
 // Using fmt package here caused stack overflow
 func (e *Status) String() string {
@@ -0,0 +1,266 @@
+package align
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/db"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/generic"
+	log "github.com/faithcomesbyhearing/fcbh-dataset-io/logger"
+	"github.com/xuri/excelize/v2"
+)
+
+/*
+The audio proof is also written as JSON, for dashboards, and as an XLSX workbook, for proofers.
+Both have one row per flagged verse, which is a verse that is in the HTML report, because its
+score is not 0, or that has a long silence.
+*/
+
+// ProofVerse is one flagged verse of the audio proof
+type ProofVerse struct {
+	Line           int     `json:"line"`
+	ItemId         string  `json:"item_id"`
+	Ref            string  `json:"ref"`
+	BookId         string  `json:"book_id"`
+	Chapter        int     `json:"chapter"`
+	Verse          string  `json:"verse"`
+	AudioFile      string  `json:"audio_file"`
+	BeginTS        float64 `json:"begin_ts"`
+	EndTS          float64 `json:"end_ts"`
+	Score          float64 `json:"score"`
+	Severity       string  `json:"severity"` // critical, question, asr or silence
+	WorstWord      string  `json:"worst_word"`
+	WorstWordScore float64 `json:"worst_word_score"` // the lowest FA score of a char in the word
+	CriticalChars  int     `json:"critical_chars"`
+	QuestionChars  int     `json:"question_chars"`
+	ASRChars       int     `json:"asr_chars"`
+	Silence        string  `json:"silence,omitempty"` // the longest kind of long silence in the verse
+	SilenceSecs    float64 `json:"silence_secs,omitempty"`
+	Text           string  `json:"text"`
+	ScriptText     string  `json:"script_text"`
+	Verdict        string  `json:"verdict,omitempty"` // the verdict of an earlier review
+}
+
+type ProofReport struct {
+	DatasetName   string       `json:"dataset_name"`
+	Created       string       `json:"created"`
+	CriticalLines int          `json:"critical_lines"`
+	QuestionLines int          `json:"question_lines"`
+	SilenceLines  int          `json:"silence_lines"`
+	Dismissed     int          `json:"dismissed"` // verses not included, because they were false positives
+	Verses        []ProofVerse `json:"verses"`
+}
+
+var silenceNames = map[int]string{
+	int(betweenCharsLong):    `between_chars`,
+	int(betweenWordsLong):    `between_words`,
+	int(betweenVersesLong):   `between_verses`,
+	int(betweenChaptersLong): `between_chapters`,
+}
+
+// ProofReport returns the flagged verses of lines
+func (a *AlignWriter) ProofReport(datasetName string, lines []generic.AlignLine) (ProofReport, *log.Status) {
+	var report ProofReport
+	report.DatasetName = datasetName
+	report.Created = time.Now().UTC().Format(time.RFC3339)
+	report.Verses = []ProofVerse{}
+	for _, line := range lines {
+		if len(line.Chars) == 0 {
+			continue
+		}
+		verse, flagged := a.proofVerse(line.Chars)
+		if !flagged {
+			continue
+		}
+		verse.Verdict = a.verdicts[verse.ItemId].Verdict
+		if verse.Verdict == db.VerdictFalsePositive {
+			report.Dismissed++
+			continue
+		}
+		verse.Line = len(report.Verses) + 1
+		var status *log.Status
+		verse.ScriptText, status = a.conn.SelectScriptLine(line.Chars[0].LineId)
+		if status != nil {
+			return report, status
+		}
+		switch verse.Severity {
+		case `critical`:
+			report.CriticalLines++
+		case `question`:
+			report.QuestionLines++
+		}
+		if verse.Silence != `` {
+			report.SilenceLines++
+		}
+		report.Verses = append(report.Verses, verse)
+	}
+	return report, nil
+}
+
+func (a *AlignWriter) proofVerse(chars []generic.AlignChar) (ProofVerse, bool) {
+	var verse ProofVerse
+	var worstWordId int64
+	verse.Score, worstWordId = a.scoreLine(chars)
+	verse.ItemId = a.itemId(chars)
+	first := chars[0]
+	ref := generic.NewVerseRef(first.LineRef)
+	verse.Ref = first.LineRef
+	verse.BookId = ref.BookId
+	verse.Chapter = ref.ChapterNum
+	verse.Verse = ref.VerseStr
+	verse.AudioFile = first.AudioFile
+	verse.BeginTS = first.BeginTS
+	verse.EndTS = chars[len(chars)-1].EndTS
+	var lowest = -1.0
+	var lowestWordId int64
+	var silence int
+	for _, ch := range chars {
+		if ch.ScoreError == int(scoreCritical) {
+			verse.CriticalChars++
+		} else if ch.ScoreError == int(scoreQuestion) {
+			verse.QuestionChars++
+		}
+		if ch.IsASR && !unicode.IsSpace(ch.Uroman) {
+			verse.ASRChars++
+		}
+		if ch.SilenceLong > silence {
+			silence = ch.SilenceLong
+			verse.SilenceSecs = ch.Silence
+		}
+		if ch.WordId > 0 && !unicode.IsSpace(ch.Uroman) && (lowest < 0 || ch.FAScore < lowest) {
+			lowest = ch.FAScore
+			lowestWordId = ch.WordId
+		}
+	}
+	verse.Text = proofText(chars)
+	verse.Silence = silenceNames[silence]
+	if worstWordId == 0 {
+		worstWordId = lowestWordId
+	}
+	verse.WorstWordScore = -1.0
+	for _, ch := range chars {
+		if ch.WordId == worstWordId && worstWordId > 0 {
+			verse.WorstWord = ch.Word
+			if verse.WorstWordScore < 0 || ch.FAScore < verse.WorstWordScore {
+				verse.WorstWordScore = ch.FAScore
+			}
+		}
+	}
+	if verse.WorstWordScore < 0 {
+		verse.WorstWordScore = 0
+	}
+	if verse.CriticalChars > 0 {
+		verse.Severity = `critical`
+	} else if verse.ASRChars > 0 {
+		verse.Severity = `asr`
+	} else if verse.QuestionChars > 0 {
+		verse.Severity = `question`
+	} else {
+		verse.Severity = `silence`
+	}
+	return verse, verse.Score != 0.0 || verse.Silence != ``
+}
+
+// WriteJSON writes the report as {dataset}_proof.json in FCBH_DATASET_TMP
+func (a *AlignWriter) WriteJSON(report ProofReport) (string, *log.Status) {
+	filename := filepath.Join(os.Getenv(`FCBH_DATASET_TMP`), report.DatasetName+"_proof.json")
+	content, err := json.MarshalIndent(report, ``, `  `)
+	if err != nil {
+		return filename, log.Error(a.ctx, 500, err, `Error encoding audio proof json`)
+	}
+	err = os.WriteFile(filename, content, 0644)
+	if err != nil {
+		return filename, log.Error(a.ctx, 500, err, `Error writing audio proof json`)
+	}
+	return filename, nil
+}
+
+var proofColumns = []struct {
+	title string
+	width float64
+}{
+	{`Line`, 7}, {`Ref`, 14}, {`Book`, 7}, {`Chapter`, 9}, {`Verse`, 8}, {`Severity`, 10},
+	{`Score`, 9}, {`Worst Word`, 18}, {`Word Score`, 11}, {`Critical`, 9}, {`Question`, 10},
+	{`ASR`, 7}, {`Silence`, 16}, {`Silence Secs`, 12}, {`Start`, 10}, {`End`, 10},
+	{`Audio File`, 28}, {`Text`, 60}, {`Script`, 60}, {`Verdict`, 14}, {`Item Id`, 24},
+}
+
+// WriteXLSX writes the report as {dataset}_proof.xlsx in FCBH_DATASET_TMP, with a header row
+// that is frozen and has filters, so that verses can be sorted and filtered in Excel.
+func (a *AlignWriter) WriteXLSX(report ProofReport) (string, *log.Status) {
+	const sheet = `Audio Proof`
+	filename := filepath.Join(os.Getenv(`FCBH_DATASET_TMP`), report.DatasetName+"_proof.xlsx")
+	file := excelize.NewFile()
+	defer file.Close()
+	err := file.SetSheetName(`Sheet1`, sheet)
+	if err != nil {
+		return filename, log.Error(a.ctx, 500, err, `Error naming audio proof sheet`)
+	}
+	headStyle, err := file.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true, Size: 12, Family: "Calibri"},
+		Fill: excelize.Fill{Type: `pattern`, Pattern: 1, Color: []string{`#D9D9D9`}},
+	})
+	if err != nil {
+		return filename, log.Error(a.ctx, 500, err, `Failed to create new style.`)
+	}
+	textStyle, err := file.NewStyle(&excelize.Style{
+		Alignment: &excelize.Alignment{WrapText: true, Vertical: `top`},
+		Font:      &excelize.Font{Size: 12, Family: "Calibri"},
+	})
+	if err != nil {
+		return filename, log.Error(a.ctx, 500, err, `Failed to create new style.`)
+	}
+	var header []any
+	for i, column := range proofColumns {
+		header = append(header, column.title)
+		name, _ := excelize.ColumnNumberToName(i + 1)
+		_ = file.SetColWidth(sheet, name, name, column.width)
+	}
+	err = file.SetSheetRow(sheet, `A1`, &header)
+	if err != nil {
+		return filename, log.Error(a.ctx, 500, err, `Unable to write cell.`)
+	}
+	for i, v := range report.Verses {
+		row := []any{v.Line, v.Ref, v.BookId, v.Chapter, v.Verse, v.Severity,
+			v.Score, v.WorstWord, v.WorstWordScore, v.CriticalChars, v.QuestionChars,
+			v.ASRChars, v.Silence, v.SilenceSecs, a.minSecFormat(v.BeginTS), a.minSecFormat(v.EndTS),
+			v.AudioFile, v.Text, v.ScriptText, v.Verdict, v.ItemId}
+		err = file.SetSheetRow(sheet, `A`+strconv.Itoa(i+2), &row)
+		if err != nil {
+			return filename, log.Error(a.ctx, 500, err, `Unable to write cell.`)
+		}
+	}
+	lastColumn, _ := excelize.ColumnNumberToName(len(proofColumns))
+	lastRow := strconv.Itoa(len(report.Verses) + 1)
+	_ = file.SetCellStyle(sheet, `A1`, lastColumn+`1`, headStyle)
+	if len(report.Verses) > 0 {
+		_ = file.SetCellStyle(sheet, `A2`, lastColumn+lastRow, textStyle)
+	}
+	err = file.AutoFilter(sheet, `A1:`+lastColumn+lastRow, nil)
+	if err != nil {
+		return filename, log.Error(a.ctx, 500, err, `Failed to set filter on audio proof`)
+	}
+	err = file.SetPanes(sheet, &excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: `A2`, ActivePane: `bottomLeft`})
+	if err != nil {
+		return filename, log.Error(a.ctx, 500, err, `Failed to freeze audio proof heading`)
+	}
+	err = file.SaveAs(filename)
+	if err != nil {
+		return filename, log.Error(a.ctx, 500, err, "Failed to save audio proof report")
+	}
+	return filename, nil
+}
+
+// proofText is the text of a line as it is in the HTML report, without markup
+func proofText(chars []generic.AlignChar) string {
+	var sb strings.Builder
+	for _, ch := range chars {
+		sb.WriteRune(ch.Uroman)
+	}
+	return sb.String()
+}
@@ -0,0 +1,122 @@
+package align
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/db"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/generic"
+	"github.com/xuri/excelize/v2"
+)
+
+func proofTestLine(ref string, lineId int64, scores []float64, silenceLong int) generic.AlignLine {
+	var line generic.AlignLine
+	for i, score := range scores {
+		var ch generic.AlignChar
+		ch.AudioFile = `B01___01_Matthew_____ENGWEBN2DA.mp3`
+		ch.LineId = lineId
+		ch.LineRef = ref
+		ch.WordId = lineId*10 + int64(i/3) + 1
+		ch.Word = []string{`abc`, `def`, `ghi`}[i/3]
+		ch.Uroman = rune(ch.Word[i%3])
+		ch.BeginTS = float64(lineId) + float64(i)*0.1
+		ch.EndTS = ch.BeginTS + 0.1
+		ch.FAScore = score
+		line.Chars = append(line.Chars, ch)
+	}
+	if silenceLong > 0 {
+		line.Chars[len(line.Chars)-1].SilenceLong = silenceLong
+		line.Chars[len(line.Chars)-1].Silence = 2.5
+	}
+	return line
+}
+
+func TestProofReport(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv(`FCBH_DATASET_DB`, t.TempDir())
+	t.Setenv(`FCBH_DATASET_TMP`, t.TempDir())
+	conn, status := db.NewerDBAdapter(ctx, true, `GaryNTest`, `TestProofReport`)
+	if status != nil {
+		t.Fatal(status)
+	}
+	defer conn.Close()
+	lines := []generic.AlignLine{
+		proofTestLine(`MAT 1:1`, 1, []float64{0.9, 0.9, 0.9, 0.00001, 0.00002, 0.9}, 0),
+		proofTestLine(`MAT 1:2`, 2, []float64{0.9, 0.9, 0.9, 0.9, 0.9, 0.9}, 0),
+		proofTestLine(`MAT 1:3`, 3, []float64{0.9, 0.5, 0.9, 0.9, 0.9, 0.9}, int(betweenVersesLong)),
+	}
+	writer := NewAlignWriter(ctx, conn)
+	report, status := writer.ProofReport(`TestProofReport`, lines)
+	if status != nil {
+		t.Fatal(status)
+	}
+	if len(report.Verses) != 2 || report.CriticalLines != 1 || report.SilenceLines != 1 {
+		t.Fatal(`Unexpected report`, report)
+	}
+	first := report.Verses[0]
+	if first.Ref != `MAT 1:1` || first.Chapter != 1 || first.Verse != `1` || first.Severity != `critical` ||
+		first.WorstWord != `def` || first.WorstWordScore != 0.00001 || first.CriticalChars != 2 || first.Score <= 0 {
+		t.Error(`Unexpected first verse`, first)
+	}
+	second := report.Verses[1]
+	if second.Ref != `MAT 1:3` || second.Severity != `silence` || second.Silence != `between_verses` ||
+		second.SilenceSecs != 2.5 || second.WorstWord != `abc` {
+		t.Error(`Unexpected second verse`, second)
+	}
+	filename, status := writer.WriteJSON(report)
+	if status != nil {
+		t.Fatal(status)
+	}
+	content, _ := os.ReadFile(filename)
+	var decoded ProofReport
+	err := json.Unmarshal(content, &decoded)
+	if err != nil || len(decoded.Verses) != 2 {
+		t.Error(`Unexpected json`, err, string(content))
+	}
+	filename, status = writer.WriteXLSX(report)
+	if status != nil {
+		t.Fatal(status)
+	}
+	file, err := excelize.OpenFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	rows, err := file.GetRows(`Audio Proof`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 || rows[0][0] != `Line` || rows[1][1] != `MAT 1:1` || rows[2][12] != `between_verses` {
+		t.Error(`Unexpected rows`, rows)
+	}
+}
+
+func TestProofReportVerdicts(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv(`FCBH_DATASET_DB`, t.TempDir())
+	conn, status := db.NewerDBAdapter(ctx, true, `GaryNTest`, `TestProofReportVerdicts`)
+	if status != nil {
+		t.Fatal(status)
+	}
+	defer conn.Close()
+	lines := []generic.AlignLine{
+		proofTestLine(`MAT 1:1`, 1, []float64{0.9, 0.9, 0.9, 0.00001, 0.00002, 0.9}, 0),
+		proofTestLine(`MAT 1:3`, 3, []float64{0.9, 0.5, 0.9, 0.9, 0.9, 0.9}, int(betweenVersesLong)),
+	}
+	writer := NewAlignWriter(ctx, conn)
+	report, _ := writer.ProofReport(`TestProofReportVerdicts`, lines)
+	first, second := report.Verses[0].ItemId, report.Verses[1].ItemId
+	if first == `` || first == second {
+		t.Fatal(`Unexpected item ids`, first, second)
+	}
+	writer.SetVerdicts(map[string]db.Verdict{
+		first:  {ItemId: first, Verdict: db.VerdictFalsePositive},
+		second: {ItemId: second, Verdict: db.VerdictRealError},
+	})
+	report, _ = writer.ProofReport(`TestProofReportVerdicts`, lines)
+	if report.Dismissed != 1 || len(report.Verses) != 1 || report.Verses[0].Verdict != db.VerdictRealError {
+		t.Error(`Unexpected report`, report)
+	}
+}
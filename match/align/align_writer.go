@@ -5,6 +5,9 @@ import (
 	"github.com/faithcomesbyhearing/fcbh-dataset-io/db"
 	"github.com/faithcomesbyhearing/fcbh-dataset-io/generic"
 	log "github.com/faithcomesbyhearing/fcbh-dataset-io/logger"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/match/report"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/match/review"
+	"html"
 	"math"
 	"os"
 	"path/filepath"
@@ -24,15 +27,34 @@ type AlignWriter struct {
 	questErrors int
 	critGaps    int
 	questGaps   int
+	verdicts    map[string]db.Verdict
+	dismissed   int
+	assets      report.Assets
+	audioDir    string
 }
 
 func NewAlignWriter(ctx context.Context, conn db.DBAdapter) AlignWriter {
 	var a AlignWriter
 	a.ctx = ctx
 	a.conn = conn
+	a.audioDir = `./`
 	return a
 }
 
+// SetAssets sets how the report includes its CSS and scripts, and its directory of audio files,
+// which is report.AudioDirectory for a bundle
+func (a *AlignWriter) SetAssets(assets report.Assets, audioDirectory string) {
+	a.assets = assets
+	if audioDirectory != `` {
+		a.audioDir = audioDirectory
+	}
+}
+
+// SetVerdicts sets the verdicts of earlier reviews, lines that were false positives are not written
+func (a *AlignWriter) SetVerdicts(verdicts map[string]db.Verdict) {
+	a.verdicts = verdicts
+}
+
 func (a *AlignWriter) WriteReport(datasetName string, lines []generic.AlignLine, filenameMap string) (string, *log.Status) {
 	var filename string
 	var status *log.Status
@@ -58,7 +80,7 @@ func (a *AlignWriter) WriteHeading() {
   <title>Audio Proofing Report</title>
 `
 	_, _ = a.out.WriteString(head)
-	_, _ = a.out.WriteString(`<link rel="stylesheet" type="text/css" href="https://cdn.datatables.net/1.10.21/css/jquery.dataTables.css">`)
+	_, _ = a.out.WriteString(a.assets.Stylesheet())
 	_, _ = a.out.WriteString("</head><body>\n")
 	_, _ = a.out.WriteString("<audio id='validateAudio'></audio>\n")
 	_, _ = a.out.WriteString(`<h2 style="text-align:center">Audio to Text Proofing Report For `)
@@ -74,7 +96,8 @@ func (a *AlignWriter) WriteHeading() {
 `
 	_, _ = a.out.WriteString(checkbox)
 	directoryInput := `<div style="text-align: center; margin: 10px;">
-		<label for="directory">Directory of Audio Files: </label><input type="text" id="directory" size="100" value="./">
+		<label for="directory">Directory of Audio Files: </label><input type="text" id="directory" size="100" value="` +
+		html.EscapeString(a.audioDir) + `">
 	</div>`
 
 	_, _ = a.out.WriteString(directoryInput)
@@ -88,6 +111,7 @@ func (a *AlignWriter) WriteHeading() {
         <th>Ref</th>
 		<th>Script</th>
 		<th>Source</th>
+		<th>Verdict</th>
     </tr>
     </thead>
     <tbody>
@@ -96,28 +120,17 @@ func (a *AlignWriter) WriteHeading() {
 }
 
 func (a *AlignWriter) WriteLine(chars []generic.AlignChar) {
-	var logTotal float64
-	var asrChars int
-	var logMap = make(map[int64][]float64)
-	var countMap = a.countCharsInWords(chars)
-	for i, char := range chars {
-		if chars[i].FAScore <= criticalThreshold {
-			chars[i].ScoreError = int(scoreCritical)
-			logScore := -math.Log10(chars[i].FAScore)
-			logMap[char.WordId] = append(logMap[char.WordId], logScore)
-		} else if chars[i].FAScore <= questionThreshold {
-			chars[i].ScoreError = int(scoreQuestion)
-		}
-		if char.IsASR && !unicode.IsSpace(char.Uroman) {
-			asrChars++
-		}
-	}
-	logTotal = a.findHighestScore(logMap, countMap)
-	logTotal += float64(asrChars) * 5.0
+	logTotal, _ := a.scoreLine(chars)
 	// skip lines with no errors
 	if logTotal == 0.0 {
 		return
 	}
+	itemId := a.itemId(chars)
+	verdict := a.verdicts[itemId]
+	if verdict.Verdict == db.VerdictFalsePositive {
+		a.dismissed++
+		return
+	}
 	var firstChar = chars[0]
 	var lastChar = chars[len(chars)-1]
 	a.lineNum++
@@ -159,9 +172,52 @@ func (a *AlignWriter) WriteLine(chars []generic.AlignChar) {
 	text = append(text, `</div>`)
 	a.writeCell(strings.Join(text, ""))
 	a.writeCell(`<button class="toggle-source-text">Show</button>`)
+	a.writeCell(review.VerdictCell(itemId, firstChar.LineRef, verdict.Verdict))
 	_, _ = a.out.WriteString("</tr>\n")
 }
 
+// scoreLine sets the ScoreError of each char, and returns the score of the line,
+// and the WordId of the word with the most critical chars, which is 0 when there is none.
+func (a *AlignWriter) scoreLine(chars []generic.AlignChar) (float64, int64) {
+	var asrChars int
+	var logMap = make(map[int64][]float64)
+	var countMap = a.countCharsInWords(chars)
+	for i, char := range chars {
+		if chars[i].FAScore <= criticalThreshold {
+			chars[i].ScoreError = int(scoreCritical)
+			logScore := -math.Log10(chars[i].FAScore)
+			logMap[char.WordId] = append(logMap[char.WordId], logScore)
+		} else if chars[i].FAScore <= questionThreshold {
+			chars[i].ScoreError = int(scoreQuestion)
+		}
+		if char.IsASR && !unicode.IsSpace(char.Uroman) {
+			asrChars++
+		}
+	}
+	logTotal, worstWordId := a.findHighestScore(logMap, countMap)
+	logTotal += float64(asrChars) * 5.0
+	return logTotal, worstWordId
+}
+
+// itemId identifies a line by its reference and its text, with the flag of each flagged char,
+// it must be called after scoreLine
+func (a *AlignWriter) itemId(chars []generic.AlignChar) string {
+	var sb strings.Builder
+	for _, ch := range chars {
+		sb.WriteRune(ch.Uroman)
+		if ch.ScoreError == int(scoreCritical) {
+			sb.WriteString(`!`)
+		} else if ch.ScoreError == int(scoreQuestion) {
+			sb.WriteString(`?`)
+		} else if ch.SilenceLong > 0 {
+			sb.WriteString(`_`)
+		} else if ch.IsASR && !unicode.IsSpace(ch.Uroman) {
+			sb.WriteString(`^`)
+		}
+	}
+	return review.ItemId(chars[0].LineRef, sb.String())
+}
+
 func (a *AlignWriter) countCharsInWords(chars []generic.AlignChar) map[int64]int {
 	var results = make(map[int64]int)
 	for _, char := range chars {
@@ -172,7 +228,7 @@ func (a *AlignWriter) countCharsInWords(chars []generic.AlignChar) map[int64]int
 	return results
 }
 
-func (a *AlignWriter) findHighestScore(logMap map[int64][]float64, chars map[int64]int) float64 {
+func (a *AlignWriter) findHighestScore(logMap map[int64][]float64, chars map[int64]int) (float64, int64) {
 	var maxLen = 0
 	var bestKey int64
 	for key, value := range logMap {
@@ -189,7 +245,7 @@ func (a *AlignWriter) findHighestScore(logMap map[int64][]float64, chars map[int
 	if len(values) >= chars[bestKey] {
 		logTotal *= 2.0
 	}
-	return logTotal
+	return logTotal, bestKey
 }
 
 func (a *AlignWriter) createHighlightList(indexes []int, length int) []bool {
@@ -223,10 +279,12 @@ func (a *AlignWriter) WriteEnd(filenameMap string) {
 	_, _ = a.out.WriteString(`<p>Lines with smaller end-of-verse gaps `)
 	_, _ = a.out.WriteString(strconv.Itoa(a.questGaps))
 	_, _ = a.out.WriteString("</p>\n")
-	_, _ = a.out.WriteString(`<script type="text/javascript" src="https://code.jquery.com/jquery-3.5.1.js"></script>`)
-	_, _ = a.out.WriteString("\n")
-	_, _ = a.out.WriteString(`<script type="text/javascript" src="https://cdn.datatables.net/1.10.21/js/jquery.dataTables.js"></script>`)
-	_, _ = a.out.WriteString("\n")
+	if a.dismissed > 0 {
+		_, _ = a.out.WriteString(`<p>Lines not shown, because they were reviewed as false positives `)
+		_, _ = a.out.WriteString(strconv.Itoa(a.dismissed))
+		_, _ = a.out.WriteString("</p>\n")
+	}
+	_, _ = a.out.WriteString(a.assets.Scripts())
 	style := `<style>
 	.dataTables_length select {
 	width: auto;
@@ -276,7 +334,7 @@ func (a *AlignWriter) WriteEnd(filenameMap string) {
     $(document).ready(function() {
         var table = $('#diffTable').DataTable({
             "columnDefs": [
-                { "orderable": false, "targets": [2,3,4,5] }
+                { "orderable": false, "targets": [2,3,4,5,7] }
 				// { "visible": false, "targets": [8] }  
             ],
             "pageLength": 50,
@@ -323,10 +381,10 @@ func (a *AlignWriter) WriteEnd(filenameMap string) {
 	  });
 	});
     </script>
-</body>
-</html>
 `
 	_, _ = a.out.WriteString("\t" + script)
+	_, _ = a.out.WriteString(review.VerdictScript(a.datasetName, review.ReportAudioProof))
+	_, _ = a.out.WriteString("</body>\n</html>\n")
 	_ = a.out.Close()
 }
 
@@ -30,6 +30,7 @@ type Compare struct {
 	verseRm     *regexp.Regexp
 	isLatin     sql.NullBool
 	diffMatch   *diffmatchpatch.DiffMatchPatch
+	wordLevel   bool
 	results     []Pair
 }
 
@@ -394,6 +395,13 @@ func (c *Compare) diff(baseVS []Verse, compVS []Verse) {
 
 func (c *Compare) diffPair(pair Pair) {
 	baseText, compText := pair.Text(c.isLatin)
+	if c.wordLevel && (len(baseText) > 0 || len(compText) > 0) {
+		c.diffWords(&pair)
+		if pair.HTML != `` {
+			c.results = append(c.results, pair)
+		}
+		return
+	}
 	if len(baseText) > 0 || len(compText) > 0 {
 		baseText = strings.TrimSpace(baseText)
 		compText = strings.TrimSpace(compText)
@@ -0,0 +1,177 @@
+package diff
+
+import (
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/db"
+	"github.com/sergi/go-diff/diffmatchpatch"
+	"html"
+	"strconv"
+	"strings"
+)
+
+/*
+A word level compare diffs the words of a verse, instead of its characters.  Each word that
+differs is given its audio span from the words table of its dataset, when mms_align has set word
+timestamps.  A word that has no timestamps, such as a word that is only in the ASR text, is given
+the span between the base words on either side of it.  In the report, a click on a word that
+differs plays its span.
+*/
+
+// PairWord is a word of a word level compare, Type is equal, delete (base only) or insert (comp only)
+type PairWord struct {
+	Type    diffmatchpatch.Operation `json:"type"`
+	Word    string                   `json:"word"`
+	BeginTS float64                  `json:"begin_ts"`
+	EndTS   float64                  `json:"end_ts"`
+}
+
+type timedWord struct {
+	word    string
+	beginTS float64
+	endTS   float64
+}
+
+// SetWordLevel sets a compare to diff words, instead of characters
+func (c *Compare) SetWordLevel(wordLevel bool) {
+	c.wordLevel = wordLevel
+}
+
+func (c *Compare) diffWords(pair *Pair) {
+	baseText, compText := pair.Text(c.isLatin)
+	baseWords := c.timedWords(c.baseDb, pair.Base.ScriptId, baseText)
+	compWords := c.timedWords(c.database, pair.Comp.ScriptId, compText)
+	var runeMap = make(map[string]rune)
+	baseRunes := wordRunes(baseWords, runeMap)
+	compRunes := wordRunes(compWords, runeMap)
+	diffs := c.diffMatch.DiffMainRunes(baseRunes, compRunes, false)
+	var b, m int
+	for _, diff := range diffs {
+		var words []string
+		for range []rune(diff.Text) {
+			var word PairWord
+			word.Type = diff.Type
+			switch diff.Type {
+			case diffmatchpatch.DiffEqual:
+				word.Word = baseWords[b].word
+				word.BeginTS, word.EndTS = baseWords[b].beginTS, baseWords[b].endTS
+				b++
+				m++
+			case diffmatchpatch.DiffDelete:
+				word.Word = baseWords[b].word
+				word.BeginTS, word.EndTS = baseWords[b].beginTS, baseWords[b].endTS
+				b++
+			case diffmatchpatch.DiffInsert:
+				word.Word = compWords[m].word
+				word.BeginTS, word.EndTS = compWords[m].beginTS, compWords[m].endTS
+				if word.EndTS == 0.0 {
+					word.BeginTS, word.EndTS = gapSpan(baseWords, b)
+				}
+				m++
+			}
+			if word.EndTS == 0.0 {
+				word.BeginTS, word.EndTS = pair.BeginTS, pair.EndTS
+			}
+			words = append(words, word.Word)
+			pair.Words = append(pair.Words, word)
+		}
+		pair.Diffs = append(pair.Diffs, diffmatchpatch.Diff{Type: diff.Type, Text: strings.Join(words, ` `)})
+	}
+	if !c.isMatch(pair.Diffs) {
+		pair.HTML = wordsHTML(*pair)
+	}
+}
+
+// timedWords are the words of text, with the timestamps of the words table when the dataset has them.
+// Each word of the table is cleaned up as the text was, and the timestamps are only used when the
+// table and the text have the same words.
+func (c *Compare) timedWords(conn db.DBAdapter, scriptId int, text string) []timedWord {
+	var results []timedWord
+	for _, word := range strings.Fields(text) {
+		results = append(results, timedWord{word: word})
+	}
+	if scriptId == 0 || conn.DB == nil {
+		return results
+	}
+	rows, status := conn.SelectWordsByScriptId(scriptId)
+	if status != nil || len(rows) == 0 {
+		return results
+	}
+	var timed []timedWord
+	for _, row := range rows {
+		rowText := row.Word
+		if !c.isLatin.Bool && row.Uroman != `` {
+			rowText = row.Uroman
+		}
+		rowText = c.verseRm.ReplaceAllString(c.cleanup(rowText), ``)
+		for _, word := range strings.Fields(rowText) {
+			timed = append(timed, timedWord{word: word, beginTS: row.WordBeginTS, endTS: row.WordEndTS})
+		}
+	}
+	if len(timed) != len(results) {
+		return results
+	}
+	for i := range timed {
+		if timed[i].word != results[i].word {
+			return results
+		}
+	}
+	return timed
+}
+
+// wordRunes gives each distinct word a rune, so that diffmatchpatch can diff words
+func wordRunes(words []timedWord, runeMap map[string]rune) []rune {
+	var runes = make([]rune, 0, len(words))
+	for _, word := range words {
+		r, ok := runeMap[word.word]
+		if !ok {
+			r = rune(0xF0000 + len(runeMap)) // a private use plane
+			runeMap[word.word] = r
+		}
+		runes = append(runes, r)
+	}
+	return runes
+}
+
+// gapSpan is the span between the base word before next and the base word at next
+func gapSpan(baseWords []timedWord, next int) (float64, float64) {
+	var beginTS, endTS float64
+	if next > 0 {
+		beginTS = baseWords[next-1].endTS
+	}
+	if next < len(baseWords) {
+		endTS = baseWords[next].beginTS
+	}
+	if endTS <= beginTS && next > 0 && next < len(baseWords) {
+		beginTS = baseWords[next-1].beginTS
+		endTS = baseWords[next].endTS
+	}
+	if endTS <= beginTS {
+		return 0.0, 0.0
+	}
+	return beginTS, endTS
+}
+
+// wordsHTML marks the words as DiffPrettyHtml marks chars, and each word that differs plays its span
+func wordsHTML(pair Pair) string {
+	var parts []string
+	for _, word := range pair.Words {
+		text := html.EscapeString(word.Word)
+		var tag, color string
+		switch word.Type {
+		case diffmatchpatch.DiffEqual:
+			parts = append(parts, text)
+			continue
+		case diffmatchpatch.DiffDelete:
+			tag, color = `del`, `#ffe6e6`
+		case diffmatchpatch.DiffInsert:
+			tag, color = `ins`, `#e6ffe6`
+		}
+		var params []string
+		params = append(params, "'"+pair.Ref.BookId+"'")
+		params = append(params, strconv.Itoa(pair.Ref.ChapterNum))
+		params = append(params, strconv.FormatFloat(word.BeginTS, 'f', 4, 64))
+		params = append(params, strconv.FormatFloat(word.EndTS, 'f', 4, 64))
+		parts = append(parts, `<`+tag+` style="background:`+color+`;" class="word" onclick="playWord(`+
+			strings.Join(params, ",")+`)">`+text+`</`+tag+`>`)
+	}
+	return strings.Join(parts, ` `)
+}
@@ -0,0 +1,84 @@
+package diff
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/db"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/decode_yaml/request"
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+func TestCompareWords(t *testing.T) {
+	ctx := context.Background()
+	baseDb := db.NewDBAdapter(ctx, ":memory:")
+	defer baseDb.Close()
+	var script db.Script
+	script.BookId = `JHN`
+	script.ChapterNum = 1
+	script.VerseStr = `1`
+	script.ScriptTexts = []string{`In the beginning was the Word`}
+	status := baseDb.InsertScripts([]db.Script{script})
+	if status != nil {
+		t.Fatal(status)
+	}
+	var words []db.Word
+	var timestamps []db.Timestamp
+	for i, word := range strings.Fields(script.ScriptTexts[0]) {
+		words = append(words, db.Word{ScriptId: 1, WordSeq: i + 1, VerseNum: 1, TType: `W`, Word: word})
+		timestamps = append(timestamps, db.Timestamp{Id: i + 1, BeginTS: float64(i), EndTS: float64(i) + 0.5})
+	}
+	status = baseDb.InsertWords(words)
+	if status != nil {
+		t.Fatal(status)
+	}
+	status = baseDb.UpdateWordTimestamps(timestamps)
+	if status != nil {
+		t.Fatal(status)
+	}
+	compDb := db.NewDBAdapter(ctx, ":memory:")
+	defer compDb.Close()
+	c := NewCompare(ctx, `GaryNTest`, `base`, compDb, `eng`, request.Testament{NT: true}, request.CompareSettings{LowerCase: true})
+	c.baseDb = baseDb
+	c.isLatin.Valid = true
+	c.isLatin.Bool = true
+	c.SetWordLevel(true)
+	var pair Pair
+	pair.Ref.BookId = `JHN`
+	pair.Ref.ChapterNum = 1
+	pair.Ref.VerseStr = `1`
+	pair.BeginTS, pair.EndTS = 0.0, 6.0
+	pair.Base = PairText{ScriptId: 1, Text: c.cleanup(`In the beginning was the Word`)}
+	pair.Comp = PairText{ScriptId: 1, Text: c.cleanup(`in the beginning there was Word`)}
+	c.diffPair(pair)
+	if len(c.results) != 1 {
+		t.Fatal(`Expected one pair, found`, len(c.results))
+	}
+	result := c.results[0]
+	var changed []PairWord
+	for _, word := range result.Words {
+		if word.Type != diffmatchpatch.DiffEqual {
+			changed = append(changed, word)
+		}
+	}
+	if len(changed) != 2 {
+		t.Fatal(`Expected two changed words`, result.Words)
+	}
+	inserted, deleted := changed[0], changed[1]
+	if inserted.Word != `there` || inserted.Type != diffmatchpatch.DiffInsert || inserted.BeginTS != 2.5 || inserted.EndTS != 3.0 {
+		t.Error(`Unexpected inserted word`, inserted)
+	}
+	if deleted.Word != `the` || deleted.Type != diffmatchpatch.DiffDelete || deleted.BeginTS != 4.0 || deleted.EndTS != 4.5 {
+		t.Error(`Unexpected deleted word`, deleted)
+	}
+	if !strings.Contains(result.HTML, `onclick="playWord('JHN',1,4.0000,4.5000)">the</del>`) {
+		t.Error(`Unexpected HTML`, result.HTML)
+	}
+	c.results = nil
+	pair.Comp.Text = pair.Base.Text
+	c.diffPair(pair)
+	if len(c.results) != 0 {
+		t.Error(`Expected matching words to have no result`, c.results)
+	}
+}
@@ -2,9 +2,12 @@ package diff
 
 import (
 	"context"
-	"github.com/faithcomesbyhearing/fcbh-dataset-io/decode_yaml/request"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/db"
 	log "github.com/faithcomesbyhearing/fcbh-dataset-io/logger"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/match/report"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/match/review"
 	"github.com/sergi/go-diff/diffmatchpatch"
+	"html"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -20,6 +23,10 @@ type HTMLWriter struct {
 	diffCount   int
 	insertSum   int
 	deleteSum   int
+	verdicts    map[string]db.Verdict
+	dismissed   int
+	assets      report.Assets
+	audioDir    string
 }
 
 func NewHTMLWriter(ctx context.Context, datasetName string) HTMLWriter {
@@ -27,22 +34,31 @@ func NewHTMLWriter(ctx context.Context, datasetName string) HTMLWriter {
 	h.ctx = ctx
 	h.datasetName = datasetName
 	h.diffMatch = diffmatchpatch.New()
+	h.audioDir = `./`
 	return h
 }
 
+// SetAssets sets how the report includes its CSS and scripts, and its directory of audio files,
+// which is report.AudioDirectory for a bundle
+func (h *HTMLWriter) SetAssets(assets report.Assets, audioDirectory string) {
+	h.assets = assets
+	if audioDirectory != `` {
+		h.audioDir = audioDirectory
+	}
+}
+
+// SetVerdicts sets the verdicts of earlier reviews, differences that were false positives are not written
+func (h *HTMLWriter) SetVerdicts(verdicts map[string]db.Verdict) {
+	h.verdicts = verdicts
+}
+
+// WriteReport writes the compare report, modelTitle is the title of the speech to text engine, or empty
 func (h *HTMLWriter) WriteReport(baseDataset string, records []Pair, languageISO string, fileMap string,
-	asr request.SpeechToText) (string, *log.Status) {
+	modelTitle string) (string, *log.Status) {
 	var err error
 	var model string
-	switch asr {
-	case request.SpeechToText{MMS: true}:
-		model = "Model: MMS"
-	case request.SpeechToText{MMSAdapter: true}:
-		model = "Model: MMS Adapter"
-	case request.SpeechToText{Wav2Vec2ASR: true}:
-		model = "Model: Wav2Vec2 Word"
-	default:
-		model = ""
+	if modelTitle != `` {
+		model = "Model: " + modelTitle
 	}
 	h.out, err = os.Create(filepath.Join(os.Getenv(`FCBH_DATASET_TMP`), h.datasetName+"_compare.html"))
 	if err != nil {
@@ -64,7 +80,7 @@ func (h *HTMLWriter) WriteHeading(baseDataset string, languageISO string, model
   <title>File Difference</title>
 `
 	_, _ = h.out.WriteString(head)
-	_, _ = h.out.WriteString(`<link rel="stylesheet" type="text/css" href="https://cdn.datatables.net/1.10.21/css/jquery.dataTables.css">`)
+	_, _ = h.out.WriteString(h.assets.Stylesheet())
 	_, _ = h.out.WriteString("</head><body>\n")
 	_, _ = h.out.WriteString(`<h2 style="text-align:center">Compare `)
 	_, _ = h.out.WriteString(baseDataset)
@@ -91,7 +107,8 @@ func (h *HTMLWriter) WriteHeading(baseDataset string, languageISO string, model
 `
 	_, _ = h.out.WriteString(checkbox)
 	directoryInput := `<div style="text-align: center; margin: 10px;">
-		<label for="directory">Directory of Audio Files: </label><input type="text" id="directory" size="100" value="./">
+		<label for="directory">Directory of Audio Files: </label><input type="text" id="directory" size="100" value="` +
+		html.EscapeString(h.audioDir) + `">
 	</div>`
 
 	_, _ = h.out.WriteString(directoryInput)
@@ -104,6 +121,7 @@ func (h *HTMLWriter) WriteHeading(baseDataset string, languageISO string, model
 		<th>Button</th>
         <th>Ref</th>
 		<th>Text Comparison</th>
+		<th>Verdict</th>
     </tr>
     </thead>
     <tbody>
@@ -115,6 +133,12 @@ func (h *HTMLWriter) WriteHeading(baseDataset string, languageISO string, model
 func (h *HTMLWriter) WriteLine(verse Pair) {
 	largest := verse.LargestLength()
 	if largest > 2 {
+		itemId := verse.ItemId()
+		verdict := h.verdicts[itemId]
+		if verdict.Verdict == db.VerdictFalsePositive {
+			h.dismissed++
+			return
+		}
 		h.diffCount++
 		inserts := verse.Inserts()
 		h.insertSum += inserts
@@ -142,6 +166,7 @@ func (h *HTMLWriter) WriteLine(verse Pair) {
 		//h.writeCell(`+` + strconv.Itoa(inserts) + ` -` + strconv.Itoa(deletes))
 		h.writeCell(verse.Ref.Description())
 		h.writeCell(verse.HTML)
+		h.writeCell(review.VerdictCell(itemId, verse.Ref.Description(), verdict.Verdict))
 		_, _ = h.out.WriteString("</tr>\n")
 	}
 }
@@ -166,10 +191,12 @@ func (h *HTMLWriter) WriteEnd(filenameMap string) {
 	_, _ = h.out.WriteString("Total Difference Count: ")
 	_, _ = h.out.WriteString(strconv.Itoa(h.diffCount))
 	_, _ = h.out.WriteString("</p>\n")
-	_, _ = h.out.WriteString(`<script type="text/javascript" src="https://code.jquery.com/jquery-3.5.1.js"></script>`)
-	_, _ = h.out.WriteString("\n")
-	_, _ = h.out.WriteString(`<script type="text/javascript" src="https://cdn.datatables.net/1.10.21/js/jquery.dataTables.js"></script>`)
-	_, _ = h.out.WriteString("\n")
+	if h.dismissed > 0 {
+		_, _ = h.out.WriteString(`<p>Differences not shown, because they were reviewed as false positives: `)
+		_, _ = h.out.WriteString(strconv.Itoa(h.dismissed))
+		_, _ = h.out.WriteString("</p>\n")
+	}
+	_, _ = h.out.WriteString(h.assets.Scripts())
 	style := `<style>
 	.dataTables_length select {
 		width: auto;
@@ -189,6 +216,9 @@ func (h *HTMLWriter) WriteEnd(filenameMap string) {
 	.dataTables_wrapper .dataTables_length, .dataTables_wrapper .dataTables_filter {
 		margin-bottom: 20px;
 	}
+	.word {
+		cursor: pointer;
+	}
 	</style>
 `
 	_, _ = h.out.WriteString(style)
@@ -196,7 +226,7 @@ func (h *HTMLWriter) WriteEnd(filenameMap string) {
     $(document).ready(function() {
         var table = $('#diffTable').DataTable({
             "columnDefs": [
-                { "orderable": false, "targets": [2,4] }
+                { "orderable": false, "targets": [2,4,5] }
 				// { "visible": false, "targets": [8] }  
             ],
             "pageLength": 50,
@@ -238,11 +268,16 @@ func (h *HTMLWriter) WriteEnd(filenameMap string) {
 			}
 		}
 	}
+	// playWord plays a word of a word level compare, with padding on each side
+	function playWord(book, chapter, startTime, endTime) {
+		const padding = 0.25;
+		playVerse(null, book, chapter, Math.max(0.0, startTime - padding), endTime + padding);
+	}
     </script>
-</body>
-</html>
 `
 	_, _ = h.out.WriteString(script)
+	_, _ = h.out.WriteString(review.VerdictScript(h.datasetName, review.ReportCompare))
+	_, _ = h.out.WriteString("</body>\n</html>\n")
 	_ = h.out.Close()
 }
 
@@ -16,7 +16,7 @@ func TestHTMLWriter(t *testing.T) {
 	}
 	ctx := context.Background()
 	writer := NewHTMLWriter(ctx, test.project)
-	filename, status := writer.WriteReport(test.baseDB, records, languageISO, fileMap)
+	filename, status := writer.WriteReport(test.baseDB, records, languageISO, fileMap, ``)
 	if status != nil {
 		t.Fatal(status)
 	}
@@ -3,6 +3,7 @@ package diff
 import (
 	"database/sql"
 	"github.com/faithcomesbyhearing/fcbh-dataset-io/generic"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/match/review"
 	"github.com/sergi/go-diff/diffmatchpatch"
 	"unicode/utf8"
 )
@@ -16,7 +17,8 @@ type Pair struct {
 	Base      PairText              `json:"base"`
 	Comp      PairText              `json:"comp"`
 	Diffs     []diffmatchpatch.Diff `json:"diffs"`
-	HTML      string                `json:"html""`
+	Words     []PairWord            `json:"words,omitempty"` // only for a word level compare
+	HTML      string                `json:"html"`
 }
 
 type PairText struct {
@@ -70,6 +72,20 @@ func (p *Pair) ScriptId() int {
 	}
 }
 
+// ItemId identifies the difference of this pair, so that a reviewer's verdict on it
+// applies to later runs that find the same difference in the same verse.
+func (p *Pair) ItemId() string {
+	var content []string
+	for _, diff := range p.Diffs {
+		if diff.Type == diffmatchpatch.DiffInsert {
+			content = append(content, `+`+diff.Text)
+		} else if diff.Type == diffmatchpatch.DiffDelete {
+			content = append(content, `-`+diff.Text)
+		}
+	}
+	return review.ItemId(p.Ref.Description(), content...)
+}
+
 func (p *Pair) Text(isLatin sql.NullBool) (string, string) {
 	if isLatin.Bool {
 		return p.Base.Text, p.Comp.Text
@@ -0,0 +1,144 @@
+package report
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	log "github.com/faithcomesbyhearing/fcbh-dataset-io/logger"
+)
+
+/*
+The HTML reports use jQuery and DataTables.  Normally they link them from their CDNs, so the
+reports only work with internet.  An offline report has them inline instead, including the
+sort icons of the DataTables CSS.  The files are downloaded from the CDNs once, and kept in
+FCBH_REPORT_ASSETS (default $FCBH_DATASET_FILES/report_assets).  On a server without internet,
+the files can be put into that directory, with the names of their URLs.
+*/
+
+const (
+	jqueryURL        = `https://code.jquery.com/jquery-3.5.1.js`
+	dataTablesURL    = `https://cdn.datatables.net/1.10.21/js/jquery.dataTables.js`
+	dataTablesCSSURL = `https://cdn.datatables.net/1.10.21/css/jquery.dataTables.css`
+)
+
+var cssImage = regexp.MustCompile(`url\("?\.\./images/([A-Za-z0-9_.-]+\.png)"?\)`)
+
+// Assets are the CSS and scripts of a report.  The zero value links them from their CDNs.
+type Assets struct {
+	offline    bool
+	css        string
+	jquery     string
+	dataTables string
+}
+
+// NewAssets returns the assets of a report, which are inline when offline is true
+func NewAssets(ctx context.Context, offline bool) (Assets, *log.Status) {
+	var a Assets
+	if !offline {
+		return a, nil
+	}
+	a.offline = true
+	var status *log.Status
+	a.jquery, status = loadAsset(ctx, jqueryURL)
+	if status != nil {
+		return a, status
+	}
+	a.dataTables, status = loadAsset(ctx, dataTablesURL)
+	if status != nil {
+		return a, status
+	}
+	a.css, status = loadAsset(ctx, dataTablesCSSURL)
+	if status != nil {
+		return a, status
+	}
+	a.css, status = inlineImages(ctx, a.css)
+	return a, status
+}
+
+// Stylesheet is written in the head of a report
+func (a Assets) Stylesheet() string {
+	if !a.offline {
+		return `<link rel="stylesheet" type="text/css" href="` + dataTablesCSSURL + `">`
+	}
+	return "<style>\n" + a.css + "\n</style>"
+}
+
+// Scripts are written before the scripts of a report, which use jQuery and DataTables
+func (a Assets) Scripts() string {
+	if !a.offline {
+		return `<script type="text/javascript" src="` + jqueryURL + `"></script>` + "\n" +
+			`<script type="text/javascript" src="` + dataTablesURL + `"></script>` + "\n"
+	}
+	return inlineScript(a.jquery) + inlineScript(a.dataTables)
+}
+
+func inlineScript(content string) string {
+	// a script element ends at the first </script, even one inside a string
+	content = strings.ReplaceAll(content, `</script`, `<\/script`)
+	return "<script type=\"text/javascript\">\n" + content + "\n</script>\n"
+}
+
+// inlineImages replaces the images of the DataTables CSS with data URLs
+func inlineImages(ctx context.Context, css string) (string, *log.Status) {
+	var status *log.Status
+	base := dataTablesCSSURL[:strings.LastIndex(dataTablesCSSURL, `/css/`)] + `/images/`
+	result := cssImage.ReplaceAllStringFunc(css, func(match string) string {
+		if status != nil {
+			return match
+		}
+		var image string
+		image, status = loadAsset(ctx, base+cssImage.FindStringSubmatch(match)[1])
+		return `url("data:image/png;base64,` + base64.StdEncoding.EncodeToString([]byte(image)) + `")`
+	})
+	return result, status
+}
+
+func assetDirectory() string {
+	directory := os.Getenv(`FCBH_REPORT_ASSETS`)
+	if directory == `` {
+		directory = filepath.Join(os.Getenv(`FCBH_DATASET_FILES`), `report_assets`)
+	}
+	return directory
+}
+
+// loadAsset reads an asset from the asset directory, and downloads it when it is not there
+func loadAsset(ctx context.Context, url string) (string, *log.Status) {
+	filePath := filepath.Join(assetDirectory(), filepath.Base(url))
+	content, err := os.ReadFile(filePath)
+	if err == nil {
+		return string(content), nil
+	}
+	log.Info(ctx, `Downloading report asset`, url)
+	req, err := http.NewRequestWithContext(ctx, `GET`, url, nil)
+	if err != nil {
+		return ``, log.Error(ctx, 500, err, `Error creating request for report asset`, url)
+	}
+	var client = http.Client{Timeout: 60 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return ``, log.Error(ctx, 502, err, `Unable to download report asset`, url, `it can be put into`, assetDirectory())
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return ``, log.ErrorNoErr(ctx, 502, `Unable to download report asset`, url, resp.Status)
+	}
+	content, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return ``, log.Error(ctx, 502, err, `Error reading report asset`, url)
+	}
+	err = os.MkdirAll(assetDirectory(), 0755)
+	if err == nil {
+		err = os.WriteFile(filePath, content, 0644)
+	}
+	if err != nil {
+		log.Warn(ctx, `Unable to keep report asset in`, filePath, err.Error())
+	}
+	return string(content), nil
+}
@@ -0,0 +1,69 @@
+package report
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	log "github.com/faithcomesbyhearing/fcbh-dataset-io/logger"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/utility/zip"
+)
+
+// AudioDirectory is the directory of the audio in a bundle, relative to its index.html
+const AudioDirectory = `audio`
+
+// Bundle zips an offline report with the audio files it plays, so that it can be used without
+// internet.  The zip has one directory, which has index.html and the audio in audio/.
+// The report must have been written with AudioDirectory as its directory of audio files.
+func Bundle(ctx context.Context, reportPath string, audioPaths []string) (string, *log.Status) {
+	directory := strings.TrimSuffix(reportPath, filepath.Ext(reportPath)) + `_bundle`
+	_ = os.RemoveAll(directory)
+	defer os.RemoveAll(directory)
+	err := os.MkdirAll(filepath.Join(directory, AudioDirectory), 0755)
+	if err != nil {
+		return ``, log.Error(ctx, 500, err, `Error creating report bundle`, directory)
+	}
+	err = copyFile(reportPath, filepath.Join(directory, `index.html`))
+	if err != nil {
+		return ``, log.Error(ctx, 500, err, `Error copying report to bundle`, reportPath)
+	}
+	if len(audioPaths) == 0 {
+		log.Warn(ctx, `There are no audio files to bundle with`, reportPath)
+	}
+	for _, audioPath := range audioPaths {
+		err = copyFile(audioPath, filepath.Join(directory, AudioDirectory, filepath.Base(audioPath)))
+		if err != nil {
+			return ``, log.Error(ctx, 500, err, `Error copying audio to report bundle`, audioPath)
+		}
+	}
+	target, size, err := zip.ZipDirectory(directory)
+	if err != nil {
+		return target, log.Error(ctx, 500, err, `Error zipping report bundle`, directory)
+	}
+	log.Info(ctx, `Report bundle`, target, size, `bytes with`, len(audioPaths), `audio files`)
+	return target, nil
+}
+
+// copyFile links a file, or copies it when it cannot be linked
+func copyFile(source string, target string) error {
+	if os.Link(source, target) == nil {
+		return nil
+	}
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,72 @@
+package report
+
+import (
+	"archive/zip"
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestAssets(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	t.Setenv(`FCBH_REPORT_ASSETS`, dir)
+	_ = os.WriteFile(filepath.Join(dir, `jquery-3.5.1.js`), []byte(`var s = "</script>";`), 0644)
+	_ = os.WriteFile(filepath.Join(dir, `jquery.dataTables.js`), []byte(`var dt = 1;`), 0644)
+	_ = os.WriteFile(filepath.Join(dir, `jquery.dataTables.css`), []byte(`th { background-image: url("../images/sort_asc.png"); }`), 0644)
+	_ = os.WriteFile(filepath.Join(dir, `sort_asc.png`), []byte(`png`), 0644)
+	assets, status := NewAssets(ctx, true)
+	if status != nil {
+		t.Fatal(status)
+	}
+	css := assets.Stylesheet()
+	if !strings.Contains(css, `url("data:image/png;base64,cG5n")`) || strings.Contains(css, `href=`) {
+		t.Error(`Unexpected stylesheet`, css)
+	}
+	scripts := assets.Scripts()
+	if !strings.Contains(scripts, `"<\/script>"`) || !strings.Contains(scripts, `var dt = 1;`) || strings.Contains(scripts, `src=`) {
+		t.Error(`Unexpected scripts`, scripts)
+	}
+	var linked Assets
+	if !strings.Contains(linked.Scripts(), `src="`+jqueryURL) || !strings.Contains(linked.Stylesheet(), dataTablesCSSURL) {
+		t.Error(`Unexpected linked assets`, linked.Scripts(), linked.Stylesheet())
+	}
+}
+
+func TestBundle(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	reportPath := filepath.Join(dir, `TestBundle_compare.html`)
+	_ = os.WriteFile(reportPath, []byte(`<html></html>`), 0644)
+	audioPath := filepath.Join(t.TempDir(), `B01___01_Matthew_____ENGWEBN2DA.mp3`)
+	_ = os.WriteFile(audioPath, []byte(`mp3`), 0644)
+	target, status := Bundle(ctx, reportPath, []string{audioPath})
+	if status != nil {
+		t.Fatal(status)
+	}
+	if target != filepath.Join(dir, `TestBundle_compare_bundle.zip`) {
+		t.Error(`Unexpected bundle`, target)
+	}
+	reader, err := zip.OpenReader(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	var names []string
+	for _, file := range reader.File {
+		names = append(names, file.Name)
+	}
+	sort.Strings(names)
+	expect := []string{`TestBundle_compare_bundle/audio/B01___01_Matthew_____ENGWEBN2DA.mp3`,
+		`TestBundle_compare_bundle/index.html`}
+	if strings.Join(names, `,`) != strings.Join(expect, `,`) {
+		t.Error(`Unexpected files`, names)
+	}
+	_, err = os.Stat(filepath.Join(dir, `TestBundle_compare_bundle`))
+	if !os.IsNotExist(err) {
+		t.Error(`Bundle directory was not removed`)
+	}
+}
@@ -0,0 +1,194 @@
+package review
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/db"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/input"
+	log "github.com/faithcomesbyhearing/fcbh-dataset-io/logger"
+)
+
+/*
+Reviewers mark the rows of compare and audio_proof HTML reports as a real error, a false
+positive, or fixed, and export their verdicts as a JSON file.  The verdicts section of a request
+imports those files into the dataset.  Later reports of the dataset do not show the rows that
+were marked false positive, and show the earlier verdict of the other rows.
+
+Each row has an item id, which is its reference and a hash of what it reports, e.g.
+"MAT 1:1#3f2a9c01b7e4", so that a row keeps its id across runs while its difference is the same.
+*/
+
+// Reports that have verdicts, named as their stages
+const (
+	ReportCompare    = `compare`
+	ReportAudioProof = `audio_proof`
+)
+
+var verdictNames = []string{db.VerdictRealError, db.VerdictFalsePositive, db.VerdictFixed}
+
+// VerdictFile is the file exported by a report
+type VerdictFile struct {
+	DatasetName string        `json:"dataset_name"`
+	Report      string        `json:"report"`
+	Reviewer    string        `json:"reviewer,omitempty"`
+	Exported    string        `json:"exported,omitempty"`
+	Verdicts    []FileVerdict `json:"verdicts"`
+}
+
+type FileVerdict struct {
+	ItemId  string `json:"item_id"`
+	Ref     string `json:"ref"`
+	Verdict string `json:"verdict"`
+	Note    string `json:"note,omitempty"`
+}
+
+// ItemId is the reference, and a hash of the content that a report row shows for it
+func ItemId(ref string, content ...string) string {
+	hash := sha256.Sum256([]byte(strings.Join(content, "\x00")))
+	return ref + `#` + hex.EncodeToString(hash[:6])
+}
+
+// Import reads verdict files, which are local paths or s3:// paths, into the dataset,
+// and returns the number of verdicts imported.
+func Import(ctx context.Context, conn db.DBAdapter, languageISO string, paths []string) (int, *log.Status) {
+	var count int
+	for _, path := range paths {
+		file, status := readVerdictFile(ctx, path)
+		if status != nil {
+			return count, status
+		}
+		var records []db.Verdict
+		for _, v := range file.Verdicts {
+			var rec db.Verdict
+			rec.ItemId = v.ItemId
+			rec.Report = file.Report
+			rec.Ref = v.Ref
+			rec.Verdict = v.Verdict
+			rec.LanguageISO = languageISO
+			rec.Reviewer = file.Reviewer
+			rec.Note = v.Note
+			records = append(records, rec)
+		}
+		status = conn.InsertVerdicts(records)
+		if status != nil {
+			return count, status
+		}
+		log.Info(ctx, `Imported`, len(records), file.Report, `verdicts from`, path)
+		count += len(records)
+	}
+	return count, nil
+}
+
+func readVerdictFile(ctx context.Context, path string) (VerdictFile, *log.Status) {
+	var file VerdictFile
+	if strings.HasPrefix(path, `s3:`) {
+		localPath := filepath.Join(os.Getenv(`FCBH_DATASET_TMP`), filepath.Base(path))
+		status := input.DownloadFile(ctx, path, localPath)
+		if status != nil {
+			return file, status
+		}
+		defer os.Remove(localPath)
+		path = localPath
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return file, log.Error(ctx, 400, err, `Unable to read verdict file`, path)
+	}
+	err = json.Unmarshal(content, &file)
+	if err != nil {
+		return file, log.Error(ctx, 400, err, `Verdict file is not valid JSON`, path)
+	}
+	if file.Report != ReportCompare && file.Report != ReportAudioProof {
+		return file, log.ErrorNoErr(ctx, 400, `Verdict file report must be compare or audio_proof, not`, file.Report, path)
+	}
+	for _, v := range file.Verdicts {
+		if v.ItemId == `` || !isVerdict(v.Verdict) {
+			return file, log.ErrorNoErr(ctx, 400, `Verdict file has an invalid verdict`, v.ItemId, v.Verdict, path)
+		}
+	}
+	return file, nil
+}
+
+func isVerdict(verdict string) bool {
+	for _, name := range verdictNames {
+		if verdict == name {
+			return true
+		}
+	}
+	return false
+}
+
+// LanguageStats are the verdicts of one language and report, over all of a user's datasets
+type LanguageStats struct {
+	LanguageISO      string  `json:"language_iso"`
+	Report           string  `json:"report"`
+	RealErrors       int     `json:"real_errors"`
+	FalsePositives   int     `json:"false_positives"`
+	Fixed            int     `json:"fixed"`
+	Total            int     `json:"total"`
+	FalsePositivePct float64 `json:"false_positive_pct"`
+}
+
+// Stats returns the false positive rate of each language and report, over the datasets of username
+func Stats(ctx context.Context, username string) ([]LanguageStats, *log.Status) {
+	var results []LanguageStats
+	counts, status := db.SelectVerdictCounts(ctx, filepath.Join(os.Getenv(`FCBH_DATASET_DB`), username))
+	if status != nil {
+		return results, status
+	}
+	var stats = make(map[string]*LanguageStats)
+	for _, count := range counts {
+		key := count.LanguageISO + ` ` + count.Report
+		s, ok := stats[key]
+		if !ok {
+			s = &LanguageStats{LanguageISO: count.LanguageISO, Report: count.Report}
+			stats[key] = s
+		}
+		switch count.Verdict {
+		case db.VerdictRealError:
+			s.RealErrors += count.Count
+		case db.VerdictFalsePositive:
+			s.FalsePositives += count.Count
+		case db.VerdictFixed:
+			s.Fixed += count.Count
+		}
+		s.Total += count.Count
+	}
+	for _, s := range stats {
+		if s.Total > 0 {
+			s.FalsePositivePct = float64(s.FalsePositives*100) / float64(s.Total)
+		}
+		results = append(results, *s)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].LanguageISO == results[j].LanguageISO {
+			return results[i].Report < results[j].Report
+		}
+		return results[i].LanguageISO < results[j].LanguageISO
+	})
+	return results, nil
+}
+
+// WriteStats writes the stats as {dataset}_verdict_stats.json in FCBH_DATASET_TMP
+func WriteStats(ctx context.Context, datasetName string, stats []LanguageStats) (string, *log.Status) {
+	filename := filepath.Join(os.Getenv(`FCBH_DATASET_TMP`), datasetName+`_verdict_stats.json`)
+	if stats == nil {
+		stats = []LanguageStats{}
+	}
+	content, err := json.MarshalIndent(stats, ``, `  `)
+	if err != nil {
+		return filename, log.Error(ctx, 500, err, `Error encoding verdict stats`)
+	}
+	err = os.WriteFile(filename, content, 0644)
+	if err != nil {
+		return filename, log.Error(ctx, 500, err, `Error writing verdict stats`)
+	}
+	return filename, nil
+}
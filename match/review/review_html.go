@@ -0,0 +1,95 @@
+package review
+
+import (
+	"encoding/json"
+	"html"
+	"strings"
+
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/db"
+)
+
+var verdictLabels = map[string]string{
+	db.VerdictRealError:     `Real Error`,
+	db.VerdictFalsePositive: `False Positive`,
+	db.VerdictFixed:         `Fixed`,
+}
+
+// VerdictCell is the content of a report cell, in which a reviewer chooses the verdict of an item.
+// prior is the verdict that was imported in an earlier run, or empty.
+func VerdictCell(itemId string, ref string, prior string) string {
+	var sb strings.Builder
+	sb.WriteString(`<select class="verdict" data-item="`)
+	sb.WriteString(html.EscapeString(itemId))
+	sb.WriteString(`" data-ref="`)
+	sb.WriteString(html.EscapeString(ref))
+	sb.WriteString(`"><option value=""></option>`)
+	for _, name := range verdictNames {
+		sb.WriteString(`<option value="`)
+		sb.WriteString(name)
+		if name == prior {
+			sb.WriteString(`" selected>`)
+		} else {
+			sb.WriteString(`">`)
+		}
+		sb.WriteString(verdictLabels[name])
+		sb.WriteString(`</option>`)
+	}
+	sb.WriteString(`</select>`)
+	return sb.String()
+}
+
+// VerdictScript is written after the DataTable of a report is created.  Verdicts are kept in the
+// browser's localStorage until they are exported as {dataset}_{report}_verdicts.json.
+func VerdictScript(datasetName string, report string) string {
+	dataset, _ := json.Marshal(datasetName)
+	name, _ := json.Marshal(report)
+	script := `<div style="text-align: center; margin: 10px;">
+		<label for="reviewer">Reviewer: </label><input type="text" id="reviewer" size="30">
+		<button id="exportVerdicts">Export Verdicts</button>
+	</div>
+<script>
+	const verdictDataset = ` + string(dataset) + `;
+	const verdictReport = ` + string(name) + `;
+	const verdictKey = 'fcbh_verdicts:' + verdictDataset + ':' + verdictReport;
+	function savedVerdicts() {
+		return JSON.parse(localStorage.getItem(verdictKey) || '{}');
+	}
+	$(document).ready(function() {
+		var selects = $('#diffTable').DataTable().$('select.verdict');
+		var saved = savedVerdicts();
+		selects.each(function() {
+			var id = $(this).attr('data-item');
+			if (saved[id] !== undefined) {
+				$(this).val(saved[id]);
+			}
+		});
+		selects.on('change', function() {
+			var saved = savedVerdicts();
+			saved[$(this).attr('data-item')] = $(this).val();
+			localStorage.setItem(verdictKey, JSON.stringify(saved));
+		});
+		$('#reviewer').val(localStorage.getItem('fcbh_reviewer') || '');
+		$('#reviewer').on('change', function() {
+			localStorage.setItem('fcbh_reviewer', $(this).val());
+		});
+		$('#exportVerdicts').on('click', function() {
+			var verdicts = [];
+			$('#diffTable').DataTable().$('select.verdict').each(function() {
+				if ($(this).val() !== '') {
+					verdicts.push({item_id: $(this).attr('data-item'), ref: $(this).attr('data-ref'), verdict: $(this).val()});
+				}
+			});
+			var file = {dataset_name: verdictDataset, report: verdictReport, reviewer: $('#reviewer').val(),
+				exported: new Date().toISOString(), verdicts: verdicts};
+			var blob = new Blob([JSON.stringify(file, null, 2)], {type: 'application/json'});
+			var link = document.createElement('a');
+			link.href = URL.createObjectURL(blob);
+			link.download = verdictDataset + '_' + verdictReport + '_verdicts.json';
+			link.click();
+			URL.revokeObjectURL(link.href);
+		});
+	});
+</script>
+`
+	return script
+}
@@ -0,0 +1,76 @@
+package review
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/db"
+)
+
+func TestItemId(t *testing.T) {
+	id := ItemId(`MAT 1:1`, `+abc`, `-de`)
+	if !strings.HasPrefix(id, `MAT 1:1#`) || len(id) != len(`MAT 1:1#`)+12 {
+		t.Error(`Unexpected item id`, id)
+	}
+	if id != ItemId(`MAT 1:1`, `+abc`, `-de`) {
+		t.Error(`Item id is not stable`)
+	}
+	if id == ItemId(`MAT 1:1`, `+abcd`, `-e`) || id == ItemId(`MAT 1:2`, `+abc`, `-de`) {
+		t.Error(`Item id is not unique`)
+	}
+}
+
+func TestImport(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv(`FCBH_DATASET_DB`, t.TempDir())
+	t.Setenv(`FCBH_DATASET_TMP`, t.TempDir())
+	conn, status := db.NewerDBAdapter(ctx, true, `GaryNTest`, `TestImport`)
+	if status != nil {
+		t.Fatal(status)
+	}
+	defer conn.Close()
+	var file = VerdictFile{DatasetName: `TestImport`, Report: ReportCompare, Reviewer: `Gary`,
+		Verdicts: []FileVerdict{
+			{ItemId: `MAT 1:1#aaa`, Ref: `MAT 1:1`, Verdict: db.VerdictFalsePositive},
+			{ItemId: `MAT 1:2#bbb`, Ref: `MAT 1:2`, Verdict: db.VerdictRealError},
+		}}
+	path := filepath.Join(t.TempDir(), `TestImport_compare_verdicts.json`)
+	content, _ := json.Marshal(file)
+	_ = os.WriteFile(path, content, 0644)
+	count, status := Import(ctx, conn, `eng`, []string{path})
+	if status != nil {
+		t.Fatal(status)
+	}
+	if count != 2 {
+		t.Error(`Expected 2 verdicts, found`, count)
+	}
+	verdicts, _ := conn.SelectVerdicts(ReportCompare)
+	if verdicts[`MAT 1:1#aaa`].Verdict != db.VerdictFalsePositive || verdicts[`MAT 1:1#aaa`].Reviewer != `Gary` {
+		t.Error(`Unexpected verdicts`, verdicts)
+	}
+	stats, status := Stats(ctx, `GaryNTest`)
+	if status != nil {
+		t.Fatal(status)
+	}
+	if len(stats) != 1 || stats[0].LanguageISO != `eng` || stats[0].Total != 2 || stats[0].FalsePositivePct != 50.0 {
+		t.Error(`Unexpected stats`, stats)
+	}
+	file.Verdicts[0].Verdict = `maybe`
+	content, _ = json.Marshal(file)
+	_ = os.WriteFile(path, content, 0644)
+	_, status = Import(ctx, conn, `eng`, []string{path})
+	if status == nil || status.Status != 400 {
+		t.Error(`Expected invalid verdict to fail`, status)
+	}
+}
+
+func TestVerdictCell(t *testing.T) {
+	cell := VerdictCell(`MAT 1:1#aaa`, `MAT 1:1`, db.VerdictFixed)
+	if !strings.Contains(cell, `data-item="MAT 1:1#aaa"`) || !strings.Contains(cell, `value="fixed" selected`) {
+		t.Error(`Unexpected cell`, cell)
+	}
+}
@@ -0,0 +1,100 @@
+package metrics
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// The metrics of jobs are kept by the controller, so that they are the same for the API server,
+// the queue worker and the CLI.  The queue depth is set by the server or worker that has a queue.
+var (
+	JobsStarted = NewCounter(`fcbh_jobs_started_total`,
+		`Jobs started, by the stage they started.  Every job starts the decode stage.`, `stage`)
+	JobsFinished = NewCounter(`fcbh_jobs_finished_total`,
+		`Jobs finished, by result, the stage a failed job stopped in, and status code`, `result`, `stage`, `status`)
+	StageDuration = NewHistogram(`fcbh_stage_duration_seconds`,
+		`Duration of each pipeline stage`, []float64{1, 10, 60, 300, 900, 1800, 3600, 7200, 14400, 43200}, `stage`)
+	ChaptersProcessed = NewCounter(`fcbh_chapters_processed_total`,
+		`Chapters processed by stages that run chapter by chapter, by stage and AI backend`, `stage`, `backend`)
+	ChapterSeconds = NewCounter(`fcbh_chapter_processing_seconds_total`,
+		`Time spent processing the chapters of fcbh_chapters_processed_total`, `stage`, `backend`)
+	PythonStarts = NewCounter(`fcbh_python_starts_total`,
+		`Python subprocesses started, by backend.  A restart is a start after a failure.`, `backend`)
+	PythonFailures = NewCounter(`fcbh_python_failures_total`,
+		`Python subprocesses that reported an error or exited with an error, by backend`, `backend`)
+	DownloadBytes = NewCounter(`fcbh_download_bytes_total`,
+		`Bytes downloaded from Bible Brain`)
+	DownloadRetries = NewCounter(`fcbh_download_retries_total`,
+		`Retries of Bible Brain API requests and file downloads`, `kind`)
+	QueueDepth = NewGauge(`fcbh_queue_depth`,
+		`Requests waiting in the queue`)
+	QueueOldestAge = NewGauge(`fcbh_queue_oldest_age_seconds`,
+		`Age of the oldest request waiting in the queue, 0 when it is empty`)
+	DiskUsage = NewGauge(`fcbh_disk_usage_bytes`,
+		`Bytes used by the files under each data directory`, `directory`)
+)
+
+// diskUsageTTL limits how often the data directories are walked, which can take a while
+const diskUsageTTL = 5 * time.Minute
+
+var diskUsage struct {
+	lock sync.Mutex
+	read time.Time
+}
+
+func init() {
+	DiskUsage.OnRead(readDiskUsage)
+}
+
+// OnReadQueue sets the queue metrics each time metrics are read.  read returns the number of
+// waiting requests, and when the oldest was queued, and false when the queue could not be read.
+func OnReadQueue(read func() (int, time.Time, bool)) {
+	QueueDepth.OnRead(func(g *Gauge) {
+		depth, oldest, ok := read()
+		if !ok {
+			return
+		}
+		var age float64
+		if depth > 0 && !oldest.IsZero() {
+			age = time.Since(oldest).Seconds()
+		}
+		g.Set(float64(depth))
+		QueueOldestAge.Set(age)
+	})
+}
+
+func readDiskUsage(g *Gauge) {
+	diskUsage.lock.Lock()
+	defer diskUsage.lock.Unlock()
+	if time.Since(diskUsage.read) < diskUsageTTL {
+		return
+	}
+	diskUsage.read = time.Now()
+	for _, name := range []string{`FCBH_DATASET_DB`, `FCBH_DATASET_FILES`, `FCBH_DATASET_TMP`} {
+		directory := os.Getenv(name)
+		if directory != `` {
+			g.Set(float64(DirectorySize(directory)), name)
+		}
+	}
+}
+
+// DirectorySize is the total size of the files under directory.  Files that cannot be read are skipped.
+func DirectorySize(directory string) int64 {
+	var size int64
+	_ = filepath.WalkDir(directory, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if entry.Type().IsRegular() {
+			info, err2 := entry.Info()
+			if err2 == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
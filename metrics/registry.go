@@ -0,0 +1,249 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	log "github.com/faithcomesbyhearing/fcbh-dataset-io/logger"
+)
+
+/*
+metrics keeps the operational metrics of a server or worker, and serves them in the
+Prometheus text format.  There are counters, gauges and histograms, each of which can have
+labels.  A gauge can have an OnRead func, which sets its value when metrics are read, e.g.
+to the depth of a queue.
+*/
+
+type metric interface {
+	write(sb *strings.Builder)
+}
+
+var registry struct {
+	lock    sync.Mutex
+	metrics []metric
+}
+
+func register(m metric) {
+	registry.lock.Lock()
+	registry.metrics = append(registry.metrics, m)
+	registry.lock.Unlock()
+}
+
+type series struct {
+	labels  []string
+	value   float64
+	buckets []uint64 // histogram only
+	count   uint64   // histogram only
+}
+
+// family is the series of one metric, by the values of its labels
+type family struct {
+	name       string
+	help       string
+	kind       string
+	labelNames []string
+	lock       sync.Mutex
+	series     map[string]*series
+}
+
+func newFamily(name string, help string, kind string, labelNames []string) family {
+	return family{name: name, help: help, kind: kind, labelNames: labelNames, series: make(map[string]*series)}
+}
+
+// get returns the series of labels, which is created when it is new.  It must be called with the lock.
+func (f *family) get(labels []string) *series {
+	var values = make([]string, len(f.labelNames))
+	copy(values, labels)
+	key := strings.Join(values, "\xff")
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labels: values}
+		f.series[key] = s
+	}
+	return s
+}
+
+func (f *family) sorted() []*series {
+	var keys = make([]string, 0, len(f.series))
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var result = make([]*series, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, f.series[key])
+	}
+	return result
+}
+
+func (f *family) writeHeader(sb *strings.Builder) {
+	sb.WriteString(`# HELP ` + f.name + ` ` + f.help + "\n")
+	sb.WriteString(`# TYPE ` + f.name + ` ` + f.kind + "\n")
+}
+
+func (f *family) writeSample(sb *strings.Builder, name string, labels []string, extra string, value float64) {
+	sb.WriteString(name)
+	var pairs []string
+	for i, label := range labels {
+		pairs = append(pairs, f.labelNames[i]+`="`+escape(label)+`"`)
+	}
+	if extra != `` {
+		pairs = append(pairs, extra)
+	}
+	if len(pairs) > 0 {
+		sb.WriteString(`{` + strings.Join(pairs, `,`) + `}`)
+	}
+	sb.WriteString(` ` + formatValue(value) + "\n")
+}
+
+func (f *family) write(sb *strings.Builder) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.writeHeader(sb)
+	for _, s := range f.sorted() {
+		f.writeSample(sb, f.name, s.labels, ``, s.value)
+	}
+}
+
+type Counter struct {
+	family
+}
+
+// NewCounter registers a counter, which only increases
+func NewCounter(name string, help string, labelNames ...string) *Counter {
+	var c = Counter{newFamily(name, help, `counter`, labelNames)}
+	register(&c)
+	return &c
+}
+
+func (c *Counter) Add(value float64, labels ...string) {
+	c.lock.Lock()
+	c.get(labels).value += value
+	c.lock.Unlock()
+}
+
+func (c *Counter) Inc(labels ...string) {
+	c.Add(1, labels...)
+}
+
+type Gauge struct {
+	family
+	onRead func(g *Gauge)
+}
+
+// NewGauge registers a gauge, which can be set to any value
+func NewGauge(name string, help string, labelNames ...string) *Gauge {
+	var g = Gauge{family: newFamily(name, help, `gauge`, labelNames)}
+	register(&g)
+	return &g
+}
+
+func (g *Gauge) Set(value float64, labels ...string) {
+	g.lock.Lock()
+	g.get(labels).value = value
+	g.lock.Unlock()
+}
+
+// OnRead sets a func that sets the gauge each time metrics are read
+func (g *Gauge) OnRead(read func(g *Gauge)) {
+	g.lock.Lock()
+	g.onRead = read
+	g.lock.Unlock()
+}
+
+func (g *Gauge) write(sb *strings.Builder) {
+	g.lock.Lock()
+	read := g.onRead
+	g.lock.Unlock()
+	if read != nil {
+		read(g)
+	}
+	g.family.write(sb)
+}
+
+type Histogram struct {
+	family
+	bounds []float64
+}
+
+// NewHistogram registers a histogram, with the upper bounds of its buckets in increasing order
+func NewHistogram(name string, help string, bounds []float64, labelNames ...string) *Histogram {
+	var h = Histogram{family: newFamily(name, help, `histogram`, labelNames), bounds: bounds}
+	register(&h)
+	return &h
+}
+
+func (h *Histogram) Observe(value float64, labels ...string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	s := h.get(labels)
+	if s.buckets == nil {
+		s.buckets = make([]uint64, len(h.bounds))
+	}
+	for i, bound := range h.bounds {
+		if value <= bound {
+			s.buckets[i]++
+		}
+	}
+	s.count++
+	s.value += value
+}
+
+func (h *Histogram) write(sb *strings.Builder) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.writeHeader(sb)
+	for _, s := range h.sorted() {
+		for i, bound := range h.bounds {
+			h.writeSample(sb, h.name+`_bucket`, s.labels, `le="`+formatValue(bound)+`"`, float64(s.buckets[i]))
+		}
+		h.writeSample(sb, h.name+`_bucket`, s.labels, `le="+Inf"`, float64(s.count))
+		h.writeSample(sb, h.name+`_sum`, s.labels, ``, s.value)
+		h.writeSample(sb, h.name+`_count`, s.labels, ``, float64(s.count))
+	}
+}
+
+// Text returns all metrics in the Prometheus text format
+func Text() string {
+	registry.lock.Lock()
+	var metrics = append([]metric(nil), registry.metrics...)
+	registry.lock.Unlock()
+	var sb strings.Builder
+	for _, m := range metrics {
+		m.write(&sb)
+	}
+	return sb.String()
+}
+
+// Handler serves the metrics, e.g. at /metrics
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(`Content-Type`, `text/plain; version=0.0.4; charset=utf-8`)
+		_, _ = w.Write([]byte(Text()))
+	})
+}
+
+// Serve serves /metrics on its own port, for a program that is not otherwise a server
+func Serve(ctx context.Context, port string) {
+	var mux = http.NewServeMux()
+	mux.Handle(`/metrics`, Handler())
+	go func() {
+		log.Info(ctx, "Metrics server starting on port", port)
+		err := http.ListenAndServe(`:`+port, mux)
+		if err != nil {
+			_ = log.Error(ctx, 500, err, `Error starting metrics server on port`, port)
+		}
+	}()
+}
+
+func escape(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+func formatValue(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
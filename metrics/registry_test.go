@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestText(t *testing.T) {
+	counter := NewCounter(`test_jobs_total`, `Test jobs`, `result`, `stage`)
+	counter.Inc(`failed`, `timestamps`)
+	counter.Add(2, `succeeded`)
+	gauge := NewGauge(`test_depth`, `Test depth`)
+	gauge.OnRead(func(g *Gauge) { g.Set(7) })
+	histogram := NewHistogram(`test_duration_seconds`, `Test duration`, []float64{1, 10}, `stage`)
+	histogram.Observe(0.5, `fetch`)
+	histogram.Observe(5, `fetch`)
+	labelled := NewGauge(`test_escape`, `Test escape`, `directory`)
+	labelled.Set(1, `a "quoted"\path`)
+	text := Text()
+	for _, expected := range []string{
+		"# HELP test_jobs_total Test jobs\n# TYPE test_jobs_total counter\n",
+		"test_jobs_total{result=\"failed\",stage=\"timestamps\"} 1\n",
+		"test_jobs_total{result=\"succeeded\",stage=\"\"} 2\n",
+		"# TYPE test_depth gauge\ntest_depth 7\n",
+		"test_duration_seconds_bucket{stage=\"fetch\",le=\"1\"} 1\n",
+		"test_duration_seconds_bucket{stage=\"fetch\",le=\"10\"} 2\n",
+		"test_duration_seconds_bucket{stage=\"fetch\",le=\"+Inf\"} 2\n",
+		"test_duration_seconds_sum{stage=\"fetch\"} 5.5\n",
+		"test_duration_seconds_count{stage=\"fetch\"} 2\n",
+		"test_escape{directory=\"a \\\"quoted\\\"\\\\path\"} 1\n",
+	} {
+		if !strings.Contains(text, expected) {
+			t.Error(`Missing`, expected)
+		}
+	}
+	recorder := httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, httptest.NewRequest(`GET`, `/metrics`, nil))
+	if !strings.Contains(recorder.Body.String(), `test_depth 7`) {
+		t.Error(`Handler did not serve the metrics`)
+	}
+}
+
+func TestQueueAndDiskUsage(t *testing.T) {
+	OnReadQueue(func() (int, time.Time, bool) {
+		return 3, time.Now().Add(-time.Minute), true
+	})
+	directory := t.TempDir()
+	_ = os.MkdirAll(filepath.Join(directory, `ENGWEB`), 0755)
+	_ = os.WriteFile(filepath.Join(directory, `ENGWEB`, `a.mp3`), make([]byte, 1000), 0644)
+	_ = os.WriteFile(filepath.Join(directory, `b.json`), make([]byte, 24), 0644)
+	if size := DirectorySize(directory); size != 1024 {
+		t.Error(`Expected 1024 bytes, not`, size)
+	}
+	t.Setenv(`FCBH_DATASET_FILES`, directory)
+	diskUsage.read = time.Time{}
+	text := Text()
+	if !strings.Contains(text, "fcbh_queue_depth 3\n") || !strings.Contains(text, "fcbh_queue_oldest_age_seconds 6") {
+		t.Error(`Expected the queue metrics`, text)
+	}
+	if !strings.Contains(text, "fcbh_disk_usage_bytes{directory=\"FCBH_DATASET_FILES\"} 1024\n") {
+		t.Error(`Expected the disk usage of FCBH_DATASET_FILES`)
+	}
+}
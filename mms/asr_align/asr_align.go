@@ -82,6 +82,9 @@ func (a *ASRAlign) ProcessFiles(files []input.InputFile) *log.Status {
 	defer a.uroman.Close()
 	var response string
 	for _, file := range files {
+		if a.ctx.Err() != nil {
+			return log.ContextError(a.ctx, "MMS ASR Align", file.BookId, file.Chapter)
+		}
 		response, status = a.processASR(file, tempDir)
 		if status != nil {
 			return status
@@ -91,6 +94,7 @@ func (a *ASRAlign) ProcessFiles(files []input.InputFile) *log.Status {
 		if status != nil {
 			return status
 		}
+		input.FileDone(a.ctx, file)
 	}
 	return status
 }
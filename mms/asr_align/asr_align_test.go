@@ -71,7 +71,7 @@ func TestASRAlign_ParseResult(t *testing.T) {
 	}
 	fmt.Println("num pairs", len(pairs))
 	report := diff.NewHTMLWriter(ctx, "datasetName1")
-	filename, status := report.WriteReport("baseDataetName", pairs, lang, fileMap, request.SpeechToText{MMS: true})
+	filename, status := report.WriteReport("baseDataetName", pairs, lang, fileMap, "MMS")
 	if status != nil {
 		t.Fatal(status)
 	}
@@ -52,11 +52,15 @@ func (f *ForcedAlign) ProcessFiles(files []input.InputFile) *log.Status {
 		lang = f.sttLang
 	}
 	for _, file := range files {
+		if f.ctx.Err() != nil {
+			return log.ContextError(f.ctx, "Word FA", file.BookId, file.Chapter)
+		}
 		log.Info(f.ctx, "Word FA", file.BookId, file.Chapter)
 		status = f.processFile(file, lang)
 		if status != nil {
 			return status
 		}
+		input.FileDone(f.ctx, file)
 	}
 	return status
 }
@@ -103,7 +107,7 @@ func (f *ForcedAlign) forcedAlign(audioFile string, textFile string, lang string
 	MMSFAPYTHON := os.Getenv("FCBH_MMS_FA_PYTHON")
 	pythonScript := filepath.Join(os.Getenv("GOPROJ"), "/mms/forced_align/align_and_segment.py")
 	outputDir := filepath.Join(tempDir, `output`)
-	cmd := exec.Command(MMSFAPYTHON,
+	cmd := exec.CommandContext(f.ctx, MMSFAPYTHON,
 		pythonScript,
 		`--audio`, audioFile,
 		`--text_filepath`, textFile,
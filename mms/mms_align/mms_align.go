@@ -19,6 +19,7 @@ import (
 	"github.com/faithcomesbyhearing/fcbh-dataset-io/utility/ffmpeg"
 	"github.com/faithcomesbyhearing/fcbh-dataset-io/utility/stdio_exec"
 	"github.com/faithcomesbyhearing/fcbh-dataset-io/utility/uroman"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/utility/worker_pool"
 	"golang.org/x/text/unicode/norm"
 )
 
@@ -42,19 +43,34 @@ type MMSAlign struct {
 	lang     string
 	sttLang  string
 	tempDir  string
+	workers  int
 	uroman   *stdio_exec.StdioExec
 	mmsAlign *stdio_exec.StdioExec
 }
 
+// alignment is the result of aligning one file, which is written to the database in file order
+type alignment struct {
+	verses []db.Audio
+	words  []db.Audio
+}
+
 func NewMMSAlign(ctx context.Context, conn db.DBAdapter, lang string, sttLang string) MMSAlign {
 	var m MMSAlign
 	m.ctx = ctx
 	m.conn = conn
 	m.lang = lang
 	m.sttLang = sttLang
+	m.workers = 1
 	return m
 }
 
+// SetWorkers sets the number of files that are aligned concurrently, each by its own Python processes.
+func (m *MMSAlign) SetWorkers(workers int) {
+	if workers > 0 {
+		m.workers = workers
+	}
+}
+
 // ProcessFiles will perform Forced Alignment on these files
 func (m *MMSAlign) ProcessFiles(files []input.InputFile) *log.Status {
 	var err error
@@ -63,35 +79,63 @@ func (m *MMSAlign) ProcessFiles(files []input.InputFile) *log.Status {
 		return log.Error(m.ctx, 500, err, `Error creating temp dir`)
 	}
 	defer os.RemoveAll(m.tempDir)
+	var status *log.Status
+	var workers = make([]MMSAlign, min(m.workers, max(len(files), 1)))
+	for i := range workers {
+		workers[i] = *m
+		status = workers[i].startPython()
+		defer workers[i].closePython()
+		if status != nil {
+			return status
+		}
+	}
+	status = worker_pool.Run(m.ctx, len(workers), files,
+		func(worker int, file input.InputFile) (alignment, *log.Status) {
+			if m.ctx.Err() != nil {
+				return alignment{}, log.ContextError(m.ctx, "MMS Align", file.BookId, file.Chapter)
+			}
+			log.Info(m.ctx, "MMS Align", file.BookId, file.Chapter)
+			return workers[worker].processFile(file)
+		},
+		func(file input.InputFile, result alignment) *log.Status {
+			status2 := m.updateTimestamps(result)
+			if status2 == nil {
+				input.FileDone(m.ctx, file)
+			}
+			return status2
+		})
+	return status
+}
+
+// startPython starts the uroman and mms_align Python processes used by one worker
+func (m *MMSAlign) startPython() *log.Status {
 	var status *log.Status
 	m.uroman, status = stdio_exec.NewStdioExec(m.ctx, os.Getenv(`FCBH_MMS_FA_PYTHON`), uroman.ScriptPath(), "-l", m.lang)
 	if status != nil {
 		return status
 	}
-	defer m.uroman.Close()
 	pythonScript := filepath.Join(os.Getenv("GOPROJ"), "mms/mms_align/mms_align.py")
 	m.mmsAlign, status = stdio_exec.NewStdioExec(m.ctx, os.Getenv(`FCBH_MMS_FA_PYTHON`), pythonScript)
-	if status != nil {
-		return status
+	return status
+}
+
+func (m *MMSAlign) closePython() {
+	if m.uroman != nil {
+		m.uroman.Close()
 	}
-	defer m.mmsAlign.Close()
-	for _, file := range files {
-		log.Info(m.ctx, "MMS Align", file.BookId, file.Chapter)
-		status = m.processFile(file)
-		if status != nil {
-			return status
-		}
+	if m.mmsAlign != nil {
+		m.mmsAlign.Close()
 	}
-	return status
 }
 
 // processFile will process one audio file through mms forced alignment
-func (m *MMSAlign) processFile(file input.InputFile) *log.Status {
+func (m *MMSAlign) processFile(file input.InputFile) (alignment, *log.Status) {
+	var result alignment
 	var status *log.Status
 	var faInput MMSAlign_Input
 	faInput.AudioFile, status = ffmpeg.ConvertMp3ToWav(m.ctx, m.tempDir, file.FilePath())
 	if status != nil {
-		return status
+		return result, status
 	}
 	var wordList []Word
 	if file.ScriptLine == "" {
@@ -100,11 +144,11 @@ func (m *MMSAlign) processFile(file input.InputFile) *log.Status {
 		faInput.NormWords, wordList, status = m.prepareScriptLineText(file.ScriptLine)
 	}
 	if status != nil {
-		return status
+		return result, status
 	}
 	content, err := json.Marshal(faInput)
 	if err != nil {
-		return log.Error(m.ctx, 500, err, `Error marshalling json`)
+		return result, log.Error(m.ctx, 500, err, `Error marshalling json`)
 	}
 	// development
 	//err2 := os.WriteFile("engweb_fa_inp.json", content, 0644)
@@ -113,7 +157,7 @@ func (m *MMSAlign) processFile(file input.InputFile) *log.Status {
 	//}
 	response, status := m.mmsAlign.Process(string(content))
 	if status != nil {
-		return status
+		return result, status
 	}
 	// development
 	//fmt.Println(len(wordList)) // temp
@@ -121,8 +165,7 @@ func (m *MMSAlign) processFile(file input.InputFile) *log.Status {
 	//if err != nil {
 	//	panic(err)
 	//}
-	status = m.processPyOutput(file, wordList, response)
-	return status
+	return m.processPyOutput(file, wordList, response)
 }
 
 func (m *MMSAlign) prepareText(bookId string, chapter int) ([]string, []Word, *log.Status) {
@@ -236,12 +279,13 @@ type MMSAlignResult struct {
 	Tokens     [][][]float64  `json:"tokens"`
 }
 
-func (m *MMSAlign) processPyOutput(file input.InputFile, wordRefs []Word, response string) *log.Status {
+func (m *MMSAlign) processPyOutput(file input.InputFile, wordRefs []Word, response string) (alignment, *log.Status) {
+	var result alignment
 	var status *log.Status
 	var mmsAlign MMSAlignResult
 	err := json.Unmarshal([]byte(response), &mmsAlign)
 	if err != nil {
-		return log.Error(m.ctx, 500, err, `Error unmarshalling json`)
+		return result, log.Error(m.ctx, 500, err, `Error unmarshalling json`)
 	}
 	var tokenDict = make(map[int]rune)
 	for chr, token := range mmsAlign.Dictionary {
@@ -266,7 +310,7 @@ func (m *MMSAlign) processPyOutput(file input.InputFile, wordRefs []Word, respon
 		faWords = append(faWords, word)
 	}
 	if len(faWords) != len(wordRefs) {
-		return log.ErrorNoErr(m.ctx, 400, "Num words input to mms_align:", len(wordRefs), ", num timestamps returned:", len(faWords))
+		return result, log.ErrorNoErr(m.ctx, 400, "Num words input to mms_align:", len(wordRefs), ", num timestamps returned:", len(faWords))
 	}
 	var words []db.Audio
 	for i, ref := range wordRefs {
@@ -304,19 +348,22 @@ func (m *MMSAlign) processPyOutput(file input.InputFile, wordRefs []Word, respon
 	verses = m.summarizeByVerse(wordsByLine)
 	verses = m.midPoint(verses)
 	verses[len(verses)-1].EndTS, status = ffmpeg.GetAudioDuration(m.ctx, file.Directory, file.Filename)
+	result.verses = verses
+	result.words = words
+	return result, status
+}
+
+// updateTimestamps writes the alignment of one file to the database
+func (m *MMSAlign) updateTimestamps(result alignment) *log.Status {
+	status := m.conn.UpdateScriptFATimestamps(result.verses)
 	if status != nil {
 		return status
 	}
-	status = m.conn.UpdateScriptFATimestamps(verses)
-	if status != nil {
-		return status
-	}
-	status = m.conn.UpdateWordFATimestamps(words)
+	status = m.conn.UpdateWordFATimestamps(result.words)
 	if status != nil {
 		return status
 	}
-	status = m.conn.InsertAudioChars(words)
-	return status
+	return m.conn.InsertAudioChars(result.words)
 }
 
 func (m *MMSAlign) groupByLine(words []db.Audio) [][]db.Audio {
@@ -94,7 +94,11 @@ func TestMMSFA_processPyOutput(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	status = fa.processPyOutput(file, wordList, string(bytes))
+	result, status := fa.processPyOutput(file, wordList, string(bytes))
+	if status != nil {
+		t.Fatal(status)
+	}
+	status = fa.updateTimestamps(result)
 	if status != nil {
 		t.Fatal(status)
 	}
@@ -13,6 +13,7 @@ import (
 	"github.com/faithcomesbyhearing/fcbh-dataset-io/utility/ffmpeg"
 	"github.com/faithcomesbyhearing/fcbh-dataset-io/utility/stdio_exec"
 	"github.com/faithcomesbyhearing/fcbh-dataset-io/utility/uroman"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/utility/worker_pool"
 )
 
 type MMSASR struct {
@@ -21,6 +22,7 @@ type MMSASR struct {
 	lang     string
 	sttLang  string
 	adapter  bool
+	workers  int
 	mmsAsrPy *stdio_exec.StdioExec
 	uroman   *stdio_exec.StdioExec
 }
@@ -32,9 +34,17 @@ func NewMMSASR(ctx context.Context, conn db.DBAdapter, lang string, sttLang stri
 	a.lang = lang
 	a.sttLang = sttLang
 	a.adapter = adapter
+	a.workers = 1
 	return a
 }
 
+// SetWorkers sets the number of files that are recognized concurrently, each by its own Python processes.
+func (a *MMSASR) SetWorkers(workers int) {
+	if workers > 0 {
+		a.workers = workers
+	}
+}
+
 // ProcessFiles will perform Auto Speech Recognition on these files
 func (a *MMSASR) ProcessFiles(files []input.InputFile) *log.Status {
 	var status *log.Status
@@ -57,6 +67,35 @@ func (a *MMSASR) ProcessFiles(files []input.InputFile) *log.Status {
 	if status != nil {
 		return status
 	}
+	var workers = make([]MMSASR, min(a.workers, max(len(files), 1)))
+	for i := range workers {
+		workers[i] = *a
+		status = workers[i].startPython(lang)
+		defer workers[i].closePython()
+		if status != nil {
+			return status
+		}
+	}
+	status = worker_pool.Run(a.ctx, len(workers), files,
+		func(worker int, file input.InputFile) ([]db.Audio, *log.Status) {
+			if a.ctx.Err() != nil {
+				return nil, log.ContextError(a.ctx, "MMS ASR", file.BookId, file.Chapter)
+			}
+			return workers[worker].processFile(file, tempDir)
+		},
+		func(file input.InputFile, audioFiles []db.Audio) *log.Status {
+			status2 := a.updateScriptText(audioFiles)
+			if status2 == nil {
+				input.FileDone(a.ctx, file)
+			}
+			return status2
+		})
+	return status
+}
+
+// startPython starts the mms_asr and uroman Python processes used by one worker
+func (a *MMSASR) startPython(lang string) *log.Status {
+	var status *log.Status
 	pythonScript := filepath.Join(os.Getenv("GOPROJ"), "mms/mms_asr/mms_asr.py")
 	var useAdapter string
 	if a.adapter {
@@ -66,37 +105,35 @@ func (a *MMSASR) ProcessFiles(files []input.InputFile) *log.Status {
 	if status != nil {
 		return status
 	}
-	defer a.mmsAsrPy.Close()
 	a.uroman, status = stdio_exec.NewStdioExec(a.ctx, os.Getenv(`FCBH_MMS_FA_PYTHON`), uroman.ScriptPath(), "-l", a.lang)
-	if status != nil {
-		return status
+	return status
+}
+
+func (a *MMSASR) closePython() {
+	if a.mmsAsrPy != nil {
+		a.mmsAsrPy.Close()
 	}
-	defer a.uroman.Close()
-	for _, file := range files {
-		status = a.processFile(file, tempDir)
-		if status != nil {
-			return status
-		}
+	if a.uroman != nil {
+		a.uroman.Close()
 	}
-	return status
 }
 
-// processFile
-func (a *MMSASR) processFile(file input.InputFile, tempDir string) *log.Status {
+// processFile performs ASR on one file, and returns the text of each script line
+func (a *MMSASR) processFile(file input.InputFile, tempDir string) ([]db.Audio, *log.Status) {
 	var status *log.Status
 	wavFile, status := ffmpeg.ConvertMp3ToWav(a.ctx, tempDir, file.FilePath())
 	if status != nil {
-		return status
+		return nil, status
 	}
 	var audioFiles []db.Audio
 	if file.ScriptLine != "" {
 		var audioFile db.Audio
 		audioFile, status = a.selectScriptLine(file.ScriptLine)
 		if status != nil {
-			return status
+			return nil, status
 		}
 		if audioFile.ScriptEndTS == 0.0 {
-			return nil
+			return nil, nil
 		}
 		log.Info(a.ctx, "MMS ASR", audioFile.BookId, audioFile.ChapterNum, file.ScriptLine)
 		audioFile.AudioVerseWav = wavFile
@@ -105,7 +142,7 @@ func (a *MMSASR) processFile(file input.InputFile, tempDir string) *log.Status {
 		log.Info(a.ctx, "MMS ASR", file.BookId, file.Chapter)
 		audioFiles, status = a.conn.SelectFAScriptTimestamps(file.BookId, file.Chapter)
 		if status != nil {
-			return status
+			return nil, status
 		}
 		audioFiles, status = ffmpeg.ChopByTimestamp(a.ctx, tempDir, wavFile, audioFiles)
 	}
@@ -115,19 +152,26 @@ func (a *MMSASR) processFile(file input.InputFile, tempDir string) *log.Status {
 		fmt.Println(ts.BookId, ts.ChapterNum, ts.VerseStr, "sid:", ts.ScriptId)
 		response, status1 := a.mmsAsrPy.Process(ts.AudioVerseWav)
 		if status1 != nil {
-			return status1
+			return nil, status1
 		}
 		fmt.Println("response:", response)
 		audioFiles[i].Text = response
 		uRoman, status2 := a.uroman.Process(response)
 		if status2 != nil {
-			return status2
+			return nil, status2
 		}
 		audioFiles[i].Uroman = uRoman
 	}
 	//log.Debug(a.ctx, "Finished ASR", file.BookId, file.Chapter)
-	var recCount int
-	recCount, status = a.conn.UpdateScriptText(audioFiles)
+	return audioFiles, status
+}
+
+// updateScriptText writes the ASR result of one file to the database
+func (a *MMSASR) updateScriptText(audioFiles []db.Audio) *log.Status {
+	if len(audioFiles) == 0 {
+		return nil
+	}
+	recCount, status := a.conn.UpdateScriptText(audioFiles)
 	if recCount != len(audioFiles) {
 		log.Warn(a.ctx, "Timestamp update counts needs investigation", recCount, len(audioFiles))
 	}
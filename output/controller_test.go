@@ -58,7 +58,7 @@ func prepareTimestampAndFMCCData(conn db.DBAdapter, bibleId string, filesetId st
 	if status != nil {
 		t.Error(status)
 	}
-	files, status := input.DBPDirectory(ctx, bibleId, `audio`, ``, filesetId, testament)
+	files, status := input.DBPDirectory(ctx, bibleId, `audio`, ``, filesetId)
 	if status != nil {
 		t.Error(status)
 	}
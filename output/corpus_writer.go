@@ -0,0 +1,289 @@
+package output
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/db"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/decode_yaml/request"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/input"
+	log "github.com/faithcomesbyhearing/fcbh-dataset-io/logger"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/utility/ffmpeg"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/utility/zip"
+)
+
+/*
+A speech corpus has the audio of each segment in wavs/{split}/{id}.wav, where split is
+train, dev or test, so that Hugging Face audiofolder finds the splits by directory.
+The manifests of each format refer to the audio by its path from the root of the corpus:
+huggingface: wavs/{split}/metadata.csv
+nemo: {split}_manifest.json
+kaldi: kaldi/{split}/wav.scp, text, segments, utt2spk
+ljspeech: ljspeech_{split}.csv, with ids of {split}/{id}, because LJSpeech audio is in wavs/
+*/
+
+// CorpusSegment is one utterance of a speech corpus, which is a verse or a script line.
+type CorpusSegment struct {
+	Id         string
+	Split      string
+	AudioPath  string // the audio file it is cut from
+	BookId     string
+	ChapterNum int
+	VerseStr   string
+	BeginTS    float64
+	EndTS      float64
+	FAScore    float64
+	Text       string
+	Filename   string // the path of its audio in the corpus
+}
+
+func (s CorpusSegment) Duration() float64 {
+	return s.EndTS - s.BeginTS
+}
+
+// PrepareCorpus finds the segments of each audio file from the timestamps of its scripts.
+func (o *Output) PrepareCorpus(audioFiles []input.InputFile, corpus request.Corpus) ([]CorpusSegment, *log.Status) {
+	var segments []CorpusSegment
+	for _, file := range audioFiles {
+		var scripts []db.Audio
+		for chapter := file.Chapter; chapter <= max(file.Chapter, file.ChapterEnd); chapter++ {
+			records, status := o.conn.SelectFAScriptTimestamps(file.BookId, chapter)
+			if status != nil {
+				return segments, status
+			}
+			for _, rec := range records {
+				if filepath.Base(rec.AudioFile) == file.Filename {
+					scripts = append(scripts, rec)
+				}
+			}
+		}
+		audioPath := filepath.Join(file.Directory, file.Filename)
+		segments = append(segments, BuildCorpusSegments(scripts, audioPath, corpus)...)
+	}
+	return segments, nil
+}
+
+// BuildCorpusSegments converts the scripts of one audio file into segments.  When segments
+// is verse, consecutive scripts of the same verse are joined, and the lowest fa_score is kept.
+// Scripts without timestamps or text, or with an fa_score below min_fa_score, are skipped.
+func BuildCorpusSegments(scripts []db.Audio, audioPath string, corpus request.Corpus) []CorpusSegment {
+	var results []CorpusSegment
+	var lastKey string
+	var lineNum int
+	for _, scr := range scripts {
+		text := strings.Join(strings.Fields(scr.Text), ` `)
+		if scr.EndTS <= scr.BeginTS || text == `` {
+			continue
+		}
+		key := scr.BookId + `:` + strconv.Itoa(scr.ChapterNum) + `:` + scr.VerseStr
+		if corpus.Segments == `verse` && key == lastKey && len(results) > 0 {
+			seg := &results[len(results)-1]
+			seg.EndTS = math.Max(seg.EndTS, scr.EndTS)
+			seg.FAScore = math.Min(seg.FAScore, scr.FAScore)
+			seg.Text = seg.Text + ` ` + text
+			continue
+		}
+		if key != lastKey {
+			lineNum = 0
+		}
+		lineNum++
+		lastKey = key
+		var seg CorpusSegment
+		seg.Id = corpusId(scr.BookId, scr.ChapterNum, scr.VerseStr)
+		if corpus.Segments == `line` {
+			seg.Id += fmt.Sprintf(`_%02d`, lineNum)
+		}
+		seg.Split = corpusSplit(corpus, scr.BookId)
+		seg.AudioPath = audioPath
+		seg.BookId = scr.BookId
+		seg.ChapterNum = scr.ChapterNum
+		seg.VerseStr = scr.VerseStr
+		seg.BeginTS = scr.BeginTS
+		seg.EndTS = scr.EndTS
+		seg.FAScore = scr.FAScore
+		seg.Text = text
+		seg.Filename = filepath.Join(`wavs`, seg.Split, seg.Id+`.`+corpus.AudioFormat)
+		results = append(results, seg)
+	}
+	if corpus.MinFAScore > 0.0 {
+		var kept = results[:0]
+		for _, seg := range results {
+			if seg.FAScore >= corpus.MinFAScore {
+				kept = append(kept, seg)
+			}
+		}
+		results = kept
+	}
+	return results
+}
+
+var leadingDigits = regexp.MustCompile(`^\d+`)
+var notIdChars = regexp.MustCompile(`[^A-Za-z0-9-]`)
+
+// corpusId is e.g. MAT_001_006-10, the verse is zero filled so that ids sort in order
+func corpusId(bookId string, chapter int, verseStr string) string {
+	verse := leadingDigits.ReplaceAllStringFunc(verseStr, func(num string) string {
+		return fmt.Sprintf(`%03s`, num)
+	})
+	verse = notIdChars.ReplaceAllString(verse, `-`)
+	return fmt.Sprintf(`%s_%03d_%s`, bookId, chapter, verse)
+}
+
+// corpusSplit assigns whole books to a split, so that no verse of a dev or test book is trained on
+func corpusSplit(corpus request.Corpus, bookId string) string {
+	if slices.Contains(corpus.TestBooks, bookId) {
+		return `test`
+	}
+	if slices.Contains(corpus.DevBooks, bookId) {
+		return `dev`
+	}
+	return `train`
+}
+
+// WriteCorpus cuts the audio of each segment, writes the manifests of each format, and
+// returns the corpus as one zip file.
+func (o *Output) WriteCorpus(segments []CorpusSegment, corpus request.Corpus) (string, *log.Status) {
+	directory := filepath.Join(os.Getenv(`FCBH_DATASET_TMP`), o.requestName+`_corpus`)
+	_ = os.RemoveAll(directory)
+	for _, split := range []string{`train`, `dev`, `test`} {
+		err := os.MkdirAll(filepath.Join(directory, `wavs`, split), 0755)
+		if err != nil {
+			return ``, log.Error(o.ctx, 500, err, `failed to create corpus directory`)
+		}
+	}
+	var audioPaths []string
+	var byAudio = make(map[string][]db.Audio)
+	for _, seg := range segments {
+		if _, ok := byAudio[seg.AudioPath]; !ok {
+			audioPaths = append(audioPaths, seg.AudioPath)
+		}
+		var ts db.Audio
+		ts.BeginTS = seg.BeginTS
+		ts.EndTS = seg.EndTS
+		ts.AudioVerseWav = filepath.Join(directory, seg.Filename)
+		byAudio[seg.AudioPath] = append(byAudio[seg.AudioPath], ts)
+	}
+	for _, audioPath := range audioPaths {
+		status := ffmpeg.ChopSegments(o.ctx, audioPath, byAudio[audioPath])
+		if status != nil {
+			return ``, status
+		}
+	}
+	manifests := CorpusManifests(segments, corpus.Formats)
+	for name, content := range manifests {
+		filename := filepath.Join(directory, name)
+		err := os.MkdirAll(filepath.Dir(filename), 0755)
+		if err != nil {
+			return ``, log.Error(o.ctx, 500, err, `failed to create corpus directory`)
+		}
+		err = os.WriteFile(filename, []byte(content), 0644)
+		if err != nil {
+			return ``, log.Error(o.ctx, 500, err, `failed to write corpus manifest`, filename)
+		}
+	}
+	zipFile, _, err := zip.ZipDirectory(directory)
+	if err != nil {
+		return zipFile, log.Error(o.ctx, 500, err, `failed to zip corpus`, directory)
+	}
+	_ = os.RemoveAll(directory)
+	return zipFile, nil
+}
+
+// CorpusManifests returns the content of each manifest file of formats, by its path in the corpus.
+// Each file is sorted by id, as Kaldi requires.
+func CorpusManifests(segments []CorpusSegment, formats []string) map[string]string {
+	var results = make(map[string]string)
+	var sorted = slices.Clone(segments)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Id < sorted[j].Id })
+	var bySplit = make(map[string][]CorpusSegment)
+	for _, seg := range sorted {
+		bySplit[seg.Split] = append(bySplit[seg.Split], seg)
+	}
+	for split, segs := range bySplit {
+		for _, format := range formats {
+			switch format {
+			case `huggingface`:
+				results[filepath.Join(`wavs`, split, `metadata.csv`)] = huggingFaceCSV(segs)
+			case `nemo`:
+				results[split+`_manifest.json`] = nemoManifest(segs)
+			case `kaldi`:
+				for name, content := range kaldiFiles(segs) {
+					results[filepath.Join(`kaldi`, split, name)] = content
+				}
+			case `ljspeech`:
+				results[`ljspeech_`+split+`.csv`] = ljSpeechCSV(segs)
+			}
+		}
+	}
+	return results
+}
+
+func huggingFaceCSV(segments []CorpusSegment) string {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	_ = writer.Write([]string{`file_name`, `transcription`, `book_id`, `chapter_num`, `verse_str`, `duration`})
+	for _, seg := range segments {
+		_ = writer.Write([]string{filepath.Base(seg.Filename), seg.Text, seg.BookId,
+			strconv.Itoa(seg.ChapterNum), seg.VerseStr, formatSec(seg.Duration())})
+	}
+	writer.Flush()
+	return buf.String()
+}
+
+type nemoLine struct {
+	AudioFilepath string  `json:"audio_filepath"`
+	Duration      float64 `json:"duration"`
+	Text          string  `json:"text"`
+}
+
+func nemoManifest(segments []CorpusSegment) string {
+	var sb strings.Builder
+	for _, seg := range segments {
+		line, _ := json.Marshal(nemoLine{AudioFilepath: filepath.ToSlash(seg.Filename),
+			Duration: math.Round(seg.Duration()*1000.0) / 1000.0, Text: seg.Text})
+		sb.Write(line)
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// kaldiFiles has each segment as its own recording, and its own speaker, because speakers are not known.
+func kaldiFiles(segments []CorpusSegment) map[string]string {
+	var wavScp, text, segs, utt2spk strings.Builder
+	for _, seg := range segments {
+		path := filepath.ToSlash(seg.Filename)
+		if filepath.Ext(path) == `.flac` {
+			wavScp.WriteString(seg.Id + ` flac -c -d -s ` + path + " |\n")
+		} else {
+			wavScp.WriteString(seg.Id + ` ` + path + "\n")
+		}
+		text.WriteString(seg.Id + ` ` + seg.Text + "\n")
+		segs.WriteString(seg.Id + ` ` + seg.Id + ` 0.000 ` + formatSec(seg.Duration()) + "\n")
+		utt2spk.WriteString(seg.Id + ` ` + seg.Id + "\n")
+	}
+	return map[string]string{`wav.scp`: wavScp.String(), `text`: text.String(),
+		`segments`: segs.String(), `utt2spk`: utt2spk.String()}
+}
+
+func ljSpeechCSV(segments []CorpusSegment) string {
+	var sb strings.Builder
+	for _, seg := range segments {
+		text := strings.ReplaceAll(seg.Text, `|`, ` `)
+		sb.WriteString(seg.Split + `/` + seg.Id + `|` + text + `|` + text + "\n")
+	}
+	return sb.String()
+}
+
+func formatSec(sec float64) string {
+	return strconv.FormatFloat(sec, 'f', 3, 64)
+}
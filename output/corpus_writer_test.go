@@ -0,0 +1,61 @@
+package output
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/db"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/decode_yaml/request"
+)
+
+func TestBuildCorpusSegments(t *testing.T) {
+	scripts := []db.Audio{
+		{BookId: `MAT`, ChapterNum: 1, VerseStr: `0`, Text: `Matthew 1`, BeginTS: 0.0, EndTS: 1.5, FAScore: 0.9},
+		{BookId: `MAT`, ChapterNum: 1, VerseStr: `1`, Text: "The book of the genealogy\n", BeginTS: 1.5, EndTS: 3.0, FAScore: 0.8},
+		{BookId: `MAT`, ChapterNum: 1, VerseStr: `1`, Text: `of Jesus Christ.`, BeginTS: 3.0, EndTS: 4.25, FAScore: 0.7},
+		{BookId: `MAT`, ChapterNum: 1, VerseStr: `2-3`, Text: `Abraham, "father" of Isaac.`, BeginTS: 4.25, EndTS: 9.0, FAScore: 0.2},
+		{BookId: `MAT`, ChapterNum: 1, VerseStr: `4`, Text: `No timestamps`},
+	}
+	var corpus = request.Corpus{Formats: []string{`huggingface`, `nemo`, `kaldi`, `ljspeech`},
+		Segments: `verse`, AudioFormat: `wav`, TestBooks: []string{`MAT`}}
+	segments := BuildCorpusSegments(scripts, `/audio/B01___01_Matthew.mp3`, corpus)
+	if len(segments) != 3 {
+		t.Fatal(`Expected 3 verse segments, got`, len(segments))
+	}
+	seg := segments[1]
+	if seg.Id != `MAT_001_001` || seg.Text != `The book of the genealogy of Jesus Christ.` ||
+		seg.EndTS != 4.25 || seg.FAScore != 0.7 || seg.Filename != `wavs/test/MAT_001_001.wav` {
+		t.Error(`Unexpected joined verse`, seg)
+	}
+	if segments[2].Id != `MAT_001_002-3` {
+		t.Error(`Unexpected id of a verse range`, segments[2].Id)
+	}
+	corpus.MinFAScore = 0.5
+	if len(BuildCorpusSegments(scripts, ``, corpus)) != 2 {
+		t.Error(`Expected the verse below min_fa_score to be skipped`)
+	}
+	corpus.Segments = `line`
+	lines := BuildCorpusSegments(scripts, ``, corpus)
+	if len(lines) != 3 || lines[2].Id != `MAT_001_001_02` {
+		t.Error(`Expected 3 lines, the last is the second line of verse 1`, lines)
+	}
+	manifests := CorpusManifests(segments, corpus.Formats)
+	if len(manifests) != 7 {
+		t.Error(`Expected a file for huggingface, nemo, ljspeech, and 4 for kaldi`, len(manifests))
+	}
+	csv := manifests[`wavs/test/metadata.csv`]
+	if !strings.Contains(csv, "MAT_001_002-3.wav,\"Abraham, \"\"father\"\" of Isaac.\",MAT,1,2-3,4.750\n") {
+		t.Error("Unexpected metadata.csv\n", csv)
+	}
+	nemo := manifests[`test_manifest.json`]
+	if !strings.HasPrefix(nemo, `{"audio_filepath":"wavs/test/MAT_001_000.wav","duration":1.5,"text":"Matthew 1"}`) {
+		t.Error("Unexpected NeMo manifest\n", nemo)
+	}
+	if manifests[`kaldi/test/segments`] != "MAT_001_000 MAT_001_000 0.000 1.500\n"+
+		"MAT_001_001 MAT_001_001 0.000 2.750\nMAT_001_002-3 MAT_001_002-3 0.000 4.750\n" {
+		t.Error("Unexpected kaldi segments\n", manifests[`kaldi/test/segments`])
+	}
+	if !strings.HasPrefix(manifests[`ljspeech_test.csv`], "test/MAT_001_000|Matthew 1|Matthew 1\n") {
+		t.Error("Unexpected LJSpeech metadata\n", manifests[`ljspeech_test.csv`])
+	}
+}
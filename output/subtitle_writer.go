@@ -0,0 +1,196 @@
+package output
+
+import (
+	"bufio"
+	"fmt"
+	log "github.com/faithcomesbyhearing/fcbh-dataset-io/logger"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Cue is one subtitle, it is a verse or a script line of one audio file.
+type Cue struct {
+	AudioFile string
+	BeginTS   float64
+	EndTS     float64
+	Text      string
+	Words     []Word
+}
+
+// PrepareCues loads the scripts, and words when karaoke is requested, and builds
+// the cues of each audio file.  Cues is either verse or line.
+func (o *Output) PrepareCues(cues string, karaoke bool) ([]Cue, *log.Status) {
+	scripts, status := o.LoadScriptStruct(o.conn)
+	if status != nil {
+		return nil, status
+	}
+	var words []Word
+	if karaoke {
+		words, status = o.LoadWordStruct(o.conn)
+		if status != nil {
+			return nil, status
+		}
+	}
+	return o.BuildCues(scripts, words, cues), nil
+}
+
+// BuildCues converts scripts into cues, skipping scripts that have no audio file or timestamps.
+// When cues is verse, consecutive scripts of the same verse are joined into one cue.
+func (o *Output) BuildCues(scripts []Script, words []Word, cues string) []Cue {
+	var wordMap = make(map[int][]Word)
+	for _, wd := range words {
+		wordMap[wd.ScriptId] = append(wordMap[wd.ScriptId], wd)
+	}
+	var results []Cue
+	var lastKey string
+	for _, scr := range scripts {
+		if scr.AudioFile == `` || scr.ScriptEndTS <= scr.ScriptBeginTS {
+			continue
+		}
+		key := scr.AudioFile + `:` + scr.BookId + `:` + strconv.Itoa(scr.ChapterNum) + `:` + scr.VerseStr
+		text := strings.Join(strings.Fields(scr.ScriptText), ` `)
+		if cues == `verse` && key == lastKey && len(results) > 0 {
+			cue := &results[len(results)-1]
+			cue.EndTS = math.Max(cue.EndTS, scr.ScriptEndTS)
+			cue.Text = strings.TrimSpace(cue.Text + ` ` + text)
+			cue.Words = append(cue.Words, wordMap[scr.ScriptId]...)
+		} else {
+			var cue Cue
+			cue.AudioFile = scr.AudioFile
+			cue.BeginTS = scr.ScriptBeginTS
+			cue.EndTS = scr.ScriptEndTS
+			cue.Text = text
+			cue.Words = wordMap[scr.ScriptId]
+			results = append(results, cue)
+		}
+		lastKey = key
+	}
+	return results
+}
+
+// WriteSubtitles writes one WebVTT and/or SRT file per audio file into a directory
+// named for the request, and returns the names of the files written.
+func (o *Output) WriteSubtitles(cues []Cue, webVTT bool, srt bool, karaoke bool) ([]string, *log.Status) {
+	var filenames []string
+	directory := filepath.Join(os.Getenv(`FCBH_DATASET_TMP`), o.requestName+`_subtitles`)
+	err := os.MkdirAll(directory, 0755)
+	if err != nil {
+		return filenames, log.Error(o.ctx, 500, err, `failed to create subtitle directory`)
+	}
+	var audioFiles []string
+	var byFile = make(map[string][]Cue)
+	for _, cue := range cues {
+		if _, ok := byFile[cue.AudioFile]; !ok {
+			audioFiles = append(audioFiles, cue.AudioFile)
+		}
+		byFile[cue.AudioFile] = append(byFile[cue.AudioFile], cue)
+	}
+	for _, audioFile := range audioFiles {
+		base := filepath.Base(audioFile)
+		base = filepath.Join(directory, strings.TrimSuffix(base, filepath.Ext(base)))
+		if webVTT {
+			filename := base + `.vtt`
+			status := o.writeFile(filename, FormatWebVTT(byFile[audioFile], karaoke))
+			if status != nil {
+				return filenames, status
+			}
+			filenames = append(filenames, filename)
+		}
+		if srt {
+			filename := base + `.srt`
+			status := o.writeFile(filename, FormatSRT(byFile[audioFile]))
+			if status != nil {
+				return filenames, status
+			}
+			filenames = append(filenames, filename)
+		}
+	}
+	return filenames, nil
+}
+
+func (o *Output) writeFile(filename string, content string) *log.Status {
+	file, err := os.Create(filename)
+	if err != nil {
+		return log.Error(o.ctx, 500, err, `failed to create subtitle file`, filename)
+	}
+	writer := bufio.NewWriter(file)
+	_, err = writer.WriteString(content)
+	if err != nil {
+		return log.Error(o.ctx, 500, err, `failed to write subtitle file`, filename)
+	}
+	err = writer.Flush()
+	if err != nil {
+		return log.Error(o.ctx, 500, err, `failed to flush subtitle file`, filename)
+	}
+	err = file.Close()
+	if err != nil {
+		return log.Error(o.ctx, 500, err, `error closing subtitle file`, filename)
+	}
+	return nil
+}
+
+// FormatWebVTT formats the cues of one audio file.  When karaoke is set and the cue has
+// words, each word is wrapped in a <c> tag preceded by the time at which it begins.
+func FormatWebVTT(cues []Cue, karaoke bool) string {
+	var sb strings.Builder
+	sb.WriteString("WEBVTT\n")
+	for i, cue := range cues {
+		sb.WriteString("\n")
+		sb.WriteString(strconv.Itoa(i + 1))
+		sb.WriteString("\n")
+		sb.WriteString(formatTS(cue.BeginTS, `.`) + ` --> ` + formatTS(cue.EndTS, `.`) + "\n")
+		if karaoke && len(cue.Words) > 0 {
+			var parts []string
+			for _, wd := range cue.Words {
+				part := `<c>` + escapeVTT(wd.Word) + `</c>`
+				if wd.WordBeginTS > cue.BeginTS && wd.WordBeginTS < cue.EndTS {
+					part = `<` + formatTS(wd.WordBeginTS, `.`) + `>` + part
+				}
+				parts = append(parts, part)
+			}
+			sb.WriteString(strings.Join(parts, ` `))
+		} else {
+			sb.WriteString(escapeVTT(cue.Text))
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// FormatSRT formats the cues of one audio file.
+func FormatSRT(cues []Cue) string {
+	var sb strings.Builder
+	for i, cue := range cues {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(strconv.Itoa(i + 1))
+		sb.WriteString("\n")
+		sb.WriteString(formatTS(cue.BeginTS, `,`) + ` --> ` + formatTS(cue.EndTS, `,`) + "\n")
+		sb.WriteString(cue.Text)
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// formatTS formats seconds as hh:mm:ss.mmm, SRT uses a comma before the milliseconds.
+func formatTS(seconds float64, sep string) string {
+	millis := int64(math.Round(seconds * 1000.0))
+	hours := millis / 3600000
+	millis -= hours * 3600000
+	minutes := millis / 60000
+	millis -= minutes * 60000
+	secs := millis / 1000
+	millis -= secs * 1000
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", hours, minutes, secs, sep, millis)
+}
+
+func escapeVTT(text string) string {
+	text = strings.ReplaceAll(text, `&`, `&amp;`)
+	text = strings.ReplaceAll(text, `<`, `&lt;`)
+	text = strings.ReplaceAll(text, `>`, `&gt;`)
+	return text
+}
@@ -0,0 +1,46 @@
+package output
+
+import (
+	"context"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/db"
+	"testing"
+)
+
+func TestBuildCues(t *testing.T) {
+	var out = NewOutput(context.Background(), db.DBAdapter{}, `TestSubtitles`, false, false)
+	scripts := []Script{
+		{ScriptId: 1, AudioFile: `B01___01_Matthew.mp3`, BookId: `MAT`, ChapterNum: 1, VerseStr: `1`,
+			ScriptText: "The book of the genealogy of Jesus Christ,\n", ScriptBeginTS: 1.0, ScriptEndTS: 3.5},
+		{ScriptId: 2, AudioFile: `B01___01_Matthew.mp3`, BookId: `MAT`, ChapterNum: 1, VerseStr: `1`,
+			ScriptText: `the son of David.`, ScriptBeginTS: 3.5, ScriptEndTS: 5.25},
+		{ScriptId: 3, AudioFile: `B01___01_Matthew.mp3`, BookId: `MAT`, ChapterNum: 1, VerseStr: `2`,
+			ScriptText: `Abraham became the father of Isaac.`, ScriptBeginTS: 5.25, ScriptEndTS: 3661.0},
+		{ScriptId: 4, AudioFile: `B01___01_Matthew.mp3`, BookId: `MAT`, ChapterNum: 1, VerseStr: `3`,
+			ScriptText: `No timestamps`},
+	}
+	words := []Word{
+		{ScriptId: 3, Word: `Abraham`, WordBeginTS: 5.25},
+		{ScriptId: 3, Word: `became`, WordBeginTS: 6.0},
+	}
+	cues := out.BuildCues(scripts, words, `verse`)
+	if len(cues) != 2 {
+		t.Fatal(`Expected 2 verse cues, got`, len(cues))
+	}
+	if cues[0].Text != `The book of the genealogy of Jesus Christ, the son of David.` || cues[0].EndTS != 5.25 {
+		t.Error(`Unexpected first cue`, cues[0])
+	}
+	if len(out.BuildCues(scripts, words, `line`)) != 3 {
+		t.Error(`Expected 3 line cues`)
+	}
+	vtt := FormatWebVTT(cues[1:], true)
+	expect := "WEBVTT\n\n1\n00:00:05.250 --> 01:01:01.000\n<c>Abraham</c> <00:00:06.000><c>became</c>\n"
+	if vtt != expect {
+		t.Error("Unexpected WebVTT\n", vtt)
+	}
+	srt := FormatSRT(cues)
+	expect = "1\n00:00:01,000 --> 00:00:05,250\nThe book of the genealogy of Jesus Christ, the son of David.\n\n" +
+		"2\n00:00:05,250 --> 01:01:01,000\nAbraham became the father of Isaac.\n"
+	if srt != expect {
+		t.Error("Unexpected SRT\n", srt)
+	}
+}
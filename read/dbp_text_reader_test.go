@@ -15,7 +15,7 @@ func TestDBPTextReader1(t *testing.T) {
 	otFileset := `ENGWEBO_ET`
 	ntFileset := `ENGWEBN_ET`
 	testament := request.Testament{NTBooks: []string{`MAT`, `MRK`}, OTBooks: []string{`JOB`, `PSA`, `PRO`, `SNG`}}
-	files, status := input.DBPDirectory(ctx, bibleId, fsType, otFileset, ntFileset, testament)
+	files, status := input.DBPDirectory(ctx, bibleId, fsType, otFileset, ntFileset)
 	if status != nil {
 		t.Error(status)
 	}
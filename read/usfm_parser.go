@@ -0,0 +1,290 @@
+package read
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/db"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/input"
+	log "github.com/faithcomesbyhearing/fcbh-dataset-io/logger"
+)
+
+/*
+USFMParser reads Paratext USFM files.  Each file is converted to USX in memory, and then
+parsed by USXParser, so that the scripts produced, and the styles included by usfm_styles.go,
+are the same as for USX.
+*/
+
+type USFMParser struct {
+	ctx  context.Context
+	conn db.DBAdapter
+	usx  USXParser
+}
+
+func NewUSFMParser(conn db.DBAdapter) USFMParser {
+	var p USFMParser
+	p.ctx = conn.Ctx
+	p.conn = conn
+	p.usx = NewUSXParser(conn)
+	return p
+}
+
+func (p *USFMParser) ProcessFiles(inputFiles []input.InputFile) *log.Status {
+	var status *log.Status
+	for _, file := range inputFiles {
+		filename := filepath.Join(file.Directory, file.Filename)
+		content, err := os.ReadFile(filename)
+		if err != nil {
+			return log.Error(p.ctx, 500, err, "USFMParser could not open USFM File.")
+		}
+		usx := USFMToUSX(string(content))
+		var records []db.Script
+		var titles titleDesc
+		records, titles, status = p.usx.decodeReader(p.ctx, bytes.NewReader(usx))
+		if status != nil {
+			return status
+		}
+		records = p.usx.addChapterHeading(records, titles)
+		records = p.usx.correctScriptNum(records)
+		status = p.conn.InsertScripts(records)
+		if status != nil {
+			return status
+		}
+	}
+	return status
+}
+
+// usfmElement is an open USX element while converting
+type usfmElement struct {
+	tag    string // para, char, note, figure, sidebar, row, cell
+	marker string
+}
+
+type usfmWriter struct {
+	buf     bytes.Buffer
+	open    []usfmElement
+	pending string // id, c, or v, when the next text begins with its number or code
+}
+
+// USFMToUSX converts USFM text into USX.  Milestones and attributes are dropped, and the
+// content of notes and figures is kept as plain text, because they are not script text.
+func USFMToUSX(content string) []byte {
+	var w usfmWriter
+	w.buf.WriteString(`<?xml version="1.0" encoding="utf-8"?>` + "\n" + `<usx version="3.0">`)
+	content = strings.TrimPrefix(content, "\uFEFF")
+	content = strings.ReplaceAll(content, `~`, "\u00A0")
+	content = strings.ReplaceAll(content, `//`, ``)
+	pos := 0
+	for pos < len(content) {
+		next := strings.IndexByte(content[pos:], '\\')
+		if next < 0 {
+			w.text(content[pos:])
+			break
+		}
+		if next > 0 {
+			w.text(content[pos : pos+next])
+		}
+		pos += next + 1
+		end := pos
+		for end < len(content) && isMarkerChar(content[end], end == pos) {
+			end++
+		}
+		marker := content[pos:end]
+		closing := end < len(content) && content[end] == '*'
+		if closing {
+			end++
+		} else if end < len(content) && (content[end] == ' ' || content[end] == '\n' || content[end] == '\r') {
+			end++
+			if content[end-1] == '\r' && end < len(content) && content[end] == '\n' {
+				end++
+			}
+		}
+		pos = end
+		w.marker(strings.TrimPrefix(marker, `+`), closing)
+	}
+	w.closeTo(0)
+	w.buf.WriteString(`</usx>`)
+	return w.buf.Bytes()
+}
+
+func isMarkerChar(c byte, first bool) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '-' ||
+		(first && c == '+')
+}
+
+func (w *usfmWriter) marker(marker string, closing bool) {
+	if marker == `` {
+		return
+	}
+	if strings.HasSuffix(marker, `-s`) || strings.HasSuffix(marker, `-e`) || marker == `ts` {
+		return // milestones have no text
+	}
+	if w.inNote() && !closing && marker != `f` && marker != `fe` && marker != `x` && marker != `ef` && marker != `ex` {
+		return // markers inside notes are dropped, and their text kept
+	}
+	if closing {
+		w.closeMarker(marker)
+		return
+	}
+	switch w.kind(marker) {
+	case `book`:
+		w.closeTo(0)
+		w.pending = `id`
+	case `chapter`:
+		w.closeTo(0)
+		w.pending = `c`
+	case `verse`:
+		w.closeChars()
+		w.pending = `v`
+	case `para`:
+		w.closeTo(w.sidebarDepth())
+		w.start(`para`, marker)
+	case `row`:
+		w.closeTo(w.sidebarDepth())
+		w.start(`row`, marker)
+	case `cell`:
+		for len(w.open) > 0 && w.open[len(w.open)-1].tag != `row` {
+			w.closeTo(len(w.open) - 1)
+		}
+		w.start(`cell`, marker)
+	case `sidebar`:
+		w.closeTo(0)
+		w.start(`sidebar`, marker)
+	case `sidebar_end`:
+		w.closeMarker(`esb`)
+	default: // char, note, figure
+		w.start(w.kind(marker), marker)
+	}
+}
+
+// kind finds the USX element of a marker, using the styles in usfm_styles.go
+func (w *usfmWriter) kind(marker string) string {
+	base := strings.TrimRight(marker, `0123456789`)
+	switch base {
+	case `id`:
+		return `book`
+	case `c`:
+		return `chapter`
+	case `v`:
+		return `verse`
+	case `tr`:
+		return `row`
+	case `th`, `thr`, `tc`, `tcr`:
+		return `cell`
+	case `esb`:
+		return `sidebar`
+	case `esbe`:
+		return `sidebar_end`
+	case `fig`:
+		return `figure`
+	}
+	for _, tag := range []string{`char`, `note`, `para`} {
+		if _, ok := usfm[tag+`.`+marker]; ok {
+			return tag
+		}
+		if _, ok := usfm[tag+`.`+base]; ok {
+			return tag
+		}
+	}
+	return `para`
+}
+
+func (w *usfmWriter) text(text string) {
+	switch w.pending {
+	case `id`:
+		code, rest := splitFirst(text)
+		w.buf.WriteString(`<book code="` + escapeXML(code) + `" style="id">` + escapeXML(rest) + `</book>`)
+		w.pending = ``
+		return
+	case `c`:
+		num, rest := splitFirst(text)
+		w.buf.WriteString(`<chapter number="` + escapeXML(num) + `" style="c"/>`)
+		text = rest
+	case `v`:
+		num, rest := splitFirst(text)
+		w.buf.WriteString(`<verse number="` + escapeXML(num) + `" style="v"/>`)
+		text = rest
+	}
+	w.pending = ``
+	text = strings.ReplaceAll(text, "\r\n", ` `)
+	text = strings.ReplaceAll(text, "\n", ` `)
+	if idx := strings.IndexByte(text, '|'); idx >= 0 && len(w.open) > 0 && w.open[len(w.open)-1].tag == `char` {
+		text = text[:idx] // attributes, such as \w gracious|lemma="grace"\w*
+	}
+	w.buf.WriteString(escapeXML(text))
+}
+
+func (w *usfmWriter) start(tag string, marker string) {
+	w.buf.WriteString(`<` + tag + ` style="` + escapeXML(marker) + `">`)
+	w.open = append(w.open, usfmElement{tag: tag, marker: marker})
+}
+
+// closeTo closes open elements until depth remain
+func (w *usfmWriter) closeTo(depth int) {
+	for len(w.open) > depth {
+		last := w.open[len(w.open)-1]
+		if last.tag == `para` || last.tag == `cell` {
+			w.buf.Truncate(len(bytes.TrimRight(w.buf.Bytes(), ` `))) // the line end before the next marker
+		}
+		w.buf.WriteString(`</` + last.tag + `>`)
+		w.open = w.open[:len(w.open)-1]
+	}
+}
+
+// closeMarker closes the most recent element of marker, and any opened inside of it
+func (w *usfmWriter) closeMarker(marker string) {
+	for i := len(w.open) - 1; i >= 0; i-- {
+		if w.open[i].marker == marker {
+			w.closeTo(i)
+			return
+		}
+	}
+}
+
+// closeChars closes character styles and notes, that are not closed before a verse
+func (w *usfmWriter) closeChars() {
+	for len(w.open) > 0 {
+		tag := w.open[len(w.open)-1].tag
+		if tag != `char` && tag != `note` && tag != `figure` {
+			return
+		}
+		w.closeTo(len(w.open) - 1)
+	}
+}
+
+func (w *usfmWriter) sidebarDepth() int {
+	for i := len(w.open) - 1; i >= 0; i-- {
+		if w.open[i].tag == `sidebar` {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+func (w *usfmWriter) inNote() bool {
+	for _, elem := range w.open {
+		if elem.tag == `note` || elem.tag == `figure` {
+			return true
+		}
+	}
+	return false
+}
+
+func splitFirst(text string) (string, string) {
+	text = strings.TrimLeft(text, " \t\r\n")
+	idx := strings.IndexAny(text, " \t\r\n")
+	if idx < 0 {
+		return text, ``
+	}
+	return text[:idx], text[idx+1:]
+}
+
+func escapeXML(text string) string {
+	var buf bytes.Buffer
+	_ = xml.EscapeText(&buf, []byte(text))
+	return buf.String()
+}
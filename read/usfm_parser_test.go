@@ -0,0 +1,67 @@
+package read
+
+import (
+	"context"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/db"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/utility/safe"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testUSFM = `\id JUD World English Bible
+\h Jude
+\toc1 The Letter from Jude
+\mt1 The Letter from Jude
+\c 1
+\p
+\v 1 Jude,\f + \fr 1:1 \ft or, Judah\f* a servant of \nd Jesus Christ\nd*, and brother of James,
+\v 2 Mercy to you and peace and \w love|lemma="love"\w* be multiplied.
+\s1 Sin and Punishment
+\q1
+\v 3 Beloved,\x - \xo 1:3 \xt Gal 1:6\x* while I was very eager to write to you,
+\q2 I was constrained to write to you.
+`
+
+const testUSX = `<?xml version="1.0" encoding="utf-8"?>
+<usx version="3.0"><book code="JUD" style="id">World English Bible</book>
+<para style="h">Jude</para>
+<para style="toc1">The Letter from Jude</para>
+<para style="mt1">The Letter from Jude</para>
+<chapter number="1" style="c"/>
+<para style="p"><verse number="1" style="v"/>Jude,<note caller="+" style="f"><char style="fr">1:1 </char><char style="ft">or, Judah</char></note> a servant of <char style="nd">Jesus Christ</char>, and brother of James, <verse number="2" style="v"/>Mercy to you and peace and <char style="w" lemma="love">love</char> be multiplied. </para>
+<para style="s1">Sin and Punishment</para>
+<para style="q1"> <verse number="3" style="v"/>Beloved,<note caller="-" style="x"><char style="xo">1:3 </char><char style="xt">Gal 1:6</char></note> while I was very eager to write to you, </para>
+<para style="q2">I was constrained to write to you. </para>
+</usx>`
+
+func TestUSFMToUSX(t *testing.T) {
+	ctx := context.Background()
+	var parser = NewUSXParser(db.DBAdapter{Ctx: ctx})
+	fromUSFM, titles1, status := parser.decodeReader(ctx, strings.NewReader(string(USFMToUSX(testUSFM))))
+	if status != nil {
+		t.Fatal(status)
+	}
+	fromUSX, titles2, status := parser.decodeReader(ctx, strings.NewReader(testUSX))
+	if status != nil {
+		t.Fatal(status)
+	}
+	if len(fromUSFM) != 3 {
+		t.Error(`Expected 3 scripts, got`, len(fromUSFM), fromUSFM)
+	}
+	if !reflect.DeepEqual(titles1, titles2) {
+		t.Error(`Titles differ`, titles1, titles2)
+	}
+	usfm := parser.correctScriptNum(parser.addChapterHeading(fromUSFM, titles1))
+	usx := parser.correctScriptNum(parser.addChapterHeading(fromUSX, titles2))
+	if len(usfm) != len(usx) {
+		t.Fatal(`Expected`, len(usx), `scripts, got`, len(usfm))
+	}
+	for i := range usx {
+		text1 := strings.Join(strings.Fields(safe.SafeStringJoin(usfm[i].ScriptTexts)), ` `)
+		text2 := strings.Join(strings.Fields(safe.SafeStringJoin(usx[i].ScriptTexts)), ` `)
+		if text1 != text2 || usfm[i].VerseStr != usx[i].VerseStr || usfm[i].UsfmStyle != usx[i].UsfmStyle {
+			t.Error(`Script differs`, usfm[i], usx[i])
+		}
+	}
+}
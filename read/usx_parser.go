@@ -55,14 +55,20 @@ func (p *USXParser) ProcessFiles(inputFiles []input.InputFile) *log.Status {
 }
 
 func (p *USXParser) decode(ctx context.Context, filename string) ([]db.Script, titleDesc, *log.Status) {
-	var records []db.Script
-	var titles titleDesc
-	var status *log.Status
 	xmlFile, err := os.Open(filename)
 	if err != nil {
-		return records, titles, log.Error(ctx, 500, err, "USXParser could not open USX File.")
+		return nil, titleDesc{}, log.Error(ctx, 500, err, "USXParser could not open USX File.")
 	}
 	defer xmlFile.Close()
+	return p.decodeReader(ctx, xmlFile)
+}
+
+// decodeReader parses USX, it is also used by USFMParser after converting USFM to USX.
+func (p *USXParser) decodeReader(ctx context.Context, reader io.Reader) ([]db.Script, titleDesc, *log.Status) {
+	var records []db.Script
+	var titles titleDesc
+	var status *log.Status
+	var err error
 	var stack Stack
 	var rec db.Script
 	var tagName string
@@ -71,7 +77,7 @@ func (p *USXParser) decode(ctx context.Context, filename string) ([]db.Script, t
 	var verseNum int
 	var verseStr = `0`
 	var usfmStyle string
-	decoder := xml.NewDecoder(xmlFile)
+	decoder := xml.NewDecoder(reader)
 	for {
 		var token xml.Token
 		token, err = decoder.Token()
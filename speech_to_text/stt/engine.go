@@ -0,0 +1,83 @@
+package stt
+
+import (
+	"context"
+	"sort"
+
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/db"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/decode_yaml/request"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/input"
+	log "github.com/faithcomesbyhearing/fcbh-dataset-io/logger"
+)
+
+// Engine is a speech to text engine.  It writes the text that it recognizes in each file to the database.
+type Engine interface {
+	ProcessFiles(files []input.InputFile) *log.Status
+}
+
+// Config is what an engine is given when it is created for a request.
+type Config struct {
+	Ctx          context.Context
+	Conn         db.DBAdapter
+	BibleId      string
+	LanguageISO  string
+	AltLanguage  string
+	Workers      int
+	SpeechToText request.SpeechToText
+}
+
+// Info describes an engine, so that the request validation, the controller and the reports
+// can use any engine that is registered without knowing it.
+type Info struct {
+	Name            string   // name used in speech_to_text: engine: of a request
+	Title           string   // model name shown in reports
+	LanguageSearch  string   // language tree search of the languages supported, empty if trained for a language
+	NeedsTimestamps bool     // transcribes the verses at their timestamps, rather than whole chapters
+	CreatesText     bool     // its text becomes the text of the dataset, rather than being compared to it
+	Executables     []string // environment variables that name the executables it runs
+	Selected        func(req request.SpeechToText) bool
+	New             func(cfg Config) Engine
+}
+
+var engines = make(map[string]Info)
+
+// Register adds an engine.  It is called from an init function.
+func Register(info Info) {
+	engines[info.Name] = info
+}
+
+// Find returns the engine selected by a request, either by name or by its own field.
+func Find(req request.SpeechToText) (Info, bool) {
+	if req.Engine != `` {
+		info, ok := engines[req.Engine]
+		return info, ok
+	}
+	for _, name := range Names() {
+		info := engines[name]
+		if info.Selected != nil && info.Selected(req) {
+			return info, true
+		}
+	}
+	return Info{}, false
+}
+
+// NeedsTimestamps returns the names of the engines that need timestamps, in sorted order.
+func NeedsTimestamps() []string {
+	var names []string
+	for _, name := range Names() {
+		if engines[name].NeedsTimestamps {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Names returns the names of all registered engines in sorted order.
+func Names() []string {
+	var names []string
+	for name := range engines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,36 @@
+package stt
+
+import (
+	"testing"
+
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/decode_yaml/request"
+)
+
+func TestFind(t *testing.T) {
+	var req request.SpeechToText
+	req.MMSAdapter = true
+	info, ok := Find(req)
+	if !ok || info.Name != `adapter_asr` {
+		t.Error("Expected adapter_asr, found", info.Name, ok)
+	}
+	req = request.SpeechToText{}
+	req.Whisper.Model.Small = true
+	info, ok = Find(req)
+	if !ok || !info.CreatesText {
+		t.Error("Expected whisper, found", info.Name, ok)
+	}
+	req = request.SpeechToText{Engine: `mms_asr`}
+	info, ok = Find(req)
+	if !ok || info.Title != `MMS` {
+		t.Error("Expected mms_asr, found", info.Name, ok)
+	}
+	req = request.SpeechToText{Engine: `vosk`}
+	_, ok = Find(req)
+	if ok {
+		t.Error("Expected vosk not to be registered")
+	}
+	_, ok = Find(request.SpeechToText{NoSpeechToText: true})
+	if ok {
+		t.Error("Expected no engine when there is no speech to text")
+	}
+}
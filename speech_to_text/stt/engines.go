@@ -0,0 +1,81 @@
+package stt
+
+import (
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/decode_yaml/request"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/mms/asr_align"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/mms/mms_asr"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/speech_to_text"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/utility/lang_tree/search"
+	asr2 "github.com/faithcomesbyhearing/fcbh-dataset-io/wav2vec2/asr"
+)
+
+// defaultWhisperModel is used when whisper is selected by engine name, without a model
+const defaultWhisperModel = `medium`
+
+// The engines that are part of this project.  Another engine can be added by a Register call here,
+// or in an init function of its own package, when that package is imported here.
+func init() {
+	Register(Info{
+		Name:            `mms_asr`,
+		Title:           `MMS`,
+		LanguageSearch:  search.MMSASR,
+		NeedsTimestamps: true,
+		Executables:     []string{`FCBH_MMS_ASR_PYTHON`, `FCBH_MMS_FA_PYTHON`},
+		Selected:        func(req request.SpeechToText) bool { return req.MMS },
+		New: func(cfg Config) Engine {
+			asr := mms_asr.NewMMSASR(cfg.Ctx, cfg.Conn, cfg.LanguageISO, cfg.AltLanguage, false)
+			asr.SetWorkers(cfg.Workers)
+			return &asr
+		},
+	})
+	Register(Info{
+		Name:            `adapter_asr`,
+		Title:           `MMS Adapter`,
+		NeedsTimestamps: true,
+		Executables:     []string{`FCBH_MMS_ASR_PYTHON`, `FCBH_MMS_FA_PYTHON`},
+		Selected:        func(req request.SpeechToText) bool { return req.MMSAdapter },
+		New: func(cfg Config) Engine {
+			asr := mms_asr.NewMMSASR(cfg.Ctx, cfg.Conn, cfg.LanguageISO, cfg.AltLanguage, true)
+			asr.SetWorkers(cfg.Workers)
+			return &asr
+		},
+	})
+	Register(Info{
+		Name:        `wav2vec2_asr`,
+		Title:       `Wav2Vec2 Word`,
+		Executables: []string{`FCBH_MMS_ASR_PYTHON`, `FCBH_MMS_FA_PYTHON`},
+		Selected:    func(req request.SpeechToText) bool { return req.Wav2Vec2ASR },
+		New: func(cfg Config) Engine {
+			asr := asr2.NewWav2Vec2ASR(cfg.Ctx, cfg.Conn, cfg.LanguageISO, cfg.AltLanguage)
+			return &asr
+		},
+	})
+	Register(Info{
+		Name:           `mms_asr_align`,
+		Title:          `MMS ASR Align`,
+		LanguageSearch: search.MMSASR,
+		Executables:    []string{`FCBH_MMS_ASR_PYTHON`, `FCBH_MMS_FA_PYTHON`},
+		Selected:       func(req request.SpeechToText) bool { return req.MMSASRAlign },
+		New: func(cfg Config) Engine {
+			asr := asr_align.NewASRAlign(cfg.Ctx, cfg.Conn, cfg.LanguageISO, cfg.AltLanguage, false)
+			return &asr
+		},
+	})
+	Register(Info{
+		Name:           `whisper`,
+		Title:          `Whisper`,
+		LanguageSearch: search.Whisper,
+		CreatesText:    true,
+		Executables:    []string{`FCBH_WHISPER_EXE`},
+		Selected:       func(req request.SpeechToText) bool { return req.Whisper.Model.String() != `` },
+		New: func(cfg Config) Engine {
+			model := cfg.SpeechToText.Whisper.Model.String()
+			if model == `` {
+				model = defaultWhisperModel
+			}
+			whisper := speech_to_text.NewWhisper(cfg.Ctx, cfg.BibleId, cfg.Conn, model, cfg.AltLanguage)
+			whisper.SetWorkers(cfg.Workers)
+			return &whisper
+		},
+	})
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/faithcomesbyhearing/fcbh-dataset-io/input"
 	log "github.com/faithcomesbyhearing/fcbh-dataset-io/logger"
 	"github.com/faithcomesbyhearing/fcbh-dataset-io/utility/lang_tree/search"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/utility/worker_pool"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -31,21 +32,29 @@ type Whisper struct {
 	model   string
 	lang2   string // 2 char language code
 	tempDir string
+	workers int
 }
 
-func NewWhisper(bibleId string, conn db.DBAdapter, model string, lang2 string) Whisper {
+func NewWhisper(ctx context.Context, bibleId string, conn db.DBAdapter, model string, lang2 string) Whisper {
 	var w Whisper
-	w.ctx = conn.Ctx
+	w.ctx = ctx
 	w.conn = conn
 	w.bibleId = bibleId
 	w.model = model
 	w.lang2 = lang2
+	w.workers = 1
 	return w
 }
 
+// SetWorkers sets the number of files that are transcribed concurrently, each by its own whisper process.
+func (w *Whisper) SetWorkers(workers int) {
+	if workers > 0 {
+		w.workers = workers
+	}
+}
+
 func (w *Whisper) ProcessFiles(files []input.InputFile) *log.Status {
 	var status *log.Status
-	var outputFile string
 	var err error
 	w.tempDir, err = os.MkdirTemp(os.Getenv(`FCBH_DATASET_TMP`), "Whisper_")
 	if err != nil {
@@ -69,45 +78,61 @@ func (w *Whisper) ProcessFiles(files []input.InputFile) *log.Status {
 			return log.ErrorNoErr(w.ctx, 400, `No compatible language code was found for`, w.bibleId)
 		}
 	}
-	for _, file := range files {
-		fmt.Println(`INPUT FILE:`, file)
-		var timestamps []db.Timestamp
-		timestamps, status = w.conn.SelectScriptTimestamps(file.BookId, file.Chapter)
-		if status != nil {
-			return status
-		}
-		status = w.conn.DeleteScripts(file.BookId, file.Chapter)
-		if status != nil {
-			return status
-		}
-		var records []db.Script
-		if len(timestamps) > 0 {
-			timestamps, status = w.ChopByTimestamp(file, timestamps)
-			if status != nil {
-				return status
+	status = worker_pool.Run(w.ctx, w.workers, files,
+		func(worker int, file input.InputFile) ([]db.Script, *log.Status) {
+			if w.ctx.Err() != nil {
+				return nil, log.ContextError(w.ctx, "Whisper", file.BookId, file.Chapter)
 			}
-			for pieceNum, piece := range timestamps {
-				fmt.Println(`VERSE PIECE:`, piece)
-				inputFile := filepath.Join(w.tempDir, piece.AudioFile)
-				outputFile, status = w.RunWhisper(inputFile)
-				var rec db.Script
-				rec, status = w.loadWhisperVerses(outputFile, file, pieceNum, piece)
-				records = append(records, rec)
+			return w.processFile(file)
+		},
+		func(file input.InputFile, records []db.Script) *log.Status {
+			status2 := w.conn.DeleteScripts(file.BookId, file.Chapter)
+			if status2 != nil {
+				return status2
 			}
-		} else {
-			outputFile, status = w.RunWhisper(file.FilePath())
-			records, status = w.loadWhisperOutput(outputFile, file)
+			w.conn.InsertScripts(records)
+			input.FileDone(w.ctx, file)
+			return nil
+		})
+	return status
+}
+
+// processFile transcribes one file, by verse when it has timestamps, and returns the scripts
+// that replace the scripts of its chapter.
+func (w *Whisper) processFile(file input.InputFile) ([]db.Script, *log.Status) {
+	var status *log.Status
+	var outputFile string
+	fmt.Println(`INPUT FILE:`, file)
+	var timestamps []db.Timestamp
+	timestamps, status = w.conn.SelectScriptTimestamps(file.BookId, file.Chapter)
+	if status != nil {
+		return nil, status
+	}
+	var records []db.Script
+	if len(timestamps) > 0 {
+		timestamps, status = w.ChopByTimestamp(file, timestamps)
+		if status != nil {
+			return nil, status
 		}
-		w.conn.InsertScripts(records)
-		records = nil
+		for pieceNum, piece := range timestamps {
+			fmt.Println(`VERSE PIECE:`, piece)
+			inputFile := filepath.Join(w.tempDir, piece.AudioFile)
+			outputFile, status = w.RunWhisper(inputFile)
+			var rec db.Script
+			rec, status = w.loadWhisperVerses(outputFile, file, pieceNum, piece)
+			records = append(records, rec)
+		}
+	} else {
+		outputFile, status = w.RunWhisper(file.FilePath())
+		records, status = w.loadWhisperOutput(outputFile, file)
 	}
-	return status
+	return records, status
 }
 
 func (w *Whisper) RunWhisper(audioFile string) (string, *log.Status) {
 	var status *log.Status
 	whisperPath := os.Getenv(`FCBH_WHISPER_EXE`)
-	cmd := exec.Command(whisperPath,
+	cmd := exec.CommandContext(w.ctx, whisperPath,
 		audioFile,
 		`--model`, w.model,
 		`--output_format`, `json`,
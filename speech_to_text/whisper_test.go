@@ -21,7 +21,7 @@ func TestWhisper(t *testing.T) {
 	var database = bibleId + `_WHISPER.db`
 	db.DestroyDatabase(database)
 	conn := db.NewDBAdapter(ctx, database)
-	var whisp = NewWhisper(bibleId, conn, `tiny`, `en`)
+	var whisp = NewWhisper(ctx, bibleId, conn, `tiny`, `en`)
 	status = whisp.ProcessFiles(files)
 
 	if status != nil {
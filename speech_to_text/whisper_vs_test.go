@@ -80,7 +80,7 @@ func TestWhisperVs(t *testing.T) {
 		if status != nil {
 			t.Fatal(status)
 		}
-		var whisp = NewWhisper(tst.bibleId, newConn, `tiny`, tst.lang2)
+		var whisp = NewWhisper(ctx, tst.bibleId, newConn, `tiny`, tst.lang2)
 		status = whisp.ProcessFiles(files)
 		if status != nil {
 			t.Fatal(status)
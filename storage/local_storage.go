@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	log "github.com/faithcomesbyhearing/fcbh-dataset-io/logger"
+)
+
+// LocalStorage keeps each object as a file, whose path beneath the directory is its key.
+type LocalStorage struct {
+	directory string
+}
+
+func NewLocalStorage(ctx context.Context, directory string) (*LocalStorage, *log.Status) {
+	var s LocalStorage
+	s.directory = directory
+	err := os.MkdirAll(directory, 0755)
+	if err != nil {
+		return &s, log.Error(ctx, 500, err, "Error creating storage directory", directory)
+	}
+	return &s, nil
+}
+
+func (s *LocalStorage) Name() string {
+	return s.directory
+}
+
+func (s *LocalStorage) List(ctx context.Context, prefix string) ([]Object, *log.Status) {
+	var objects []Object
+	// Only the directory of the prefix is walked, e.g. a/b for a/b/c
+	start := s.directory
+	if strings.Contains(prefix, `/`) {
+		start = filepath.Join(s.directory, filepath.FromSlash(prefix[:strings.LastIndex(prefix, `/`)]))
+	}
+	err := filepath.WalkDir(start, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if entry.IsDir() || strings.HasSuffix(path, `.part`) {
+			return nil
+		}
+		rel, err := filepath.Rel(s.directory, path)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil // removed while listing
+		}
+		objects = append(objects, Object{Key: key, Size: info.Size()})
+		return nil
+	})
+	if err != nil {
+		return objects, log.Error(ctx, 500, err, "Error listing storage", s.directory, prefix)
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return objects, nil
+}
+
+func (s *LocalStorage) Download(ctx context.Context, key string, filePath string) *log.Status {
+	source, status := s.path(ctx, key)
+	if status != nil {
+		return status
+	}
+	file, err := os.Open(source)
+	if err != nil {
+		return log.Error(ctx, 400, err, `Failed to get object`, key)
+	}
+	defer file.Close()
+	return writeFile(ctx, filePath, file)
+}
+
+func (s *LocalStorage) Upload(ctx context.Context, key string, body io.Reader) *log.Status {
+	target, status := s.path(ctx, key)
+	if status != nil {
+		return status
+	}
+	err := os.MkdirAll(filepath.Dir(target), 0755)
+	if err != nil {
+		return log.Error(ctx, 500, err, "Error creating storage directory for", key)
+	}
+	return writeFile(ctx, target, body)
+}
+
+// path returns the file of a key, which must not be outside of the directory
+func (s *LocalStorage) path(ctx context.Context, key string) (string, *log.Status) {
+	clean := filepath.Clean(filepath.FromSlash(key))
+	if clean == `.` || filepath.IsAbs(clean) || strings.HasPrefix(clean, `..`) {
+		return ``, log.ErrorNoErr(ctx, 400, `Invalid storage key`, key)
+	}
+	return filepath.Join(s.directory, clean), nil
+}
+
+// writeFile writes filePath.part, and renames it, so that a failed copy does not leave a partial file
+func writeFile(ctx context.Context, filePath string, body io.Reader) *log.Status {
+	file, err := os.Create(filePath + `.part`)
+	if err != nil {
+		return log.Error(ctx, 400, err, `Failed to create file`, filePath)
+	}
+	_, err = io.Copy(file, body)
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(filePath+`.part`, filePath)
+	}
+	if err != nil {
+		_ = os.Remove(filePath + `.part`)
+		return log.Error(ctx, 400, err, `Failed to copy object to`, filePath)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalStorage(t *testing.T) {
+	ctx := context.Background()
+	directory := t.TempDir()
+	store, status := NewStorage(ctx, `file://`+directory)
+	if status != nil {
+		t.Fatal(status)
+	}
+	if store.Name() != directory {
+		t.Error(`Name should be`, directory, `it is`, store.Name())
+	}
+	for _, key := range []string{`GaryNTest/MyProject/00001/request/MyProject.yaml`,
+		`GaryNTest/MyProject/00001/output/MyProject.csv`,
+		`GaryNTest/MyProjectB/00001/output/MyProjectB.csv`} {
+		status = store.Upload(ctx, key, strings.NewReader(key))
+		if status != nil {
+			t.Fatal(status)
+		}
+	}
+	objects, status := store.List(ctx, `GaryNTest/MyProject/`)
+	if status != nil {
+		t.Fatal(status)
+	}
+	if len(objects) != 2 || objects[0].Key != `GaryNTest/MyProject/00001/output/MyProject.csv` {
+		t.Error(`Unexpected objects`, objects)
+	}
+	objects, _ = store.List(ctx, `GaryNTest/MyProj`)
+	if len(objects) != 3 {
+		t.Error(`Prefix should match 3 objects`, objects)
+	}
+	objects, _ = store.List(ctx, `Nobody/`)
+	if len(objects) != 0 {
+		t.Error(`Unexpected objects`, objects)
+	}
+	filePath := filepath.Join(t.TempDir(), `MyProject.yaml`)
+	status = store.Download(ctx, `GaryNTest/MyProject/00001/request/MyProject.yaml`, filePath)
+	if status != nil {
+		t.Fatal(status)
+	}
+	content, _ := os.ReadFile(filePath)
+	if string(content) != `GaryNTest/MyProject/00001/request/MyProject.yaml` {
+		t.Error(`Unexpected content`, string(content))
+	}
+	status = store.Upload(ctx, `../outside`, strings.NewReader(``))
+	if status == nil {
+		t.Error(`A key outside of the directory was accepted`)
+	}
+}
+
+func TestNewStorage(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	t.Setenv(`FCBH_S3_LOCAL_ROOT`, root)
+	store, status := NewStorage(ctx, `s3://dbp-prod/`)
+	if status != nil {
+		t.Fatal(status)
+	}
+	if store.Name() != filepath.Join(root, `dbp-prod`) {
+		t.Error(`Bucket should be a directory in FCBH_S3_LOCAL_ROOT`, store.Name())
+	}
+	t.Setenv(`FCBH_S3_LOCAL_ROOT`, ``)
+	store, status = NewStorage(ctx, `dataset-io`)
+	if status != nil {
+		t.Fatal(status)
+	}
+	if _, ok := store.(*S3Storage); !ok || store.Name() != `s3://dataset-io` {
+		t.Error(`Expected an S3Storage`, store.Name())
+	}
+	_, status = NewStorage(ctx, ``)
+	if status == nil {
+		t.Error(`An empty location was accepted`)
+	}
+}
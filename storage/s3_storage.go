@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	log "github.com/faithcomesbyhearing/fcbh-dataset-io/logger"
+)
+
+// S3Storage is an S3 bucket.  The region is taken from AWS_REGION, and defaults to us-west-2.
+// When FCBH_S3_ENDPOINT is set, it is used instead of AWS, with path style addressing,
+// which MinIO and most S3 compatible stores expect.
+type S3Storage struct {
+	client *s3.Client
+	bucket string
+}
+
+func NewS3Storage(ctx context.Context, bucket string) (*S3Storage, *log.Status) {
+	var s S3Storage
+	s.bucket = bucket
+	region := os.Getenv("AWS_REGION")
+	if region == `` {
+		region = "us-west-2"
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
+	if err != nil {
+		return &s, log.Error(ctx, 400, err, `Failed to load AWS configuration`)
+	}
+	endpoint := os.Getenv(`FCBH_S3_ENDPOINT`)
+	s.client = s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if endpoint != `` {
+			o.BaseEndpoint = aws.String(endpoint)
+			o.UsePathStyle = true
+		}
+	})
+	return &s, nil
+}
+
+func (s *S3Storage) Name() string {
+	return "s3://" + s.bucket
+}
+
+func (s *S3Storage) List(ctx context.Context, prefix string) ([]Object, *log.Status) {
+	var objects []Object
+	paginator := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(prefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return objects, log.Error(ctx, 400, err, `Failed to list objects of`, s.Name(), prefix)
+		}
+		for _, object := range page.Contents {
+			objects = append(objects, Object{Key: aws.ToString(object.Key), Size: aws.ToInt64(object.Size)})
+		}
+	}
+	return objects, nil
+}
+
+func (s *S3Storage) Download(ctx context.Context, key string, filePath string) *log.Status {
+	response, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return log.Error(ctx, 400, err, `Failed to get object`, key)
+	}
+	defer response.Body.Close()
+	return writeFile(ctx, filePath, response.Body)
+}
+
+func (s *S3Storage) Upload(ctx context.Context, key string, body io.Reader) *log.Status {
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+		Body:   body,
+	})
+	if err != nil {
+		return log.Error(ctx, 500, err, "Error uploading to", s.Name(), key)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	log "github.com/faithcomesbyhearing/fcbh-dataset-io/logger"
+)
+
+/*
+Storage is a bucket of objects, which is either an S3 bucket, or a local directory.  It keeps
+the run history of Courier, and it is the source of database.aws_s3, audio_data.aws_s3 and
+text_data.aws_s3.  Keys are slash separated, e.g. {username}/{dataset}/{run}/output/{file},
+in both implementations, so that a local directory has the same layout as a bucket.
+
+FCBH_S3_ENDPOINT sets the endpoint of an S3 compatible store, e.g. http://localhost:9000
+for MinIO.  FCBH_S3_LOCAL_ROOT keeps every bucket as a directory beneath it, e.g. for tests.
+*/
+
+type Object struct {
+	Key  string
+	Size int64
+}
+
+type Storage interface {
+	// Name identifies the storage in log messages, e.g. s3://bucket or a directory
+	Name() string
+	// List returns the objects whose keys begin with prefix
+	List(ctx context.Context, prefix string) ([]Object, *log.Status)
+	// Download saves an object to filePath
+	Download(ctx context.Context, key string, filePath string) *log.Status
+	// Upload saves the content of body as an object
+	Upload(ctx context.Context, key string, body io.Reader) *log.Status
+}
+
+// NewStorage returns a LocalStorage when location is a directory path or file:// url,
+// and otherwise an S3Storage, which uses it as a bucket name.  When FCBH_S3_LOCAL_ROOT is set,
+// a bucket is a LocalStorage of the directory of that name in it.
+func NewStorage(ctx context.Context, location string) (Storage, *log.Status) {
+	if strings.HasPrefix(location, `file://`) {
+		return NewLocalStorage(ctx, strings.TrimPrefix(location, `file://`))
+	}
+	if strings.HasPrefix(location, `/`) || strings.HasPrefix(location, `.`) {
+		return NewLocalStorage(ctx, location)
+	}
+	bucket := strings.Trim(strings.TrimPrefix(location, `s3://`), `/`)
+	if bucket == `` {
+		return nil, log.ErrorNoErr(ctx, 400, `Storage location is empty`)
+	}
+	localRoot := os.Getenv(`FCBH_S3_LOCAL_ROOT`)
+	if localRoot != `` {
+		return NewLocalStorage(ctx, filepath.Join(localRoot, bucket))
+	}
+	return NewS3Storage(ctx, bucket)
+}
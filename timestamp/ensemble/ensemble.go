@@ -0,0 +1,239 @@
+package ensemble
+
+import (
+	"context"
+	"sort"
+	"strconv"
+
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/db"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/decode_yaml/request"
+	log "github.com/faithcomesbyhearing/fcbh-dataset-io/logger"
+)
+
+// DefaultThreshold is the disagreement in seconds at which a verse boundary is flagged
+const DefaultThreshold = 0.5
+
+// Verse is the comparison of the timestamps of one verse across the timestamp sets.
+// HasBegin and HasEnd are false for a set that has no such timestamp, and it is not counted.
+type Verse struct {
+	BookId      string
+	ChapterNum  int
+	VerseStr    string
+	ScriptId    int     // script_id in the dataset of the request
+	BeginTS     float64 // timestamps of the dataset of the request, kept where there is no median
+	EndTS       float64
+	Begins      []float64
+	Ends        []float64
+	HasBegin    []bool
+	HasEnd      []bool
+	BeginDelta  float64 // largest minus smallest begin timestamp
+	EndDelta    float64 // largest minus smallest end timestamp
+	MedianBegin float64
+	MedianEnd   float64
+	Flagged     bool
+}
+
+// Ensemble compares the script timestamps of two or more datasets, verse by verse.
+type Ensemble struct {
+	ctx      context.Context
+	conn     db.DBAdapter // dataset of the request, which receives the consensus timestamps
+	username string
+	config   request.TSEnsemble
+}
+
+func NewEnsemble(ctx context.Context, conn db.DBAdapter, username string, config request.TSEnsemble) Ensemble {
+	var e Ensemble
+	e.ctx = ctx
+	e.conn = conn
+	e.username = username
+	e.config = config
+	if e.config.Threshold == 0.0 {
+		e.config.Threshold = DefaultThreshold
+	}
+	return e
+}
+
+// Process compares the timestamp sets, and when consensus is requested, writes the median
+// timestamps of each verse that is in two or more sets to the dataset of the request.
+func (e *Ensemble) Process() ([]Verse, *log.Status) {
+	var verses []Verse
+	var sets []db.DBAdapter
+	for _, name := range e.config.Datasets {
+		if name == e.conn.Project {
+			sets = append(sets, e.conn)
+			continue
+		}
+		if !db.DatabaseExists(e.username, name) {
+			return verses, log.ErrorNoErr(e.ctx, 400, `Timestamp dataset does not exist:`, name)
+		}
+		conn, status := db.NewerDBAdapter(e.ctx, false, e.username, name)
+		if status != nil {
+			return verses, status
+		}
+		defer conn.Close()
+		sets = append(sets, conn)
+	}
+	verses, status := e.Compare(sets)
+	if status != nil {
+		return verses, status
+	}
+	if e.config.Consensus {
+		status = e.updateConsensus(verses)
+	}
+	return verses, status
+}
+
+// Compare compares the timestamps of each verse in the dataset of the request across the sets.
+func (e *Ensemble) Compare(sets []db.DBAdapter) ([]Verse, *log.Status) {
+	var results []Verse
+	chapters, status := e.conn.SelectBookChapter()
+	if status != nil {
+		return results, status
+	}
+	for _, chap := range chapters {
+		var verses []db.Timestamp
+		verses, status = e.conn.SelectScriptTimestamps(chap.BookId, chap.ChapterNum)
+		if status != nil {
+			return results, status
+		}
+		var setMaps []map[string]db.Timestamp
+		for _, set := range sets {
+			var timestamps []db.Timestamp
+			timestamps, status = set.SelectScriptTimestamps(chap.BookId, chap.ChapterNum)
+			if status != nil {
+				return results, status
+			}
+			var tsMap = make(map[string]db.Timestamp)
+			for i, key := range lineKeys(timestamps) {
+				tsMap[key] = timestamps[i]
+			}
+			setMaps = append(setMaps, tsMap)
+		}
+		keys := lineKeys(verses)
+		for i, vs := range verses {
+			var verse Verse
+			verse.BookId = chap.BookId
+			verse.ChapterNum = chap.ChapterNum
+			verse.VerseStr = vs.VerseStr
+			verse.ScriptId = vs.Id
+			verse.BeginTS = vs.BeginTS
+			verse.EndTS = vs.EndTS
+			for _, tsMap := range setMaps {
+				ts, ok := tsMap[keys[i]]
+				hasTS := ok && (ts.BeginTS > 0.0 || ts.EndTS > 0.0)
+				verse.Begins = append(verse.Begins, ts.BeginTS)
+				verse.Ends = append(verse.Ends, ts.EndTS)
+				verse.HasBegin = append(verse.HasBegin, hasTS)
+				verse.HasEnd = append(verse.HasEnd, hasTS && ts.EndTS > 0.0) // Bible Brain has no end for last verse
+			}
+			e.summarize(&verse)
+			results = append(results, verse)
+		}
+	}
+	return results, nil
+}
+
+// lineKeys returns the key of each timestamp of a chapter, which is its verse_str and its
+// occurrence of that verse_str.  Line-level datasets have several scripts with the same verse_str,
+// and datasets of the same text have them in the same order.
+func lineKeys(timestamps []db.Timestamp) []string {
+	var keys = make([]string, 0, len(timestamps))
+	var seen = make(map[string]int)
+	for _, ts := range timestamps {
+		seen[ts.VerseStr]++
+		keys = append(keys, ts.VerseStr+`#`+strconv.Itoa(seen[ts.VerseStr]))
+	}
+	return keys
+}
+
+func (e *Ensemble) summarize(verse *Verse) {
+	begins := present(verse.Begins, verse.HasBegin)
+	ends := present(verse.Ends, verse.HasEnd)
+	if len(begins) > 1 {
+		verse.BeginDelta = begins[len(begins)-1] - begins[0]
+	}
+	if len(ends) > 1 {
+		verse.EndDelta = ends[len(ends)-1] - ends[0]
+	}
+	verse.MedianBegin = median(begins)
+	verse.MedianEnd = median(ends)
+	verse.Flagged = verse.BeginDelta > e.config.Threshold || verse.EndDelta > e.config.Threshold
+}
+
+// Counts returns the number of verses compared in two or more sets, and the number flagged.
+func Counts(verses []Verse) (int, int) {
+	var compared, flagged int
+	for _, verse := range verses {
+		if len(present(verse.Begins, verse.HasBegin)) > 1 {
+			compared++
+		}
+		if verse.Flagged {
+			flagged++
+		}
+	}
+	return compared, flagged
+}
+
+// CheckGate returns an error when the percent of compared verses that are flagged exceeds max_flagged_pct
+func (e *Ensemble) CheckGate(verses []Verse) *log.Status {
+	if e.config.MaxFlaggedPct <= 0.0 {
+		return nil
+	}
+	compared, flagged := Counts(verses)
+	if compared == 0 {
+		return nil
+	}
+	pct := float64(flagged) * 100.0 / float64(compared)
+	if pct > e.config.MaxFlaggedPct {
+		return log.ErrorNoErr(e.ctx, 400, `Timestamp sets disagree on`, flagged, `of`, compared,
+			`verses, more than max_flagged_pct`, e.config.MaxFlaggedPct)
+	}
+	return nil
+}
+
+func (e *Ensemble) updateConsensus(verses []Verse) *log.Status {
+	var timestamps []db.Timestamp
+	for _, verse := range verses {
+		if len(present(verse.Begins, verse.HasBegin)) < 2 {
+			continue
+		}
+		var ts db.Timestamp
+		ts.Id = verse.ScriptId
+		ts.VerseStr = verse.VerseStr
+		ts.BeginTS = verse.BeginTS
+		ts.EndTS = verse.EndTS
+		if verse.MedianBegin > 0.0 {
+			ts.BeginTS = verse.MedianBegin
+		}
+		if verse.MedianEnd > 0.0 {
+			ts.EndTS = verse.MedianEnd
+		}
+		timestamps = append(timestamps, ts)
+	}
+	log.Info(e.ctx, "Update", len(timestamps), "verses with consensus timestamps.")
+	return e.conn.UpdateScriptBeginEndTS(timestamps)
+}
+
+// present returns the values of the sets that have them, in sorted order
+func present(values []float64, has []bool) []float64 {
+	var results []float64
+	for i, value := range values {
+		if has[i] {
+			results = append(results, value)
+		}
+	}
+	sort.Float64s(results)
+	return results
+}
+
+// median expects sorted values
+func median(values []float64) float64 {
+	size := len(values)
+	if size == 0 {
+		return 0.0
+	}
+	if size%2 == 1 {
+		return values[size/2]
+	}
+	return (values[size/2-1] + values[size/2]) / 2.0
+}
@@ -0,0 +1,93 @@
+package ensemble
+
+import (
+	"context"
+	"testing"
+
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/db"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/decode_yaml/request"
+)
+
+func testDataset(t *testing.T, ctx context.Context, begins []float64) db.DBAdapter {
+	conn := db.NewDBAdapter(ctx, ":memory:")
+	var scripts []db.Script
+	for i, begin := range begins {
+		var scp db.Script
+		scp.BookId = `TIT`
+		scp.ChapterNum = 1
+		scp.VerseStr = string(rune('1' + i))
+		scp.ScriptNum = scp.VerseStr
+		scp.ScriptText = `text`
+		scp.ScriptBeginTS = begin
+		if i < len(begins)-1 {
+			scp.ScriptEndTS = begins[i+1]
+		}
+		scripts = append(scripts, scp)
+	}
+	status := conn.InsertScripts(scripts)
+	if status != nil {
+		t.Fatal(status)
+	}
+	return conn
+}
+
+func TestCompare(t *testing.T) {
+	ctx := context.Background()
+	set1 := testDataset(t, ctx, []float64{0.0, 10.0, 20.0, 30.0})
+	set2 := testDataset(t, ctx, []float64{0.0, 10.2, 21.0, 30.1})
+	set3 := testDataset(t, ctx, []float64{0.0, 10.1, 20.1, 30.0})
+	target := testDataset(t, ctx, []float64{0.0, 0.0, 0.0, 0.0})
+	status := target.UpdateScriptBeginEndTS([]db.Timestamp{{Id: 4, BeginTS: 0.0, EndTS: 40.0}})
+	if status != nil {
+		t.Fatal(status)
+	}
+	var config request.TSEnsemble
+	config.Datasets = []string{`set1`, `set2`, `set3`}
+	config.MaxFlaggedPct = 25.0
+	ens := NewEnsemble(ctx, target, `test`, config)
+	verses, status := ens.Compare([]db.DBAdapter{set1, set2, set3})
+	if status != nil {
+		t.Fatal(status)
+	}
+	if len(verses) != 4 {
+		t.Fatal("Expected 4 verses, found", len(verses))
+	}
+	if !verses[2].Flagged || !verses[1].Flagged || verses[0].Flagged || verses[3].Flagged {
+		t.Error("Expected verses 2 and 3 to be flagged", verses)
+	}
+	if verses[2].MedianBegin != 20.1 {
+		t.Error("Expected median begin 20.1, found", verses[2].MedianBegin)
+	}
+	compared, flagged := Counts(verses)
+	if compared != 4 || flagged != 2 {
+		t.Error("Expected 4 compared, 2 flagged, found", compared, flagged)
+	}
+	if ens.CheckGate(verses) == nil {
+		t.Error("Expected 50% flagged to fail the gate")
+	}
+	status = ens.updateConsensus(verses)
+	if status != nil {
+		t.Fatal(status)
+	}
+	timestamps, status := target.SelectScriptTimestamps(`TIT`, 1)
+	if status != nil {
+		t.Fatal(status)
+	}
+	if timestamps[1].BeginTS != 10.1 || timestamps[1].EndTS != 20.1 {
+		t.Error("Expected consensus 10.1 to 20.1, found", timestamps[1])
+	}
+	if timestamps[3].BeginTS != 30.0 || timestamps[3].EndTS != 40.0 {
+		t.Error("Expected end 40.0 to be kept without a median end, found", timestamps[3])
+	}
+}
+
+func TestLineKeys(t *testing.T) {
+	timestamps := []db.Timestamp{{VerseStr: `1`}, {VerseStr: `1`}, {VerseStr: `2`}, {VerseStr: `1`}}
+	keys := lineKeys(timestamps)
+	expect := []string{`1#1`, `1#2`, `2#1`, `1#3`}
+	for i := range expect {
+		if keys[i] != expect[i] {
+			t.Error("Expected key", expect[i], "found", keys[i])
+		}
+	}
+}
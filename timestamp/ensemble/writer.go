@@ -0,0 +1,100 @@
+package ensemble
+
+import (
+	"encoding/csv"
+	"html"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	log "github.com/faithcomesbyhearing/fcbh-dataset-io/logger"
+)
+
+// WriteCSV writes every verse compared, with the timestamps of each set.
+func (e *Ensemble) WriteCSV(datasetName string, verses []Verse) (string, *log.Status) {
+	file, err := os.Create(filepath.Join(os.Getenv(`FCBH_DATASET_TMP`), datasetName+"_ts_ensemble.csv"))
+	if err != nil {
+		return "", log.Error(e.ctx, 500, err, `Error creating timestamp ensemble csv`)
+	}
+	defer file.Close()
+	writer := csv.NewWriter(file)
+	var header = []string{`book_id`, `chapter_num`, `verse_str`}
+	for _, name := range e.config.Datasets {
+		header = append(header, name+`_begin_ts`, name+`_end_ts`)
+	}
+	header = append(header, `begin_delta`, `end_delta`, `median_begin_ts`, `median_end_ts`, `flagged`)
+	_ = writer.Write(header)
+	for _, verse := range verses {
+		var line = []string{verse.BookId, strconv.Itoa(verse.ChapterNum), verse.VerseStr}
+		for i := range verse.Begins {
+			line = append(line, formatTS(verse.Begins[i], verse.HasBegin[i]), formatTS(verse.Ends[i], verse.HasEnd[i]))
+		}
+		line = append(line, formatTS(verse.BeginDelta, true), formatTS(verse.EndDelta, true),
+			formatTS(verse.MedianBegin, true), formatTS(verse.MedianEnd, true), strconv.FormatBool(verse.Flagged))
+		_ = writer.Write(line)
+	}
+	writer.Flush()
+	err = writer.Error()
+	if err != nil {
+		return "", log.Error(e.ctx, 500, err, `Error writing timestamp ensemble csv`)
+	}
+	return file.Name(), nil
+}
+
+// WriteHTML writes a summary, and a table of the verses whose boundaries disagree by more than the threshold.
+func (e *Ensemble) WriteHTML(datasetName string, verses []Verse) (string, *log.Status) {
+	file, err := os.Create(filepath.Join(os.Getenv(`FCBH_DATASET_TMP`), datasetName+"_ts_ensemble.html"))
+	if err != nil {
+		return "", log.Error(e.ctx, 500, err, `Error creating timestamp ensemble html`)
+	}
+	defer file.Close()
+	compared, flagged := Counts(verses)
+	head := `<!DOCTYPE html>
+<html>
+ <head>
+  <meta charset="utf-8">
+  <title>Timestamp Ensemble</title>
+  <style>
+	table { border-collapse: collapse; margin: auto; }
+	th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: right; }
+	th:first-child, td:first-child { text-align: left; }
+  </style>
+ </head>
+<body>
+`
+	_, _ = file.WriteString(head)
+	_, _ = file.WriteString(`<h2 style="text-align:center">Timestamp Disagreement `)
+	_, _ = file.WriteString(html.EscapeString(datasetName))
+	_, _ = file.WriteString("</h2>\n")
+	_, _ = file.WriteString(`<h3 style="text-align:center">`)
+	_, _ = file.WriteString(strconv.Itoa(flagged) + ` of ` + strconv.Itoa(compared) + ` verses differ by more than `)
+	_, _ = file.WriteString(strconv.FormatFloat(e.config.Threshold, 'f', -1, 64) + " seconds</h3>\n")
+	_, _ = file.WriteString("<table>\n<thead><tr><th>Ref</th>")
+	for _, name := range e.config.Datasets {
+		_, _ = file.WriteString(`<th>` + html.EscapeString(name) + ` Begin</th><th>End</th>`)
+	}
+	_, _ = file.WriteString("<th>Begin Delta</th><th>End Delta</th></tr></thead>\n<tbody>\n")
+	for _, verse := range verses {
+		if !verse.Flagged {
+			continue
+		}
+		_, _ = file.WriteString(`<tr><td>`)
+		_, _ = file.WriteString(verse.BookId + ` ` + strconv.Itoa(verse.ChapterNum) + `:` + html.EscapeString(verse.VerseStr))
+		_, _ = file.WriteString(`</td>`)
+		for i := range verse.Begins {
+			_, _ = file.WriteString(`<td>` + formatTS(verse.Begins[i], verse.HasBegin[i]) + `</td>`)
+			_, _ = file.WriteString(`<td>` + formatTS(verse.Ends[i], verse.HasEnd[i]) + `</td>`)
+		}
+		_, _ = file.WriteString(`<td>` + formatTS(verse.BeginDelta, true) + `</td>`)
+		_, _ = file.WriteString(`<td>` + formatTS(verse.EndDelta, true) + "</td></tr>\n")
+	}
+	_, _ = file.WriteString("</tbody>\n</table>\n</body>\n</html>\n")
+	return file.Name(), nil
+}
+
+func formatTS(value float64, has bool) string {
+	if !has {
+		return ``
+	}
+	return strconv.FormatFloat(value, 'f', 3, 64)
+}
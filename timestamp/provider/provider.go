@@ -0,0 +1,86 @@
+package provider
+
+import (
+	"context"
+	"sort"
+
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/db"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/decode_yaml/request"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/input"
+	log "github.com/faithcomesbyhearing/fcbh-dataset-io/logger"
+)
+
+// Provider writes the script timestamps of the audio files it is given to the database.
+type Provider interface {
+	ProcessFiles(files []input.InputFile) *log.Status
+}
+
+// Config is what a provider is given when it is created for a request.
+type Config struct {
+	Ctx         context.Context
+	Conn        db.DBAdapter
+	BibleId     string
+	LanguageISO string
+	AltLanguage string
+	AudioOTId   string
+	AudioNTId   string
+	Workers     int
+	Detail      request.Detail
+	Testament   request.Testament
+	Fallback    bool // another provider follows in the chain, so a lack of timestamps is not an error
+}
+
+// Info describes a provider, so that the request validation and the controller can use
+// any provider that is registered without knowing it.
+type Info struct {
+	Name        string   // name used in timestamps: chain: of a request
+	ByChapter   bool     // processes the files it is given, rather than the whole dataset
+	Executables []string // environment variables that name the executables it runs
+	Selected    func(req request.Timestamps) bool
+	New         func(cfg Config) (Provider, *log.Status)
+}
+
+var providers = make(map[string]Info)
+
+// Register adds a provider.  It is called from an init function.
+func Register(info Info) {
+	providers[info.Name] = info
+}
+
+func Find(name string) (Info, bool) {
+	info, ok := providers[name]
+	return info, ok
+}
+
+// Chain returns the providers selected by a request, in the order they are tried.
+// It is either the chain listed in the request, or the one provider selected by its own field.
+func Chain(req request.Timestamps) ([]Info, bool) {
+	var results []Info
+	if len(req.Chain) > 0 {
+		for _, name := range req.Chain {
+			info, ok := providers[name]
+			if !ok {
+				return nil, false
+			}
+			results = append(results, info)
+		}
+		return results, true
+	}
+	for _, name := range Names() {
+		info := providers[name]
+		if info.Selected != nil && info.Selected(req) {
+			return []Info{info}, true
+		}
+	}
+	return results, true
+}
+
+// Names returns the names of all registered providers in sorted order.
+func Names() []string {
+	var names []string
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,30 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/decode_yaml/request"
+)
+
+func TestChain(t *testing.T) {
+	var req request.Timestamps
+	req.Aeneas = true
+	chain, ok := Chain(req)
+	if !ok || len(chain) != 1 || chain[0].Name != `aeneas` {
+		t.Error("Expected aeneas alone", chain)
+	}
+	req = request.Timestamps{Chain: []string{`bible_brain`, `mms_align`}}
+	chain, ok = Chain(req)
+	if !ok || len(chain) != 2 || chain[0].ByChapter || !chain[1].ByChapter {
+		t.Error("Expected bible_brain then mms_align", chain)
+	}
+	req = request.Timestamps{Chain: []string{`bible_brain`, `gentle`}}
+	_, ok = Chain(req)
+	if ok {
+		t.Error("Expected gentle not to be registered")
+	}
+	chain, ok = Chain(request.Timestamps{NoTimestamps: true})
+	if !ok || len(chain) != 0 {
+		t.Error("Expected no providers", chain)
+	}
+}
@@ -0,0 +1,86 @@
+package provider
+
+import (
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/decode_yaml/request"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/encode"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/fetch"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/input"
+	log "github.com/faithcomesbyhearing/fcbh-dataset-io/logger"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/mms"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/mms/mms_align"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/timestamp"
+)
+
+// The providers that are part of this project.  Another provider can be added by a Register call here,
+// or in an init function of its own package, when that package is imported here.
+func init() {
+	Register(Info{
+		Name:     `bible_brain`,
+		Selected: func(req request.Timestamps) bool { return req.BibleBrain },
+		New: func(cfg Config) (Provider, *log.Status) {
+			return &bibleBrain{cfg: cfg}, nil
+		},
+	})
+	Register(Info{
+		Name:        `aeneas`,
+		ByChapter:   true,
+		Executables: []string{`FCBH_AENEAS_PYTHON`},
+		Selected:    func(req request.Timestamps) bool { return req.Aeneas },
+		New: func(cfg Config) (Provider, *log.Status) {
+			aeneas := encode.NewAeneas(cfg.Ctx, cfg.Conn, cfg.BibleId, cfg.LanguageISO, cfg.Detail)
+			return &aeneas, nil
+		},
+	})
+	Register(Info{
+		Name:      `ts_bucket`,
+		ByChapter: true,
+		Selected:  func(req request.Timestamps) bool { return req.TSBucket },
+		New: func(cfg Config) (Provider, *log.Status) {
+			ts, status := timestamp.NewTSBucket(cfg.Ctx, cfg.Conn)
+			return &ts, status
+		},
+	})
+	Register(Info{
+		Name:        `mms_fa_verse`,
+		ByChapter:   true,
+		Executables: []string{`FCBH_MMS_FA_PYTHON`},
+		Selected:    func(req request.Timestamps) bool { return req.MMSFAVerse },
+		New: func(cfg Config) (Provider, *log.Status) {
+			fa := mms.NewForcedAlign(cfg.Ctx, cfg.Conn, cfg.LanguageISO, cfg.AltLanguage)
+			return &fa, nil
+		},
+	})
+	Register(Info{
+		Name:        `mms_align`,
+		ByChapter:   true,
+		Executables: []string{`FCBH_MMS_FA_PYTHON`},
+		Selected:    func(req request.Timestamps) bool { return req.MMSAlign },
+		New: func(cfg Config) (Provider, *log.Status) {
+			align := mms_align.NewMMSAlign(cfg.Ctx, cfg.Conn, cfg.LanguageISO, cfg.AltLanguage)
+			align.SetWorkers(cfg.Workers)
+			return &align, nil
+		},
+	})
+}
+
+// bibleBrain loads the timestamps of the audio filesets from Bible Brain.
+// It loads every chapter in the testament of the request, not only the files it is given.
+type bibleBrain struct {
+	cfg Config
+}
+
+func (b *bibleBrain) ProcessFiles(files []input.InputFile) *log.Status {
+	var filesetIds = []string{b.cfg.AudioOTId, b.cfg.AudioNTId}
+	for _, filesetId := range filesetIds {
+		if filesetId != `` {
+			api := fetch.NewAPIDBPTimestamps(b.cfg.Conn, filesetId)
+			_, status := api.LoadTimestamps(b.cfg.Testament)
+			if status != nil && b.cfg.Fallback && status.Message == fetch.NoTimestamps {
+				log.Info(b.cfg.Ctx, "No Bible Brain timestamps for", filesetId, "use next provider.")
+			} else if status != nil {
+				return status
+			}
+		}
+	}
+	return nil
+}
@@ -87,6 +87,9 @@ func (t *TSBucket) ListPrefix(bucket, prefix string) ([]string, *log.Status) {
 
 func (t *TSBucket) ProcessFiles(files []input.InputFile) *log.Status {
 	for _, file := range files {
+		if t.ctx.Err() != nil {
+			return log.ContextError(t.ctx, "TS Bucket", file.BookId, file.Chapter)
+		}
 		//var scripts []db.Script
 		scripts, status := t.conn.SelectScriptsByChapter(file.BookId, file.Chapter)
 		if status != nil {
@@ -112,6 +115,7 @@ func (t *TSBucket) ProcessFiles(files []input.InputFile) *log.Status {
 		if status != nil {
 			return status
 		}
+		input.FileDone(t.ctx, file)
 	}
 	return nil
 }
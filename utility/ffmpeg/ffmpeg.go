@@ -53,6 +53,34 @@ func ChopByTimestamp(ctx context.Context, tempDir string, inputFile string, time
 	return results, nil
 }
 
+// ChopSegments cuts each timestamp of inputFile into the file named by its AudioVerseWav, as 16 kHz
+// mono audio.  The format is chosen by the extension of each file, .wav or .flac.
+func ChopSegments(ctx context.Context, inputFile string, timestamps []db.Audio) *log.Status {
+	// ffmpeg -i input.mp3 -ss 3.000 -to 4.000 -ar 16000 -ac 1 -c:a pcm_s16le output.wav
+	var command []string
+	command = append(command, `-i`, inputFile)
+	command = append(command, `-y`)
+	for _, ts := range timestamps {
+		command = append(command, `-ss`, strconv.FormatFloat(ts.BeginTS, 'f', 3, 64))
+		command = append(command, `-to`, strconv.FormatFloat(ts.EndTS, 'f', 3, 64))
+		command = append(command, `-ar`, `16000`, `-ac`, `1`)
+		if filepath.Ext(ts.AudioVerseWav) == `.flac` {
+			command = append(command, `-c:a`, `flac`)
+		} else {
+			command = append(command, `-c:a`, `pcm_s16le`)
+		}
+		command = append(command, ts.AudioVerseWav)
+	}
+	cmd := exec.CommandContext(ctx, `ffmpeg`, command...)
+	var stderrBuf bytes.Buffer
+	cmd.Stderr = &stderrBuf
+	err := cmd.Run()
+	if err != nil {
+		return log.Error(ctx, 500, err, stderrBuf.String())
+	}
+	return nil
+}
+
 // ChopOneSegment uses timestamps extract one segment from an audio file
 func ChopOneSegment(ctx context.Context, tempDir string, inputFile string, beginTS float64, endTS float64) (string, *log.Status) {
 	var outputFile string
@@ -8,15 +8,18 @@ import (
 	"sync"
 
 	log "github.com/faithcomesbyhearing/fcbh-dataset-io/logger"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/metrics"
 )
 
 /**
 This func will run a long running process, such as a python training program,
 and capture the stdout and stderr output, It will log the stdout lines as INFO,
-and the STDERR lines as WARN.
+and the STDERR lines as WARN.  The lines are logged with the name of the script as their backend.
 */
 
 func RunScriptWithLogging(ctx context.Context, python string, args ...string) *log.Status {
+	backend := backendName(python, args)
+	ctx = log.WithBackend(ctx, backend)
 	var newArgs []string
 	newArgs = append(newArgs, "-u")
 	newArgs = append(newArgs, args...)
@@ -33,6 +36,7 @@ func RunScriptWithLogging(ctx context.Context, python string, args ...string) *l
 	if err != nil {
 		return log.Error(ctx, 500, err, `Unable to execute command`, cmd.String())
 	}
+	metrics.PythonStarts.Inc(backend)
 	var pythonErr *log.Status
 	var mu sync.Mutex
 	var wg sync.WaitGroup
@@ -75,6 +79,9 @@ func RunScriptWithLogging(ctx context.Context, python string, args ...string) *l
 	mu.Lock()
 	pyErr := pythonErr
 	mu.Unlock()
+	if pyErr != nil || status != nil {
+		metrics.PythonFailures.Inc(backend)
+	}
 	if pyErr != nil {
 		return pyErr
 	}
@@ -5,17 +5,25 @@ import (
 	"context"
 	"encoding/binary"
 	"io"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	log "github.com/faithcomesbyhearing/fcbh-dataset-io/logger"
+	"github.com/faithcomesbyhearing/fcbh-dataset-io/metrics"
 )
 
+// killDelay is the time a Python process is given to exit after an interrupt, when the context is done
+const killDelay = 10 * time.Second
+
 type StdioExec struct {
 	ctx       context.Context
 	command   string
 	args      []string
+	backend   string
 	cmd       *exec.Cmd
 	stdin     io.WriteCloser
 	stdout    io.ReadCloser
@@ -29,11 +37,20 @@ type StdioExec struct {
 
 func NewStdioExec(ctx context.Context, command string, args ...string) (*StdioExec, *log.Status) {
 	var stdio StdioExec
+	stdio.backend = backendName(command, args)
+	ctx = log.WithBackend(ctx, stdio.backend)
 	stdio.ctx = ctx
 	stdio.command = command
 	stdio.args = args
 	var err error
 	stdio.cmd = exec.CommandContext(ctx, command, args...)
+	// When ctx is cancelled or times out, interrupt the process so it can exit cleanly, then kill it.
+	stdio.cmd.Cancel = func() error {
+		err := stdio.cmd.Process.Signal(os.Interrupt)
+		time.AfterFunc(killDelay, func() { _ = stdio.cmd.Process.Kill() })
+		return err
+	}
+	stdio.cmd.WaitDelay = killDelay
 	stdio.stdin, err = stdio.cmd.StdinPipe()
 	if err != nil {
 		return &stdio, log.Error(ctx, 500, err, `Unable to open stdin for reading`)
@@ -50,12 +67,23 @@ func NewStdioExec(ctx context.Context, command string, args ...string) (*StdioEx
 	if err != nil {
 		return &stdio, log.Error(ctx, 500, err, `Unable to start writing`)
 	}
+	metrics.PythonStarts.Inc(stdio.backend)
 	stdio.handleStderr()
 	stdio.writer = bufio.NewWriterSize(stdio.stdin, 4096)
 	stdio.reader = bufio.NewReaderSize(stdio.stdout, 4096)
 	return &stdio, nil
 }
 
+// backendName is the name of the Python script that is run, or else of the command
+func backendName(command string, args []string) string {
+	for _, arg := range args {
+		if strings.HasSuffix(arg, `.py`) {
+			return strings.TrimSuffix(filepath.Base(arg), `.py`)
+		}
+	}
+	return filepath.Base(command)
+}
+
 func (s *StdioExec) handleStderr() {
 	s.stderrWg.Add(1)
 	go func() {
@@ -67,6 +95,9 @@ func (s *StdioExec) handleStderr() {
 				status := log.ExecError(s.ctx, 500, line)
 				if status != nil {
 					s.errMutex.Lock()
+					if s.pythonErr == nil {
+						metrics.PythonFailures.Inc(s.backend)
+					}
 					s.pythonErr = status
 					s.errMutex.Unlock()
 				}
@@ -87,6 +118,10 @@ func (s *StdioExec) getPythonErr() *log.Status {
 
 func (s *StdioExec) Process(input string) (string, *log.Status) {
 	var result string
+	status := log.ContextError(s.ctx, s.command)
+	if status != nil {
+		return result, status
+	}
 	pyErr := s.getPythonErr()
 	if pyErr != nil {
 		return result, pyErr
@@ -101,6 +136,9 @@ func (s *StdioExec) Process(input string) (string, *log.Status) {
 	}
 	result, err = s.reader.ReadString('\n')
 	if err != nil {
+		if s.ctx.Err() != nil {
+			return result, log.ContextError(s.ctx, `Stopped`, s.command)
+		}
 		return result, log.Error(s.ctx, 500, err, `Error reading response from`, s.command)
 	}
 	pyErr = s.getPythonErr()
@@ -113,6 +151,10 @@ func (s *StdioExec) Process(input string) (string, *log.Status) {
 
 func (s *StdioExec) ProcessBytes(input []byte) (string, *log.Status) {
 	var result string
+	status := log.ContextError(s.ctx, s.command)
+	if status != nil {
+		return result, status
+	}
 	pyErr := s.getPythonErr()
 	if pyErr != nil {
 		return result, pyErr
@@ -133,6 +175,9 @@ func (s *StdioExec) ProcessBytes(input []byte) (string, *log.Status) {
 	}
 	result, err = s.reader.ReadString('\n')
 	if err != nil {
+		if s.ctx.Err() != nil {
+			return result, log.ContextError(s.ctx, `Stopped`, s.command)
+		}
 		return result, log.Error(s.ctx, 500, err, `Error reading response from`, s.command)
 	}
 	pyErr = s.getPythonErr()
@@ -153,7 +198,12 @@ func (s *StdioExec) Close() {
 	s.stderrWg.Wait()
 	if s.cmd != nil && s.cmd.Process != nil {
 		err := s.cmd.Wait()
-		if err != nil {
+		if err != nil && s.ctx.Err() != nil {
+			log.Info(s.ctx, `Module stopped`, s.cmd.String(), s.ctx.Err())
+		} else if err != nil {
+			if s.getPythonErr() == nil {
+				metrics.PythonFailures.Inc(s.backend)
+			}
 			// Do not return error so that s.pythonErr is reported
 			_ = log.Error(s.ctx, 500, err, `Module failed`, s.cmd.String())
 		}
@@ -16,3 +16,12 @@ func TestStdioExec(t *testing.T) {
 	fmt.Println("result:", result, status, status2)
 	stdio1.Close()
 }
+
+func TestBackendName(t *testing.T) {
+	if name := backendName(`/usr/bin/python3`, []string{`-u`, `/opt/mms/mms_asr.py`, `eng`}); name != `mms_asr` {
+		t.Error(`Expected mms_asr, not`, name)
+	}
+	if name := backendName(`/usr/local/bin/uroman`, nil); name != `uroman` {
+		t.Error(`Expected uroman, not`, name)
+	}
+}
@@ -0,0 +1,88 @@
+package worker_pool
+
+import (
+	"context"
+	"sync"
+
+	log "github.com/faithcomesbyhearing/fcbh-dataset-io/logger"
+)
+
+// Run performs task on each item using a number of concurrent workers.  Each worker is
+// identified by a number from 0 to workers-1, so that a task can use Python processes
+// that belong to its worker.  The results are passed to write one at a time, from the
+// calling goroutine, in the order of items, so that the database is written by one goroutine
+// only, and in the same order as a sequential run.  Run stops at the first item whose task
+// or write fails, after writing the results of all items before it, and returns that error.
+func Run[I any, R any](ctx context.Context, workers int, items []I,
+	task func(worker int, item I) (R, *log.Status),
+	write func(item I, result R) *log.Status) *log.Status {
+	type outcome struct {
+		index  int
+		result R
+		status *log.Status
+	}
+	if workers > len(items) {
+		workers = len(items)
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	var jobs = make(chan int)
+	var results = make(chan outcome, workers)
+	var stop = make(chan struct{})
+	go func() {
+		defer close(jobs)
+		for i := range items {
+			select {
+			case jobs <- i:
+			case <-stop:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for i := range jobs {
+				result, status := task(worker, items[i])
+				results <- outcome{index: i, result: result, status: status}
+			}
+		}(w)
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	var status *log.Status
+	var pending = make(map[int]outcome)
+	var next int
+	for out := range results {
+		if status != nil {
+			continue // drain the results of tasks that were running
+		}
+		pending[out.index] = out
+		for status == nil {
+			done, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			status = done.status
+			if status == nil {
+				status = write(items[done.index], done.result)
+			}
+		}
+		if status != nil {
+			close(stop)
+		}
+	}
+	if status == nil && next < len(items) {
+		status = log.ContextError(ctx, `Workers stopped`)
+	}
+	return status
+}
@@ -0,0 +1,71 @@
+package worker_pool
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+	"time"
+
+	log "github.com/faithcomesbyhearing/fcbh-dataset-io/logger"
+)
+
+func TestRunOrder(t *testing.T) {
+	ctx := context.Background()
+	var items []int
+	for i := 0; i < 50; i++ {
+		items = append(items, i)
+	}
+	var written []int
+	status := Run(ctx, 4, items, func(worker int, item int) (int, *log.Status) {
+		time.Sleep(time.Duration(rand.Intn(3)) * time.Millisecond)
+		return item * 10, nil
+	}, func(item int, result int) *log.Status {
+		written = append(written, result)
+		return nil
+	})
+	if status != nil {
+		t.Fatal(status)
+	}
+	if len(written) != len(items) {
+		t.Fatal("Expected", len(items), "results, got", len(written))
+	}
+	for i, result := range written {
+		if result != i*10 {
+			t.Error("Result", i, "is", result, "out of order")
+		}
+	}
+}
+
+func TestRunError(t *testing.T) {
+	ctx := context.Background()
+	var items = []int{0, 1, 2, 3, 4, 5, 6, 7}
+	var written []int
+	status := Run(ctx, 3, items, func(worker int, item int) (int, *log.Status) {
+		if item == 4 {
+			return 0, log.ErrorNoErr(ctx, 400, "Failed item", item)
+		}
+		return item, nil
+	}, func(item int, result int) *log.Status {
+		written = append(written, result)
+		return nil
+	})
+	if status == nil || status.Status != 400 {
+		t.Fatal("Expected error from item 4, got", status)
+	}
+	if len(written) != 4 {
+		t.Error("Expected items before 4 to be written, got", written)
+	}
+}
+
+func TestRunCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	status := Run(ctx, 2, []int{1, 2, 3}, func(worker int, item int) (int, *log.Status) {
+		return item, log.ContextError(ctx, "Task", item)
+	}, func(item int, result int) *log.Status {
+		return nil
+	})
+	if status == nil || !status.IsCancelled() {
+		t.Error("Expected cancelled, got", status)
+	}
+}
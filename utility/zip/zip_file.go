@@ -60,3 +60,55 @@ func ZipFiles(sources []string) (string, int64, error) {
 	}
 	return target, info.Size(), nil
 }
+
+// ZipDirectory zips every file under directory into directory.zip, with paths that begin
+// with the name of directory.  Audio files are stored, because they do not compress.
+func ZipDirectory(directory string) (string, int64, error) {
+	directory = filepath.Clean(directory)
+	target := directory + ".zip"
+	zipFile, err := os.Create(target)
+	if err != nil {
+		return target, 0, err
+	}
+	defer zipFile.Close()
+	zipWriter := zip.NewWriter(zipFile)
+	parent := filepath.Dir(directory)
+	err = filepath.WalkDir(directory, func(path string, entry os.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		name, err := filepath.Rel(parent, path)
+		if err != nil {
+			return err
+		}
+		var header = zip.FileHeader{Name: filepath.ToSlash(name), Method: zip.Deflate}
+		switch filepath.Ext(path) {
+		case ".wav", ".flac", ".mp3", ".opus", ".webm":
+			header.Method = zip.Store
+		}
+		writer, err := zipWriter.CreateHeader(&header)
+		if err != nil {
+			return err
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		_, err = io.Copy(writer, file)
+		return err
+	})
+	if err != nil {
+		_ = zipWriter.Close()
+		return target, 0, err
+	}
+	err = zipWriter.Close()
+	if err != nil {
+		return target, 0, err
+	}
+	info, err := zipFile.Stat()
+	if err != nil {
+		return target, 0, err
+	}
+	return target, info.Size(), nil
+}
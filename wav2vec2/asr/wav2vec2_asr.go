@@ -68,11 +68,15 @@ func (a *Wav2Vec2ASR) ProcessFiles(files []input.InputFile) *log.Status {
 	sampleDBPath := filepath.Join(os.Getenv(`FCBH_DATASET_TMP`), project+`.db`)
 	sampleDB := db.NewDBAdapter(a.ctx, sampleDBPath)
 	for _, file := range files {
+		if a.ctx.Err() != nil {
+			return log.ContextError(a.ctx, "Wav2Vec2 ASR", file.BookId, file.Chapter)
+		}
 		log.Info(a.ctx, "Wav2Vec2 ASR", file.BookId, file.Chapter)
 		status = a.processFile(file, sampleDB)
 		if status != nil {
 			return status
 		}
+		input.FileDone(a.ctx, file)
 	}
 	return status
 }